@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	spylog "github.com/imagespy/api/log"
+	"github.com/imagespy/api/registry"
+	"github.com/imagespy/api/scrape"
+	"github.com/imagespy/api/updater"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	discoverDBConnection      string
+	discoverExclude           []string
+	discoverInclude           []string
+	discoverLogLevel          string
+	discoverPromPushAddress   string
+	discoverRegistryAddress   string
+	discoverRegistryCredsFile string
+	discoverRegistryDockerCfg string
+	discoverRegistryInsecure  bool
+	discoverRegistryPassword  string
+	discoverRegistryUsername  string
+	discoverVPPreReleases     string
+	discoverVPRulesFile       string
+	discoverWorkerCount       int
+)
+
+var discoverCmd = &cobra.Command{
+	Use:   "discover",
+	Short: "Scrapes all repositories listed in the catalog of a registry",
+	Run: func(cmd *cobra.Command, args []string) {
+		mustInitLogging(discoverLogLevel)
+		rules := mustInitVersionParsers(discoverVPPreReleases, discoverVPRulesFile)
+		s, err := newStore(discoverDBConnection)
+		if err != nil {
+			log.Fatal(spylog.FormatError(err))
+		}
+
+		defer s.Close()
+
+		registry.SetLog(log.StandardLogger())
+		reg, err := registry.NewRegistry(
+			discoverRegistryAddress,
+			registry.Opts{
+				Credentials: mustInitCredentialStore(discoverRegistryCredsFile, discoverRegistryDockerCfg),
+				Insecure:    discoverRegistryInsecure,
+				Password:    discoverRegistryPassword,
+				Username:    discoverRegistryUsername,
+			},
+		)
+		if err != nil {
+			log.Fatal(spylog.FormatError(err))
+		}
+
+		u, err := updater.NewDiscoveryUpdater(discoverPromPushAddress, reg, scrape.NewScraper(s, rules), s, rules, discoverInclude, discoverExclude, discoverWorkerCount)
+		if err != nil {
+			log.Fatal(spylog.FormatError(err))
+		}
+
+		err = u.Run()
+		if err != nil {
+			log.Fatal(spylog.FormatError(err))
+		}
+	},
+}
+
+func init() {
+	discoverCmd.Flags().StringVar(&discoverDBConnection, "db.connection", "", "connection string to connect to the database, e.g. mysql://..., postgres://..., sqlite3://... or memory://")
+	discoverCmd.Flags().StringSliceVar(&discoverExclude, "exclude", []string{}, "glob pattern of repositories to skip, e.g. \"library/*\" (can be repeated)")
+	discoverCmd.Flags().StringSliceVar(&discoverInclude, "include", []string{}, "glob pattern of repositories to scrape, e.g. \"library/*\" (can be repeated, default all)")
+	discoverCmd.Flags().StringVar(&discoverLogLevel, "log.level", "warn", "log level")
+	discoverCmd.Flags().StringVar(&discoverPromPushAddress, "pushgateway.address", "", "address of the Prometheus Pushgateway")
+	discoverCmd.Flags().StringVar(&discoverRegistryAddress, "registry.address", "docker.io", "address of the docker registry")
+	discoverCmd.Flags().StringVar(&discoverRegistryCredsFile, "registry.credentials.file", "", "path to a YAML or JSON file that maps registry hosts to credentials")
+	discoverCmd.Flags().StringVar(&discoverRegistryDockerCfg, "registry.credentials.docker-config", "", "path to a config.json of the Docker CLI to read credentials of registries from")
+	discoverCmd.Flags().BoolVar(&discoverRegistryInsecure, "registry.insecure", false, "disable certificate validation")
+	discoverCmd.Flags().StringVar(&discoverRegistryPassword, "registry.password", "", "password to authenticate against the docker registry")
+	discoverCmd.Flags().StringVar(&discoverRegistryUsername, "registry.username", "", "username to authenticate against the docker registry")
+	discoverCmd.Flags().StringVar(&discoverVPPreReleases, "versionparser.pre-releases", "exclude", "whether a pre-release of a semantic version can become the latest version, \"exclude\" or \"include\"")
+	discoverCmd.Flags().StringVar(&discoverVPRulesFile, "versionparser.rules", "", "path to a YAML or JSON file that configures the version parsers per repository")
+	discoverCmd.Flags().IntVar(&discoverWorkerCount, "workers", 1, "number of workers that scrape repositories")
+	rootCmd.AddCommand(discoverCmd)
+}
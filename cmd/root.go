@@ -4,6 +4,11 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/imagespy/api/registry"
+	"github.com/imagespy/api/store"
+	"github.com/imagespy/api/store/gorm"
+	"github.com/imagespy/api/store/memory"
+	"github.com/imagespy/api/versionparser"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -36,3 +41,65 @@ func mustInitLogging(logLevel string) {
 
 	log.SetLevel(lvl)
 }
+
+// mustInitCredentialStore returns nil if neither a credentials file nor a Docker config has been set.
+func mustInitCredentialStore(credentialsFile, dockerConfig string) registry.CredentialStore {
+	stores := []registry.CredentialStore{}
+	if credentialsFile != "" {
+		s, err := registry.NewFileCredentialStore(credentialsFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		stores = append(stores, s)
+	}
+
+	if dockerConfig != "" {
+		s, err := registry.NewDockerConfigCredentialStore(dockerConfig)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		stores = append(stores, s)
+	}
+
+	if len(stores) == 0 {
+		return nil
+	}
+
+	return registry.ChainCredentialStores(stores...)
+}
+
+// mustInitVersionParsers returns the version parsers that select the latest version of a distinction.
+// The built-in parsers apply to all repositories if rulesFile is empty.
+func mustInitVersionParsers(preReleasePolicy string, rulesFile string) *versionparser.Rules {
+	policy, err := versionparser.ParsePreReleasePolicy(preReleasePolicy)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	opts := versionparser.Opts{PreReleasePolicy: policy}
+	if rulesFile == "" {
+		return versionparser.NewRules(opts)
+	}
+
+	rules, err := versionparser.LoadRules(rulesFile, opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return rules
+}
+
+// memoryConnection selects the in-memory store instead of a database.
+const memoryConnection = "memory://"
+
+// newStore connects to the database of the connection string.
+func newStore(connection string) (store.Store, error) {
+	if connection == memoryConnection {
+		log.Warn("using the in-memory store, all data is lost when the process exits")
+		return memory.New(), nil
+	}
+
+	return gorm.New(connection)
+}
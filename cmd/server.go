@@ -2,10 +2,13 @@ package cmd
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/imagespy/api/queue"
 	"github.com/imagespy/api/registry"
 	"github.com/imagespy/api/scrape"
 	"github.com/imagespy/api/store/gorm"
+	"github.com/imagespy/api/updater"
 	"github.com/imagespy/api/web"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -13,14 +16,28 @@ import (
 
 var (
 	serverDBConnection      string
+	serverDiscoveryExclude  []string
+	serverDiscoveryInclude  []string
+	serverDiscoveryInterval time.Duration
+	serverDiscoveryWorkers  int
 	serverHTTPAddress       string
 	serverLogLevel          string
 	serverMigrationsEnabled bool
 	serverMigrationsPath    string
+	serverQueueMaxAttempts  int
+	serverQueueRetention    time.Duration
+	serverQueueWorkers      int
 	serverRegistryAddress   string
+	serverRegistryCredsFile string
+	serverRegistryDockerCfg string
 	serverRegistryInsecure  bool
 	serverRegistryPassword  string
 	serverRegistryUsername  string
+	serverVPPreReleases     string
+	serverVPRulesFile       string
+	serverWebhookHosts      []string
+	serverWebhookRepos      []string
+	serverWebhookSecret     string
 )
 
 var serverCmd = &cobra.Command{
@@ -28,7 +45,8 @@ var serverCmd = &cobra.Command{
 	Short: "Serves the HTTP API",
 	Run: func(cmd *cobra.Command, args []string) {
 		mustInitLogging(serverLogLevel)
-		if serverMigrationsEnabled {
+		rules := mustInitVersionParsers(serverVPPreReleases, serverVPRulesFile)
+		if serverMigrationsEnabled && serverDBConnection != memoryConnection {
 			log.Info("executing migrations")
 			err := gorm.Migrate(serverDBConnection, serverMigrationsPath)
 			if err != nil {
@@ -37,7 +55,7 @@ var serverCmd = &cobra.Command{
 			log.Info("migrations executed")
 		}
 
-		s, err := gorm.New(serverDBConnection)
+		s, err := newStore(serverDBConnection)
 		if err != nil {
 			log.Fatalf("unable to connect to database: %s", err)
 		}
@@ -47,29 +65,90 @@ var serverCmd = &cobra.Command{
 		reg, err := registry.NewRegistry(
 			serverRegistryAddress,
 			registry.Opts{
-				Insecure: serverRegistryInsecure,
-				Password: serverRegistryPassword,
-				Username: serverRegistryUsername,
+				Credentials: mustInitCredentialStore(serverRegistryCredsFile, serverRegistryDockerCfg),
+				Insecure:    serverRegistryInsecure,
+				Password:    serverRegistryPassword,
+				Username:    serverRegistryUsername,
 			},
 		)
 		if err != nil {
 			log.Fatal(err)
 		}
 
-		handler := web.Init(reg, scrape.NewScraper(s), s)
+		scraper := scrape.NewScraper(s, rules)
+		if serverDiscoveryInterval > 0 {
+			u, err := updater.NewDiscoveryUpdater("", reg, scraper, s, rules, serverDiscoveryInclude, serverDiscoveryExclude, serverDiscoveryWorkers)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			go runDiscovery(u, serverDiscoveryInterval)
+		}
+
+		queueOpts := queue.DefaultOpts()
+		queueOpts.MaxAttempts = serverQueueMaxAttempts
+		queueOpts.Retention = serverQueueRetention
+		queueOpts.Workers = serverQueueWorkers
+		q := queue.NewQueue(reg, scraper, s, queueOpts)
+		go q.Run(make(chan struct{}))
+
+		webhookOpts := web.WebhookOpts{
+			AllowedHosts:        serverWebhookHosts,
+			AllowedRepositories: serverWebhookRepos,
+			Secret:              serverWebhookSecret,
+		}
+		err = webhookOpts.Validate()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if webhookOpts.Secret == "" {
+			log.Warn("webhook secret not set, anyone can send events to the server and read the errors of jobs")
+		}
+
+		handler := web.Init(reg, scraper, s, q, webhookOpts, rules)
 		log.Fatal(http.ListenAndServe(serverHTTPAddress, handler))
 	},
 }
 
+// runDiscovery runs the discovery immediately and then every interval.
+func runDiscovery(u updater.Updater, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		log.Info("discovering repositories")
+		err := u.Run()
+		if err != nil {
+			log.Errorf("discovering repositories failed: %s", err)
+		}
+
+		<-ticker.C
+	}
+}
+
 func init() {
-	serverCmd.Flags().StringVar(&serverDBConnection, "db.connection", "", "connection string to connect to the database")
+	serverCmd.Flags().StringVar(&serverDBConnection, "db.connection", "", "connection string to connect to the database, e.g. mysql://..., postgres://..., sqlite3://... or memory://")
+	serverCmd.Flags().StringSliceVar(&serverDiscoveryExclude, "discovery.exclude", []string{}, "glob pattern of repositories to skip during discovery (can be repeated)")
+	serverCmd.Flags().StringSliceVar(&serverDiscoveryInclude, "discovery.include", []string{}, "glob pattern of repositories to scrape during discovery (can be repeated, default all)")
+	serverCmd.Flags().DurationVar(&serverDiscoveryInterval, "discovery.interval", 0, "interval at which to scrape all repositories in the catalog of the registry, disabled if 0")
+	serverCmd.Flags().IntVar(&serverDiscoveryWorkers, "discovery.workers", 1, "number of workers that scrape repositories during discovery")
 	serverCmd.Flags().StringVar(&serverHTTPAddress, "http.address", ":3001", "ip:port combination to bind to")
 	serverCmd.Flags().StringVar(&serverLogLevel, "log.level", "warn", "set the log level")
 	serverCmd.Flags().BoolVar(&serverMigrationsEnabled, "migrations.enabled", false, "execute migrations on startup")
-	serverCmd.Flags().StringVar(&serverMigrationsPath, "migrations.path", "file:///migrations", "path to directory containing migration files")
+	serverCmd.Flags().StringVar(&serverMigrationsPath, "migrations.path", "file:///migrations", "path to directory containing one directory of migration files per database")
+	serverCmd.Flags().IntVar(&serverQueueMaxAttempts, "queue.max-attempts", 10, "number of attempts after which a failing job of the queue is marked as dead")
+	serverCmd.Flags().DurationVar(&serverQueueRetention, "queue.retention", 7*24*time.Hour, "time to keep jobs of the queue that are done, kept forever if 0")
+	serverCmd.Flags().IntVar(&serverQueueWorkers, "queue.workers", 2, "number of workers that process jobs of the queue")
 	serverCmd.Flags().StringVar(&serverRegistryAddress, "registry.address", "docker.io", "the address of the docker registry")
+	serverCmd.Flags().StringVar(&serverRegistryCredsFile, "registry.credentials.file", "", "path to a YAML or JSON file that maps registry hosts to credentials")
+	serverCmd.Flags().StringVar(&serverRegistryDockerCfg, "registry.credentials.docker-config", "", "path to a config.json of the Docker CLI to read credentials of registries from")
 	serverCmd.Flags().BoolVar(&serverRegistryInsecure, "registry.insecure", false, "disable certificate validation")
 	serverCmd.Flags().StringVar(&serverRegistryPassword, "registry.password", "", "the password to authenticate against the docker registry")
 	serverCmd.Flags().StringVar(&serverRegistryUsername, "registry.username", "", "the username to authenticate against the docker registry")
+	serverCmd.Flags().StringVar(&serverVPPreReleases, "versionparser.pre-releases", "exclude", "whether a pre-release of a semantic version can become the latest version, \"exclude\" or \"include\"")
+	serverCmd.Flags().StringVar(&serverVPRulesFile, "versionparser.rules", "", "path to a YAML or JSON file that configures the version parsers per repository")
+	serverCmd.Flags().StringSliceVar(&serverWebhookHosts, "webhook.allowed-hosts", []string{}, "glob pattern of registry hosts which events are processed, e.g. \"*.example.com\" (can be repeated, default all)")
+	serverCmd.Flags().StringSliceVar(&serverWebhookRepos, "webhook.allowed-repositories", []string{}, "glob pattern of repositories which events are processed, e.g. \"team/*\" (can be repeated, default all)")
+	serverCmd.Flags().StringVar(&serverWebhookSecret, "webhook.secret", "", "secret that registries send in the header \"Authorization: Bearer <secret>\", authentication is disabled if empty")
 	rootCmd.AddCommand(serverCmd)
 }
@@ -1,26 +1,27 @@
 package cmd
 
 import (
-	"database/sql"
-
 	spylog "github.com/imagespy/api/log"
 	"github.com/imagespy/api/registry"
 	"github.com/imagespy/api/scrape"
-	"github.com/imagespy/api/store/gorm"
 	"github.com/imagespy/api/updater"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
 var (
-	updaterDBConnection     string
-	updaterLogLevel         string
-	updaterPromPushAddress  string
-	updaterRegistryAddress  string
-	updaterRegistryInsecure bool
-	updaterRegistryPassword string
-	updaterRegistryUsername string
-	updaterWorkerCount      int
+	updaterDBConnection      string
+	updaterLogLevel          string
+	updaterPromPushAddress   string
+	updaterRegistryAddress   string
+	updaterRegistryCredsFile string
+	updaterRegistryDockerCfg string
+	updaterRegistryInsecure  bool
+	updaterRegistryPassword  string
+	updaterRegistryUsername  string
+	updaterVPPreReleases     string
+	updaterVPRulesFile       string
+	updaterWorkerCount       int
 )
 
 var updaterCmd = &cobra.Command{
@@ -32,32 +33,30 @@ var updaterAllCmd = &cobra.Command{
 	Short: "Updates all images",
 	Run: func(cmd *cobra.Command, args []string) {
 		mustInitLogging(updaterLogLevel)
-		s, err := gorm.New(updaterDBConnection)
+		rules := mustInitVersionParsers(updaterVPPreReleases, updaterVPRulesFile)
+		s, err := newStore(updaterDBConnection)
 		if err != nil {
 			log.Fatal(spylog.FormatError(err))
 		}
 
 		defer s.Close()
-		db, err := sql.Open("mysql", updaterDBConnection)
-		if err != nil {
-			log.Fatal(spylog.FormatError(err))
-		}
 
 		registry.SetLog(log.StandardLogger())
 		reg, err := registry.NewRegistry(
 			updaterRegistryAddress,
 			registry.Opts{
-				Insecure: updaterRegistryInsecure,
-				Password: updaterRegistryPassword,
-				Username: updaterRegistryUsername,
+				Credentials: mustInitCredentialStore(updaterRegistryCredsFile, updaterRegistryDockerCfg),
+				Insecure:    updaterRegistryInsecure,
+				Password:    updaterRegistryPassword,
+				Username:    updaterRegistryUsername,
 			},
 		)
 		if err != nil {
 			log.Fatal(spylog.FormatError(err))
 		}
 
-		scraper := scrape.NewScraper(s)
-		u := updater.NewAllImagesUpdater(updaterPromPushAddress, db, reg, scraper)
+		scraper := scrape.NewScraper(s, rules)
+		u := updater.NewAllImagesUpdater(updaterPromPushAddress, reg, scraper, s)
 		err = u.Run()
 		if err != nil {
 			log.Fatal(spylog.FormatError(err))
@@ -70,7 +69,8 @@ var updaterLatestCmd = &cobra.Command{
 	Short: "Updates the latest version of images",
 	Run: func(cmd *cobra.Command, args []string) {
 		mustInitLogging(updaterLogLevel)
-		s, err := gorm.New(updaterDBConnection)
+		rules := mustInitVersionParsers(updaterVPPreReleases, updaterVPRulesFile)
+		s, err := newStore(updaterDBConnection)
 		if err != nil {
 			log.Fatal(spylog.FormatError(err))
 		}
@@ -81,16 +81,17 @@ var updaterLatestCmd = &cobra.Command{
 		reg, err := registry.NewRegistry(
 			updaterRegistryAddress,
 			registry.Opts{
-				Insecure: updaterRegistryInsecure,
-				Password: updaterRegistryPassword,
-				Username: updaterRegistryUsername,
+				Credentials: mustInitCredentialStore(updaterRegistryCredsFile, updaterRegistryDockerCfg),
+				Insecure:    updaterRegistryInsecure,
+				Password:    updaterRegistryPassword,
+				Username:    updaterRegistryUsername,
 			},
 		)
 		if err != nil {
 			log.Fatal(spylog.FormatError(err))
 		}
 
-		scraper := scrape.NewScraper(s)
+		scraper := scrape.NewScraper(s, rules)
 		u := updater.NewLatestImageUpdater(updaterPromPushAddress, reg, scraper, s, updaterWorkerCount)
 		err = u.Run()
 		if err != nil {
@@ -100,13 +101,17 @@ var updaterLatestCmd = &cobra.Command{
 }
 
 func init() {
-	updaterCmd.PersistentFlags().StringVar(&updaterDBConnection, "db.connection", "", "connection string to connect to the database")
+	updaterCmd.PersistentFlags().StringVar(&updaterDBConnection, "db.connection", "", "connection string to connect to the database, e.g. mysql://..., postgres://..., sqlite3://... or memory://")
 	updaterCmd.PersistentFlags().StringVar(&updaterLogLevel, "log.level", "warn", "log level")
 	updaterCmd.PersistentFlags().StringVar(&updaterPromPushAddress, "pushgateway.address", "", "address of the Prometheus Pushgateway")
 	updaterCmd.PersistentFlags().StringVar(&updaterRegistryAddress, "registry.address", "docker.io", "address of the docker registry")
+	updaterCmd.PersistentFlags().StringVar(&updaterRegistryCredsFile, "registry.credentials.file", "", "path to a YAML or JSON file that maps registry hosts to credentials")
+	updaterCmd.PersistentFlags().StringVar(&updaterRegistryDockerCfg, "registry.credentials.docker-config", "", "path to a config.json of the Docker CLI to read credentials of registries from")
 	updaterCmd.PersistentFlags().BoolVar(&updaterRegistryInsecure, "registry.insecure", false, "disable certificate validation")
 	updaterCmd.PersistentFlags().StringVar(&updaterRegistryPassword, "registry.password", "", "password to authenticate against the docker registry")
 	updaterCmd.PersistentFlags().StringVar(&updaterRegistryUsername, "registry.username", "", "username to authenticate against the docker registry")
+	updaterCmd.PersistentFlags().StringVar(&updaterVPPreReleases, "versionparser.pre-releases", "exclude", "whether a pre-release of a semantic version can become the latest version, \"exclude\" or \"include\"")
+	updaterCmd.PersistentFlags().StringVar(&updaterVPRulesFile, "versionparser.rules", "", "path to a YAML or JSON file that configures the version parsers per repository")
 	updaterCmd.PersistentFlags().IntVar(&updaterWorkerCount, "workers", 1, "number of workers that process updates")
 	updaterCmd.AddCommand(updaterAllCmd)
 	updaterCmd.AddCommand(updaterLatestCmd)
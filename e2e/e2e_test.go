@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net"
@@ -28,6 +29,8 @@ var (
 	httpResponse      *http.Response
 	imagespyAPICmd    *exec.Cmd
 	imagespyAPICmdOut *bytes.Buffer
+	// updateFixtures writes the bodies of responses to the fixture files instead of comparing them.
+	updateFixtures = os.Getenv("E2E_UPDATE_FIXTURES") != ""
 )
 
 type testingT struct {
@@ -226,6 +229,10 @@ func theAPIRespondsWithABodyOf(fixtureFilePath string) error {
 		return fmt.Errorf("reading response body: %s", err)
 	}
 
+	if updateFixtures {
+		return writeFixture(fixtureFilePath, actualBytes)
+	}
+
 	if !assert.JSONEq(t, string(expectedBytes), string(actualBytes)) {
 		return t.getLastError()
 	}
@@ -233,6 +240,22 @@ func theAPIRespondsWithABodyOf(fixtureFilePath string) error {
 	return nil
 }
 
+func writeFixture(fixtureFilePath string, body []byte) error {
+	b := &bytes.Buffer{}
+	err := json.Indent(b, body, "", "  ")
+	if err != nil {
+		return fmt.Errorf("indenting response body: %s", err)
+	}
+
+	b.WriteString("\n")
+	err = ioutil.WriteFile(fixtureFilePath, b.Bytes(), 0644)
+	if err != nil {
+		return fmt.Errorf("writing fixture file: %s", err)
+	}
+
+	return nil
+}
+
 func sendingTheRequestTo(requestFilePath, path string) error {
 	f, err := os.Open(requestFilePath)
 	if err != nil {
@@ -0,0 +1,248 @@
+package queue
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/imagespy/api/registry"
+	"github.com/imagespy/api/scrape"
+	"github.com/imagespy/api/store"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	promJobsProcessed = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:      "queue_jobs_processed_total",
+			Namespace: "imagespy",
+			Help:      "A counter of processed jobs by action and resulting status.",
+		},
+		[]string{"action", "status"},
+	)
+)
+
+// Queue processes jobs in the background. Jobs are persisted in the store and survive a restart.
+type Queue interface {
+	// Enqueue stores a job. A worker processes it as soon as possible.
+	Enqueue(j *store.Job) error
+	// Run starts the workers and blocks until stop gets closed.
+	Run(stop <-chan struct{})
+}
+
+// Opts configures the retries and the workers of a queue.
+type Opts struct {
+	// BackoffBase is the delay before the first retry of a failed job. It doubles with every attempt.
+	BackoffBase time.Duration
+	// BackoffMax limits the delay between two attempts.
+	BackoffMax time.Duration
+	// CleanupInterval is the interval at which jobs that are done and older than Retention are deleted.
+	CleanupInterval time.Duration
+	// Lease is the time a worker has to process a job. Another worker processes the job again after the lease expired,
+	// e.g. because the server has been restarted.
+	Lease time.Duration
+	// MaxAttempts is the number of attempts after which a failing job is marked as dead.
+	MaxAttempts int
+	// PollInterval is the interval at which idle workers check for due jobs.
+	PollInterval time.Duration
+	// Retention is the time a job that is done is kept. Jobs are kept forever if it is 0.
+	// Dead jobs are always kept.
+	Retention time.Duration
+	Workers   int
+}
+
+// DefaultOpts returns the options used by the server.
+func DefaultOpts() Opts {
+	return Opts{
+		BackoffBase:     10 * time.Second,
+		BackoffMax:      time.Hour,
+		CleanupInterval: time.Hour,
+		Lease:           10 * time.Minute,
+		MaxAttempts:     10,
+		PollInterval:    5 * time.Second,
+		Retention:       7 * 24 * time.Hour,
+		Workers:         2,
+	}
+}
+
+type queue struct {
+	opts     Opts
+	registry registry.Registry
+	scraper  scrape.Scraper
+	store    store.Store
+	timeFunc func() time.Time
+	wake     chan struct{}
+}
+
+// NewQueue returns a Queue that scrapes images of r with sc.
+func NewQueue(r registry.Registry, sc scrape.Scraper, s store.Store, o Opts) Queue {
+	return &queue{
+		opts:     o,
+		registry: r,
+		scraper:  sc,
+		store:    s,
+		timeFunc: func() time.Time { return time.Now().UTC() },
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+func (q *queue) Enqueue(j *store.Job) error {
+	if j.NextRunAt.IsZero() {
+		j.NextRunAt = q.timeFunc()
+	}
+
+	err := q.store.Jobs().Create(j)
+	if err != nil {
+		return fmt.Errorf("queue.Enqueue - creating job: %s", err)
+	}
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+func (q *queue) Run(stop <-chan struct{}) {
+	wg := &sync.WaitGroup{}
+	if q.opts.Retention > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.runCleanup(stop)
+		}()
+	}
+
+	wg.Add(q.opts.Workers)
+	for i := 0; i < q.opts.Workers; i++ {
+		go func() {
+			defer wg.Done()
+			q.work(stop)
+		}()
+	}
+
+	wg.Wait()
+}
+
+func (q *queue) runCleanup(stop <-chan struct{}) {
+	ticker := time.NewTicker(q.opts.CleanupInterval)
+	defer ticker.Stop()
+	for {
+		q.cleanup()
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// cleanup deletes jobs that are done and older than the retention.
+func (q *queue) cleanup() {
+	deleted, err := q.store.Jobs().DeleteDone(q.timeFunc().Add(-q.opts.Retention))
+	if err != nil {
+		log.Errorf("queue.cleanup - deleting done jobs: %s", err)
+		return
+	}
+
+	log.Debugf("deleted %d done jobs", deleted)
+}
+
+func (q *queue) work(stop <-chan struct{}) {
+	for {
+		if q.processNext() {
+			select {
+			case <-stop:
+				return
+			default:
+				continue
+			}
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-q.wake:
+		case <-time.After(q.opts.PollInterval):
+		}
+	}
+}
+
+// processNext processes the next due job. It returns false if no job is due.
+func (q *queue) processNext() bool {
+	now := q.timeFunc()
+	j, err := q.store.Jobs().Claim(now, now.Add(q.opts.Lease))
+	if err != nil {
+		if err != store.ErrDoesNotExist {
+			log.Errorf("queue.processNext - claiming job: %s", err)
+		}
+
+		return false
+	}
+
+	log.Debugf("processing job %d %s %s %s", j.ID, j.Action, j.Name, j.Reference)
+	q.finish(j, q.process(j))
+	return true
+}
+
+func (q *queue) process(j *store.Job) error {
+	switch j.Action {
+	case store.JobActionDeleteImage:
+		return q.scraper.DeleteImage(j.Name, j.Reference)
+	case store.JobActionDeleteTag:
+		return q.scraper.DeleteTag(j.Name, j.Reference)
+	case store.JobActionScrape:
+		regImage, err := q.registry.Image(j.Name + ":" + j.Reference)
+		if err != nil {
+			return err
+		}
+
+		err = q.scraper.ScrapeImage(regImage)
+		if err != nil {
+			return err
+		}
+
+		return q.scraper.ScrapeLatestImage(regImage)
+	}
+
+	return fmt.Errorf("unknown action %s", j.Action)
+}
+
+// finish stores the result of a job. A failed job is retried with an exponential backoff until it reaches MaxAttempts.
+func (q *queue) finish(j *store.Job, processErr error) {
+	if processErr == nil {
+		j.LastError = ""
+		j.Status = store.JobStatusDone
+	} else {
+		j.LastError = processErr.Error()
+		if j.Attempts >= q.opts.MaxAttempts {
+			log.Errorf("job %d failed %d times and is marked as dead: %s", j.ID, j.Attempts, processErr)
+			j.Status = store.JobStatusDead
+		} else {
+			log.Warnf("job %d failed and will be retried: %s", j.ID, processErr)
+			j.NextRunAt = q.timeFunc().Add(q.backoff(j.Attempts))
+			j.Status = store.JobStatusPending
+		}
+	}
+
+	promJobsProcessed.WithLabelValues(j.Action, j.Status).Inc()
+	err := q.store.Jobs().Update(j)
+	if err != nil {
+		log.Errorf("queue.finish - updating job %d: %s", j.ID, err)
+	}
+}
+
+func (q *queue) backoff(attempts int) time.Duration {
+	d := q.opts.BackoffBase
+	for i := 1; i < attempts; i++ {
+		d = d * 2
+		if d >= q.opts.BackoffMax {
+			return q.opts.BackoffMax
+		}
+	}
+
+	return d
+}
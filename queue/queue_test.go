@@ -0,0 +1,131 @@
+package queue
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	registryMock "github.com/imagespy/api/registry/mock"
+	"github.com/imagespy/api/scrape"
+	"github.com/imagespy/api/store"
+	"github.com/imagespy/api/store/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestQueue(ctrl *gomock.Controller, jobStore store.JobStore, scraper scrape.Scraper, now time.Time) *queue {
+	s := mock.NewMockStore(ctrl)
+	s.EXPECT().
+		Jobs().
+		Return(jobStore).
+		AnyTimes()
+
+	rm := registryMock.NewRegistry()
+	rm.AddImage(registryMock.NewImage("", "unit.test/first", nil, 2, "1.0"))
+	q := NewQueue(rm, scraper, s, Opts{BackoffBase: 10 * time.Second, BackoffMax: time.Minute, Lease: time.Minute, MaxAttempts: 3}).(*queue)
+	q.timeFunc = func() time.Time { return now }
+	return q
+}
+
+func TestQueue_processNext(t *testing.T) {
+	now := time.Date(2019, 6, 21, 10, 0, 0, 0, time.UTC)
+	testcases := []struct {
+		name           string
+		job            *store.Job
+		scrapeErr      error
+		expectedJob    *store.Job
+		expectedResult bool
+	}{
+		{
+			name:           "success",
+			job:            &store.Job{Action: store.JobActionScrape, Attempts: 1, Name: "unit.test/first", Reference: "1.0", Status: store.JobStatusRunning},
+			expectedJob:    &store.Job{Action: store.JobActionScrape, Attempts: 1, Name: "unit.test/first", Reference: "1.0", Status: store.JobStatusDone},
+			expectedResult: true,
+		},
+		{
+			name:           "retry with backoff",
+			job:            &store.Job{Action: store.JobActionDeleteTag, Attempts: 2, Name: "unit.test/first", Reference: "1.0", Status: store.JobStatusRunning},
+			scrapeErr:      fmt.Errorf("unit test"),
+			expectedJob:    &store.Job{Action: store.JobActionDeleteTag, Attempts: 2, LastError: "unit test", Name: "unit.test/first", NextRunAt: now.Add(20 * time.Second), Reference: "1.0", Status: store.JobStatusPending},
+			expectedResult: true,
+		},
+		{
+			name:           "dead after max attempts",
+			job:            &store.Job{Action: store.JobActionDeleteTag, Attempts: 3, Name: "unit.test/first", Reference: "1.0", Status: store.JobStatusRunning},
+			scrapeErr:      fmt.Errorf("unit test"),
+			expectedJob:    &store.Job{Action: store.JobActionDeleteTag, Attempts: 3, LastError: "unit test", Name: "unit.test/first", Reference: "1.0", Status: store.JobStatusDead},
+			expectedResult: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			jobStore := mock.NewMockJobStore(ctrl)
+			jobStore.EXPECT().
+				Claim(now, now.Add(time.Minute)).
+				Return(tc.job, nil)
+			jobStore.EXPECT().
+				Update(gomock.Eq(tc.expectedJob)).
+				Return(nil)
+
+			scraper := scrape.NewMockScraper(ctrl)
+			switch tc.job.Action {
+			case store.JobActionDeleteTag:
+				scraper.EXPECT().
+					DeleteTag(tc.job.Name, tc.job.Reference).
+					Return(tc.scrapeErr)
+			case store.JobActionScrape:
+				scraper.EXPECT().
+					ScrapeImage(gomock.Any()).
+					Return(nil)
+				scraper.EXPECT().
+					ScrapeLatestImage(gomock.Any()).
+					Return(nil)
+			}
+
+			q := newTestQueue(ctrl, jobStore, scraper, now)
+			assert.Equal(t, tc.expectedResult, q.processNext())
+		})
+	}
+}
+
+func TestQueue_processNext_NoJob(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Date(2019, 6, 21, 10, 0, 0, 0, time.UTC)
+	jobStore := mock.NewMockJobStore(ctrl)
+	jobStore.EXPECT().
+		Claim(now, now.Add(time.Minute)).
+		Return(nil, store.ErrDoesNotExist)
+
+	q := newTestQueue(ctrl, jobStore, scrape.NewMockScraper(ctrl), now)
+	assert.False(t, q.processNext())
+}
+
+func TestQueue_backoff(t *testing.T) {
+	q := &queue{opts: Opts{BackoffBase: 10 * time.Second, BackoffMax: time.Minute}}
+	assert.Equal(t, 10*time.Second, q.backoff(1))
+	assert.Equal(t, 20*time.Second, q.backoff(2))
+	assert.Equal(t, 40*time.Second, q.backoff(3))
+	assert.Equal(t, time.Minute, q.backoff(4))
+	assert.Equal(t, time.Minute, q.backoff(20))
+}
+
+func TestQueue_cleanup(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Date(2019, 6, 21, 10, 0, 0, 0, time.UTC)
+	jobStore := mock.NewMockJobStore(ctrl)
+	jobStore.EXPECT().
+		DeleteDone(now.Add(-24*time.Hour)).
+		Return(2, nil)
+
+	q := newTestQueue(ctrl, jobStore, scrape.NewMockScraper(ctrl), now)
+	q.opts.Retention = 24 * time.Hour
+	q.cleanup()
+}
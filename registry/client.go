@@ -0,0 +1,103 @@
+package registry
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/manifestlist"
+	"github.com/docker/distribution/manifest/schema1"
+	"github.com/docker/distribution/manifest/schema2"
+	reg "github.com/genuinetools/reg/registry"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+var manifestMediaTypes = []string{
+	ocispec.MediaTypeImageIndex,
+	ocispec.MediaTypeImageManifest,
+	manifestlist.MediaTypeManifestList,
+	schema2.MediaTypeManifest,
+	schema1.MediaTypeSignedManifest,
+}
+
+var manifestAcceptHeader = strings.Join(manifestMediaTypes, ",")
+
+// IsManifestMediaType returns true if the registry package can parse manifests of the media type.
+func IsManifestMediaType(mediaType string) bool {
+	for _, mt := range manifestMediaTypes {
+		if mt == mediaType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// fetchManifest retrieves the manifest identified by ref. Unlike the manifest functions of reg it accepts all manifest
+// types the registry package can parse, including OCI image indexes and OCI image manifests.
+// The digest is taken from the Docker-Content-Digest header and calculated from the payload if the header is missing.
+func fetchManifest(regClient *reg.Registry, repository, ref string) (distribution.Manifest, digest.Digest, error) {
+	uri := fmt.Sprintf("%s/v2/%s/manifests/%s", regClient.URL, repository, ref)
+	log.Debugf("Fetching manifest %s", uri)
+	req, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	req.Header.Set("Accept", manifestAcceptHeader)
+	resp, err := regClient.Client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("fetching manifest %s of repository %s failed with status code %d", ref, repository, resp.StatusCode)
+	}
+
+	payload, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	m, _, err := distribution.UnmarshalManifest(resp.Header.Get("Content-Type"), payload)
+	if err != nil {
+		return nil, "", err
+	}
+
+	d, err := digest.Parse(resp.Header.Get("Docker-Content-Digest"))
+	if err != nil {
+		d = digest.FromBytes(payload)
+	}
+
+	return m, d, nil
+}
+
+// fetchBlob retrieves the content of the blob identified by d.
+func fetchBlob(regClient *reg.Registry, repository string, d digest.Digest) ([]byte, error) {
+	uri := fmt.Sprintf("%s/v2/%s/blobs/%s", regClient.URL, repository, d)
+	log.Debugf("Fetching blob %s", uri)
+	resp, err := regClient.Client.Get(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching blob %s of repository %s failed with status code %d", d, repository, resp.StatusCode)
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.Validate() == nil && d.Algorithm().FromBytes(b) != d {
+		return nil, fmt.Errorf("content of blob %s of repository %s does not match its digest", d, repository)
+	}
+
+	return b, nil
+}
@@ -0,0 +1,251 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"sync"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+const dockerHubHost = "index.docker.io"
+
+// CredentialStore provides the credentials to authenticate against a registry.
+type CredentialStore interface {
+	// Credentials returns the username and password for host.
+	// It returns empty strings and no error if the store does not know the host.
+	Credentials(host string) (string, string, error)
+}
+
+// NormalizeHost converts the different notations of a registry host to the notation used by the registry package.
+// The scheme and path are removed and all aliases of Docker Hub are converted to index.docker.io.
+func NormalizeHost(host string) string {
+	h := strings.TrimPrefix(strings.TrimPrefix(host, "https://"), "http://")
+	h = strings.SplitN(h, "/", 2)[0]
+	switch h {
+	case "", "docker.io", "registry-1.docker.io", dockerHubHost:
+		return dockerHubHost
+	}
+
+	return h
+}
+
+type chainCredentialStore struct {
+	stores []CredentialStore
+}
+
+// ChainCredentialStores returns a CredentialStore that queries the given stores in order
+// and returns the first credentials found.
+func ChainCredentialStores(stores ...CredentialStore) CredentialStore {
+	return &chainCredentialStore{stores: stores}
+}
+
+func (c *chainCredentialStore) Credentials(host string) (string, string, error) {
+	for _, s := range c.stores {
+		username, password, err := s.Credentials(host)
+		if err != nil {
+			return "", "", err
+		}
+
+		if username != "" || password != "" {
+			return username, password, nil
+		}
+	}
+
+	return "", "", nil
+}
+
+type fileCredentials struct {
+	Password string `json:"password" yaml:"password"`
+	Username string `json:"username" yaml:"username"`
+}
+
+type fileCredentialStore struct {
+	credentials map[string]fileCredentials
+}
+
+// NewFileCredentialStore reads credentials from a YAML or JSON file.
+// The file maps registry hosts to a username and a password:
+//
+//   registry.example.com:
+//     username: reguser
+//     password: secret
+func NewFileCredentialStore(path string) (CredentialStore, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading credentials file %s: %s", path, err)
+	}
+
+	raw := map[string]fileCredentials{}
+	err = yaml.Unmarshal(b, &raw)
+	if err != nil {
+		return nil, fmt.Errorf("parsing credentials file %s: %s", path, err)
+	}
+
+	s := &fileCredentialStore{credentials: map[string]fileCredentials{}}
+	for host, c := range raw {
+		s.credentials[NormalizeHost(host)] = c
+	}
+
+	return s, nil
+}
+
+func (f *fileCredentialStore) Credentials(host string) (string, string, error) {
+	c := f.credentials[NormalizeHost(host)]
+	return c.Username, c.Password, nil
+}
+
+type dockerConfigAuth struct {
+	Auth          string `json:"auth"`
+	IdentityToken string `json:"identitytoken"`
+	Password      string `json:"password"`
+	Username      string `json:"username"`
+}
+
+type dockerConfig struct {
+	Auths       map[string]dockerConfigAuth `json:"auths"`
+	CredHelpers map[string]string           `json:"credHelpers"`
+	CredsStore  string                      `json:"credsStore"`
+}
+
+type dockerCredentials struct {
+	password string
+	username string
+}
+
+type dockerConfigCredentialStore struct {
+	auths       map[string]dockerCredentials
+	cache       map[string]dockerCredentials
+	cacheMutex  *sync.Mutex
+	credHelpers map[string]string
+	credsStore  string
+	execHelper  func(helper string, host string) (string, string, error)
+}
+
+// NewDockerConfigCredentialStore reads credentials from a config.json file of the Docker CLI.
+// Credentials of a host are read from "credHelpers" first, then from "auths" and then from "credsStore".
+func NewDockerConfigCredentialStore(path string) (CredentialStore, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading docker config %s: %s", path, err)
+	}
+
+	dc := &dockerConfig{}
+	err = json.Unmarshal(b, dc)
+	if err != nil {
+		return nil, fmt.Errorf("parsing docker config %s: %s", path, err)
+	}
+
+	s := &dockerConfigCredentialStore{
+		auths:       map[string]dockerCredentials{},
+		cache:       map[string]dockerCredentials{},
+		cacheMutex:  &sync.Mutex{},
+		credHelpers: map[string]string{},
+		credsStore:  dc.CredsStore,
+		execHelper:  execCredentialHelper,
+	}
+	for host, a := range dc.Auths {
+		c := dockerCredentials{password: a.Password, username: a.Username}
+		if a.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(a.Auth)
+			if err != nil {
+				return nil, fmt.Errorf("decoding auth of %s in docker config %s: %s", host, path, err)
+			}
+
+			parts := strings.SplitN(string(decoded), ":", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("auth of %s in docker config %s is not in the format username:password", host, path)
+			}
+
+			c.username = parts[0]
+			c.password = parts[1]
+		}
+
+		if a.IdentityToken != "" {
+			c.password = a.IdentityToken
+		}
+
+		s.auths[NormalizeHost(host)] = c
+	}
+
+	for host, helper := range dc.CredHelpers {
+		s.credHelpers[NormalizeHost(host)] = helper
+	}
+
+	return s, nil
+}
+
+func (d *dockerConfigCredentialStore) Credentials(host string) (string, string, error) {
+	h := NormalizeHost(host)
+	helper, ok := d.credHelpers[h]
+	if ok {
+		return d.credentialsFromHelper(helper, h)
+	}
+
+	c, ok := d.auths[h]
+	if ok {
+		return c.username, c.password, nil
+	}
+
+	if d.credsStore != "" {
+		return d.credentialsFromHelper(d.credsStore, h)
+	}
+
+	return "", "", nil
+}
+
+// credentialsFromHelper caches the result of a credential helper to avoid executing it for every new registry client.
+func (d *dockerConfigCredentialStore) credentialsFromHelper(helper string, host string) (string, string, error) {
+	d.cacheMutex.Lock()
+	defer d.cacheMutex.Unlock()
+	c, ok := d.cache[host]
+	if ok {
+		return c.username, c.password, nil
+	}
+
+	serverURL := host
+	if host == dockerHubHost {
+		serverURL = "https://index.docker.io/v1/"
+	}
+
+	username, password, err := d.execHelper(helper, serverURL)
+	if err != nil {
+		return "", "", err
+	}
+
+	d.cache[host] = dockerCredentials{password: password, username: username}
+	return username, password, nil
+}
+
+// execCredentialHelper executes the Docker credential helper docker-credential-<helper>.
+func execCredentialHelper(helper string, serverURL string) (string, string, error) {
+	cmd := exec.Command("docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(serverURL)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		// Helpers report unknown hosts on stdout and exit with a non-zero code.
+		if strings.Contains(string(out), "credentials not found") {
+			return "", "", nil
+		}
+
+		return "", "", fmt.Errorf("executing credential helper %s for %s: %s %s", helper, serverURL, err, stderr.String())
+	}
+
+	result := struct {
+		Secret   string
+		Username string
+	}{}
+	err = json.Unmarshal(out, &result)
+	if err != nil {
+		return "", "", fmt.Errorf("parsing output of credential helper %s for %s: %s", helper, serverURL, err)
+	}
+
+	return result.Username, result.Secret, nil
+}
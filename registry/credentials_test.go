@@ -0,0 +1,169 @@
+package registry
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTempFile(t *testing.T, dir, name, content string) string {
+	p := filepath.Join(dir, name)
+	err := ioutil.WriteFile(p, []byte(content), 0600)
+	require.NoError(t, err)
+	return p
+}
+
+func TestNormalizeHost(t *testing.T) {
+	testcases := []struct {
+		host     string
+		expected string
+	}{
+		{"", "index.docker.io"},
+		{"docker.io", "index.docker.io"},
+		{"https://index.docker.io/v1/", "index.docker.io"},
+		{"registry-1.docker.io", "index.docker.io"},
+		{"ghcr.io", "ghcr.io"},
+		{"https://registry.example.com:5000", "registry.example.com:5000"},
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, tc.expected, NormalizeHost(tc.host), tc.host)
+	}
+}
+
+func TestFileCredentialStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "credentials")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	testcases := []struct {
+		content string
+		name    string
+	}{
+		{"docker.io:\n  username: hubuser\n  password: hubsecret\nghcr.io:\n  username: ghuser\n  password: ghsecret\n", "credentials.yaml"},
+		{`{"docker.io": {"username": "hubuser", "password": "hubsecret"}, "ghcr.io": {"username": "ghuser", "password": "ghsecret"}}`, "credentials.json"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := NewFileCredentialStore(writeTempFile(t, dir, tc.name, tc.content))
+			require.NoError(t, err)
+
+			username, password, err := s.Credentials("index.docker.io")
+			assert.NoError(t, err)
+			assert.Equal(t, "hubuser", username)
+			assert.Equal(t, "hubsecret", password)
+
+			username, password, err = s.Credentials("ghcr.io")
+			assert.NoError(t, err)
+			assert.Equal(t, "ghuser", username)
+			assert.Equal(t, "ghsecret", password)
+
+			username, password, err = s.Credentials("quay.io")
+			assert.NoError(t, err)
+			assert.Empty(t, username)
+			assert.Empty(t, password)
+		})
+	}
+}
+
+func TestDockerConfigCredentialStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "credentials")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// "aHVidXNlcjpodWI6c2VjcmV0" is the base64 encoding of "hubuser:hub:secret".
+	p := writeTempFile(t, dir, "config.json", `{
+  "auths": {
+    "https://index.docker.io/v1/": {"auth": "aHVidXNlcjpodWI6c2VjcmV0"},
+    "quay.io": {"username": "quayuser", "password": "quaysecret"}
+  },
+  "credHelpers": {"ghcr.io": "gh"},
+  "credsStore": "desktop"
+}`)
+	s, err := NewDockerConfigCredentialStore(p)
+	require.NoError(t, err)
+
+	helperCalls := []string{}
+	dcs := s.(*dockerConfigCredentialStore)
+	dcs.execHelper = func(helper string, serverURL string) (string, string, error) {
+		helperCalls = append(helperCalls, helper+" "+serverURL)
+		return helper + "user", helper + "secret", nil
+	}
+
+	testcases := []struct {
+		host             string
+		expectedUsername string
+		expectedPassword string
+	}{
+		{"index.docker.io", "hubuser", "hub:secret"},
+		{"quay.io", "quayuser", "quaysecret"},
+		{"ghcr.io", "ghuser", "ghsecret"},
+		{"ghcr.io", "ghuser", "ghsecret"},
+		{"registry.example.com", "desktopuser", "desktopsecret"},
+	}
+	for _, tc := range testcases {
+		username, password, err := s.Credentials(tc.host)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expectedUsername, username, tc.host)
+		assert.Equal(t, tc.expectedPassword, password, tc.host)
+	}
+
+	assert.Equal(t, []string{"gh ghcr.io", "desktop registry.example.com"}, helperCalls)
+}
+
+func TestChainCredentialStores(t *testing.T) {
+	dir, err := ioutil.TempDir("", "credentials")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	first, err := NewFileCredentialStore(writeTempFile(t, dir, "first.yaml", "ghcr.io:\n  username: first\n  password: first\n"))
+	require.NoError(t, err)
+	second, err := NewFileCredentialStore(writeTempFile(t, dir, "second.yaml", "ghcr.io:\n  username: second\n  password: second\nquay.io:\n  username: second\n  password: second\n"))
+	require.NoError(t, err)
+
+	s := ChainCredentialStores(first, second)
+	username, _, err := s.Credentials("ghcr.io")
+	assert.NoError(t, err)
+	assert.Equal(t, "first", username)
+	username, _, err = s.Credentials("quay.io")
+	assert.NoError(t, err)
+	assert.Equal(t, "second", username)
+}
+
+func TestRegistry_Credentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "credentials")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	store, err := NewFileCredentialStore(writeTempFile(t, dir, "credentials.yaml", "ghcr.io:\n  username: ghuser\n  password: ghsecret\n"))
+	require.NoError(t, err)
+	r, err := NewRegistry("https://registry.example.com", Opts{Credentials: store, Password: "secret", Username: "reguser"})
+	require.NoError(t, err)
+
+	testcases := []struct {
+		image            string
+		expectedUsername string
+		expectedPassword string
+		testName         string
+	}{
+		{"registry.example.com/team/app:1.0.0", "reguser", "secret", "Address of the registry"},
+		{"ghcr.io/team/app:1.0.0", "ghuser", "ghsecret", "Host in the credential store"},
+		{"quay.io/team/app:1.0.0", "", "", "Other host"},
+		{"team/app:1.0.0", "", "", "Docker Hub"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.testName, func(t *testing.T) {
+			repo, err := r.Repository(tc.image)
+			require.NoError(t, err)
+			client := repo.(*repository).regClient
+			assert.Equal(t, tc.expectedUsername, client.Username)
+			assert.Equal(t, tc.expectedPassword, client.Password)
+		})
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"fmt"
 
 	"github.com/docker/distribution/manifest/manifestlist"
+	"github.com/docker/distribution/manifest/ocischema"
 	"github.com/docker/distribution/manifest/schema1"
 	"github.com/docker/distribution/manifest/schema2"
 	reg "github.com/genuinetools/reg/registry"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 )
 
 type image struct {
@@ -78,41 +80,37 @@ func (i *image) Tag() (string, error) {
 
 func (i *image) populate() error {
 	log.Debug("Populating image")
-	if i.parsed.Digest.String() == "" {
-		d, err := i.regClient.Digest(i.parsed)
-		if err != nil {
-			return err
-		}
+	rawManifest, manifestDigest, err := fetchManifest(i.regClient, i.parsed.Path, i.parsed.Reference())
+	if err != nil {
+		return err
+	}
 
-		err = i.parsed.WithDigest(d)
+	if i.parsed.Digest.String() == "" {
+		err = i.parsed.WithDigest(manifestDigest)
 		if err != nil {
 			return err
 		}
 	}
 
-	rawManifest, err := i.regClient.Manifest(i.parsed.Path, i.parsed.Tag)
-	if err != nil {
-		return err
-	}
-
 	switch manifest := rawManifest.(type) {
 	case *schema2.DeserializedManifest:
 		i.schemaVersion = manifest.SchemaVersion
-		p := &PlatformV2{
-			architecture: "amd64",
-			digest:       "",
-			image:        i,
-			os:           "linux",
-			osVersion:    "",
-			regClient:    i.regClient,
-			variant:      "",
-		}
+		p := i.newDefaultPlatformV2()
 		p.manifest = NewManifestV2(manifest.Manifest, p)
-
+		i.platforms = append(i.platforms, p)
+	case *ocischema.DeserializedManifest:
+		i.schemaVersion = manifest.SchemaVersion
+		p := i.newDefaultPlatformV2()
+		p.manifest = NewManifestOCI(manifest.Manifest, p)
 		i.platforms = append(i.platforms, p)
 	case *manifestlist.DeserializedManifestList:
 		i.schemaVersion = manifest.SchemaVersion
 		for _, platformManifest := range manifest.Manifests {
+			if !isPlatformManifest(platformManifest) {
+				log.Debugf("Skipping manifest %s of image %s", platformManifest.Digest, i.parsed.String())
+				continue
+			}
+
 			i.platforms = append(i.platforms, &PlatformV2{
 				architecture: platformManifest.Platform.Architecture,
 				digest:       platformManifest.Digest,
@@ -127,17 +125,12 @@ func (i *image) populate() error {
 		}
 	case *schema1.SignedManifest:
 		i.schemaVersion = manifest.SchemaVersion
-		d, err := i.regClient.Digest(i.parsed)
-		if err != nil {
-			return err
-		}
-
 		p := &PlatformV1{
 			digest:    "",
 			image:     i,
 			regClient: i.regClient,
 		}
-		m, err := NewManifestV1(p, manifest, d)
+		m, err := NewManifestV1(p, manifest, i.parsed.Digest)
 		if err != nil {
 			return err
 		}
@@ -151,3 +144,26 @@ func (i *image) populate() error {
 	i.populated = true
 	return nil
 }
+
+// newDefaultPlatformV2 returns the platform of an image that consists of a single manifest.
+func (i *image) newDefaultPlatformV2() *PlatformV2 {
+	return &PlatformV2{
+		architecture: "amd64",
+		digest:       "",
+		image:        i,
+		os:           "linux",
+		osVersion:    "",
+		regClient:    i.regClient,
+		variant:      "",
+	}
+}
+
+// isPlatformManifest reports whether an entry of a manifest list or an OCI image index describes a runnable image.
+// BuildKit adds attestation manifests to an index that use "unknown" as their architecture and OS.
+func isPlatformManifest(d manifestlist.ManifestDescriptor) bool {
+	if d.MediaType != "" && d.MediaType != schema2.MediaTypeManifest && d.MediaType != ocispec.MediaTypeImageManifest {
+		return false
+	}
+
+	return d.Platform.Architecture != "unknown" && d.Platform.OS != "unknown"
+}
@@ -1,15 +1,24 @@
 package registry
 
 import (
+	"time"
+
 	dockerImage "github.com/docker/docker/image"
 	digest "github.com/opencontainers/go-digest"
 )
 
 type Config interface {
+	Created() time.Time
+	DiffIDs() []digest.Digest
 	Digest() digest.Digest
+	Entrypoint() []string
+	Env() []string
+	ExposedPorts() []string
 	History() ([]dockerImage.History, error)
+	Labels() map[string]string
 	MediaType() string
 	Size() int
+	User() string
 }
 
 type Image interface {
@@ -47,6 +56,7 @@ type Platform interface {
 
 type Registry interface {
 	Address() string
+	Repositories() ([]Repository, error)
 	Repository(imageName string) (Repository, error)
 	Image(imageName string) (Image, error)
 }
@@ -2,6 +2,7 @@ package mock
 
 import (
 	"fmt"
+	"sort"
 	"time"
 
 	dockerImage "github.com/docker/docker/image"
@@ -101,6 +102,21 @@ func (m *mockRegistry) Image(imageName string) (registry.Image, error) {
 	return nil, fmt.Errorf("Unknown reference for %s", imageName)
 }
 
+func (m *mockRegistry) Repositories() ([]registry.Repository, error) {
+	names := []string{}
+	for name := range m.repositories {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	repositories := []registry.Repository{}
+	for _, name := range names {
+		repositories = append(repositories, m.repositories[name])
+	}
+
+	return repositories, nil
+}
+
 func (m *mockRegistry) Repository(imageName string) (registry.Repository, error) {
 	p, err := reg.ParseImage(imageName)
 	if err != nil {
@@ -197,7 +213,8 @@ func (m *mockPlatform) Variant() string {
 func NewPlatform(arch string, digest string, layers []string, manifestDigest string, os string, historyCreated time.Time) *mockPlatform {
 	manifest := &mockManifest{
 		config: &mockConfig{
-			digest: manifestDigest,
+			created: historyCreated,
+			digest:  manifestDigest,
 			history: []dockerImage.History{
 				{Created: historyCreated},
 			},
@@ -217,8 +234,9 @@ func NewPlatform(arch string, digest string, layers []string, manifestDigest str
 
 type mockManifest struct {
 	registry.Manifest
-	config registry.Config
-	layers []registry.Layer
+	config    registry.Config
+	layers    []registry.Layer
+	mediaType string
 }
 
 func (m *mockManifest) Config() (registry.Config, error) {
@@ -229,16 +247,45 @@ func (m *mockManifest) Layers() []registry.Layer {
 	return m.layers
 }
 
+func (m *mockManifest) MediaType() string {
+	return m.mediaType
+}
+
 type mockConfig struct {
 	registry.Config
+	created time.Time
 	digest  string
 	history []dockerImage.History
 }
 
+func (m *mockConfig) Created() time.Time {
+	return m.created
+}
+
 func (m *mockConfig) Digest() digest.Digest {
 	return digest.Digest(m.digest)
 }
 
+func (m *mockConfig) Entrypoint() []string {
+	return nil
+}
+
+func (m *mockConfig) Env() []string {
+	return nil
+}
+
+func (m *mockConfig) ExposedPorts() []string {
+	return nil
+}
+
+func (m *mockConfig) Labels() map[string]string {
+	return nil
+}
+
+func (m *mockConfig) User() string {
+	return ""
+}
+
 func (m *mockConfig) History() ([]dockerImage.History, error) {
 	return m.history, nil
 }
@@ -251,3 +298,11 @@ type mockLayer struct {
 func (m *mockLayer) Digest() (string, error) {
 	return m.digest, nil
 }
+
+func (m *mockLayer) MediaType() string {
+	return ""
+}
+
+func (m *mockLayer) Size() int {
+	return 0
+}
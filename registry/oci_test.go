@@ -0,0 +1,150 @@
+package registry
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testRegistryContent struct {
+	mediaType string
+	payload   []byte
+}
+
+// newTestRegistry starts a registry that serves the given manifests and blobs of the repository "unit/test".
+// Manifests are served by tag and by digest.
+func newTestRegistry(t *testing.T, manifests map[string]testRegistryContent, blobs map[digest.Digest][]byte) *httptest.Server {
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/v2/unit/test/manifests/") {
+			ref := strings.TrimPrefix(r.URL.Path, "/v2/unit/test/manifests/")
+			for tag, c := range manifests {
+				d := digest.FromBytes(c.payload)
+				if ref == tag || ref == d.String() {
+					w.Header().Set("Content-Type", c.mediaType)
+					w.Header().Set("Docker-Content-Digest", d.String())
+					w.Write(c.payload)
+					return
+				}
+			}
+		}
+
+		if strings.HasPrefix(r.URL.Path, "/v2/unit/test/blobs/") {
+			d := digest.Digest(strings.TrimPrefix(r.URL.Path, "/v2/unit/test/blobs/"))
+			b, ok := blobs[d]
+			if ok {
+				w.Write(b)
+				return
+			}
+		}
+
+		w.WriteHeader(http.StatusNotFound)
+	}))
+}
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+	return b
+}
+
+func TestImageOCI(t *testing.T) {
+	config := mustMarshal(t, map[string]interface{}{
+		"architecture": "arm64",
+		"os":           "linux",
+		"history": []map[string]interface{}{
+			{"created": "2019-06-01T10:00:00Z", "created_by": "ADD file:abc in /"},
+			{"created": "2019-06-02T10:00:00Z", "created_by": "RUN make", "empty_layer": true},
+		},
+	})
+	configDigest := digest.FromBytes(config)
+	layerDigest := digest.FromString("layer")
+	platformManifest := mustMarshal(t, map[string]interface{}{
+		"schemaVersion": 2,
+		"mediaType":     ocispec.MediaTypeImageManifest,
+		"config":        map[string]interface{}{"mediaType": ocispec.MediaTypeImageConfig, "digest": configDigest, "size": len(config)},
+		"layers": []map[string]interface{}{
+			{"mediaType": ocispec.MediaTypeImageLayerGzip, "digest": layerDigest, "size": 1024},
+		},
+	})
+	platformManifestDigest := digest.FromBytes(platformManifest)
+	attestationDigest := digest.FromString("attestation")
+	index := mustMarshal(t, map[string]interface{}{
+		"schemaVersion": 2,
+		"mediaType":     ocispec.MediaTypeImageIndex,
+		"manifests": []map[string]interface{}{
+			{
+				"mediaType": ocispec.MediaTypeImageManifest,
+				"digest":    platformManifestDigest,
+				"size":      len(platformManifest),
+				"platform":  map[string]interface{}{"architecture": "arm64", "os": "linux", "variant": "v8"},
+			},
+			{
+				"mediaType": ocispec.MediaTypeImageManifest,
+				"digest":    attestationDigest,
+				"size":      100,
+				"platform":  map[string]interface{}{"architecture": "unknown", "os": "unknown"},
+			},
+		},
+	})
+
+	srv := newTestRegistry(
+		t,
+		map[string]testRegistryContent{
+			"1.0.0": {mediaType: ocispec.MediaTypeImageIndex, payload: index},
+			"arm64": {mediaType: ocispec.MediaTypeImageManifest, payload: platformManifest},
+		},
+		map[digest.Digest][]byte{configDigest: config},
+	)
+	defer srv.Close()
+
+	address := strings.TrimPrefix(srv.URL, "https://")
+	img, err := NewImage(address+"/unit/test:1.0.0", Opts{Insecure: true})
+	require.NoError(t, err)
+
+	d, err := img.Digest()
+	require.NoError(t, err)
+	assert.Equal(t, digest.FromBytes(index).String(), d)
+
+	platforms, err := img.Platforms()
+	require.NoError(t, err)
+	require.Len(t, platforms, 1)
+	assert.Equal(t, "arm64", platforms[0].Architecture())
+	assert.Equal(t, "v8", platforms[0].Variant())
+
+	m, err := platforms[0].Manifest()
+	require.NoError(t, err)
+	assert.Equal(t, ocispec.MediaTypeImageManifest, m.MediaType())
+	require.Len(t, m.Layers(), 1)
+	ld, err := m.Layers()[0].Digest()
+	require.NoError(t, err)
+	assert.Equal(t, layerDigest.String(), ld)
+	assert.Equal(t, ocispec.MediaTypeImageLayerGzip, m.Layers()[0].MediaType())
+
+	c, err := m.Config()
+	require.NoError(t, err)
+	assert.Equal(t, configDigest, c.Digest())
+	assert.Equal(t, ocispec.MediaTypeImageConfig, c.MediaType())
+	history, err := c.History()
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, "RUN make", history[1].CreatedBy)
+
+	single, err := NewImage(address+"/unit/test:arm64", Opts{Insecure: true})
+	require.NoError(t, err)
+	platforms, err = single.Platforms()
+	require.NoError(t, err)
+	require.Len(t, platforms, 1)
+	m, err = platforms[0].Manifest()
+	require.NoError(t, err)
+	assert.Equal(t, ocispec.MediaTypeImageManifest, m.MediaType())
+	d, err = single.Digest()
+	require.NoError(t, err)
+	assert.Equal(t, platformManifestDigest.String(), d)
+}
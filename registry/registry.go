@@ -1,6 +1,11 @@
 package registry
 
 import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"sync"
+
 	"github.com/docker/docker/api/types"
 	reg "github.com/genuinetools/reg/registry"
 	digest "github.com/opencontainers/go-digest"
@@ -9,13 +14,21 @@ import (
 const digestSHA256GzippedEmptyTar = digest.Digest("sha256:a3ed95caeb02ffe68cdd9fd84406680ae93d633cb16422d00e8a7c22955b46d4")
 
 type Opts struct {
-	Insecure bool
-	Password string
-	Username string
+	// Credentials provides the credentials for each registry host.
+	// Username and Password are only used for the address of the registry if Credentials does not know it.
+	// Requests to other hosts are anonymous unless Credentials knows the host.
+	Credentials CredentialStore
+	Insecure    bool
+	Password    string
+	Username    string
 }
 
 type registry struct {
-	address   string
+	address string
+	// clients holds one client per registry host.
+	// Repositories of the same host share a client, so that its transport reuses cached tokens.
+	clients   map[string]*reg.Registry
+	mutex     sync.Mutex
 	opts      Opts
 	regClient *reg.Registry
 }
@@ -29,19 +42,46 @@ func newRegClient(address string, o Opts) (*reg.Registry, error) {
 		ServerAddress: address,
 		Username:      o.Username,
 	}
+	if o.Credentials != nil {
+		username, password, err := o.Credentials.Credentials(address)
+		if err != nil {
+			return nil, fmt.Errorf("reading credentials of registry %s: %s", address, err)
+		}
+
+		if username != "" || password != "" {
+			log.Debugf("Using credentials of credential store for registry %s", address)
+			auth.Password = password
+			auth.Username = username
+		}
+	}
 
 	regClient, err := reg.New(auth, reg.Opt{Insecure: o.Insecure, SkipPing: true})
 	if err != nil {
 		return nil, err
 	}
 
-	transport := &AuthTokenTransport{
-		Transport: regClient.Client.Transport,
+	// The transports of reg are replaced because its TokenTransport requests a new token for every request.
+	regClient.Client.Transport = &reg.ErrorTransport{
+		Transport: &AuthTokenTransport{
+			Password:  auth.Password,
+			Transport: baseTransport(o.Insecure),
+			Username:  auth.Username,
+		},
 	}
-	regClient.Client.Transport = transport
 	return regClient, nil
 }
 
+func baseTransport(insecure bool) http.RoundTripper {
+	if !insecure {
+		return http.DefaultTransport
+	}
+
+	return &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}
+}
+
 func NewRegistry(address string, o Opts) (Registry, error) {
 	regClient, err := newRegClient(address, o)
 	if err != nil {
@@ -50,6 +90,7 @@ func NewRegistry(address string, o Opts) (Registry, error) {
 
 	r := &registry{
 		address:   address,
+		clients:   map[string]*reg.Registry{NormalizeHost(address): regClient},
 		regClient: regClient,
 		opts:      o,
 	}
@@ -102,6 +143,7 @@ func (r *registry) Image(imageName string) (Image, error) {
 	return repo.Image(img.Digest.String(), img.Tag), nil
 }
 
+// Repositories returns all repositories listed in the catalog of the registry.
 func (r *registry) Repositories() ([]Repository, error) {
 	repositories := []Repository{}
 	catalogItems, err := r.regClient.Catalog("")
@@ -110,7 +152,8 @@ func (r *registry) Repositories() ([]Repository, error) {
 	}
 
 	for _, catalogItem := range catalogItems {
-		repo, err := r.Repository(catalogItem)
+		// Items of the catalog do not contain the domain of the registry.
+		repo, err := r.Repository(r.regClient.Domain + "/" + catalogItem)
 		if err != nil {
 			return nil, err
 		}
@@ -127,7 +170,7 @@ func (r *registry) Repository(imageName string) (Repository, error) {
 		return nil, err
 	}
 
-	client, err := newRegClient(img.Domain, r.opts)
+	client, err := r.clientOf(img.Domain)
 	if err != nil {
 		return nil, err
 	}
@@ -138,6 +181,32 @@ func (r *registry) Repository(imageName string) (Repository, error) {
 	}, nil
 }
 
+// clientOf returns the client of a registry host and creates it on first use.
+func (r *registry) clientOf(host string) (*reg.Registry, error) {
+	host = NormalizeHost(host)
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	client, ok := r.clients[host]
+	if ok {
+		return client, nil
+	}
+
+	o := r.opts
+	if host != NormalizeHost(r.address) {
+		// The credentials of the registry must not be sent to the hosts named in image references.
+		o.Password = ""
+		o.Username = ""
+	}
+
+	client, err := newRegClient(host, o)
+	if err != nil {
+		return nil, err
+	}
+
+	r.clients[host] = client
+	return client, nil
+}
+
 func ParseImage(n string) (string, string, string, string, error) {
 	i, err := reg.ParseImage(n)
 	if err != nil {
@@ -2,7 +2,11 @@ package registry
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -130,3 +134,39 @@ func TestImageV2(t *testing.T) {
 		})
 	}
 }
+
+// newTestTagsRegistry starts a registry that lists the tags of a repository if the request contains a token issued by tokenServer.
+func newTestTagsRegistry(tokenServer *httptest.Server) *httptest.Server {
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scope := scopeOfPath(r.URL.Path)
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer "+scope+"-") {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="unit-test",scope="%s"`, tokenServer.URL, scope))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"tags": ["1.0.0"]}`))
+	}))
+}
+
+func TestRegistry_Repository_SharesTokens(t *testing.T) {
+	ts := &testTokenServer{expiresIn: 300}
+	tokenSrv := httptest.NewServer(ts)
+	defer tokenSrv.Close()
+	registrySrv := newTestTagsRegistry(tokenSrv)
+	defer registrySrv.Close()
+
+	host := strings.TrimPrefix(registrySrv.URL, "https://")
+	r, err := NewRegistry(host, Opts{Insecure: true, Password: "secret", Username: "reguser"})
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		repo, err := r.Repository(host + "/unit/test")
+		require.NoError(t, err)
+		images, err := repo.Images()
+		require.NoError(t, err)
+		assert.Len(t, images, 1)
+	}
+
+	assert.Equal(t, 1, ts.requestCount(), "repositories of the same host share the token")
+}
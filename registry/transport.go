@@ -1,36 +1,379 @@
 package registry
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
 	"time"
 )
 
-// AuthTokenTransport defines the data structure for custom http.Request options.
+const (
+	// defaultTokenLifetime is used if the token server omits expires_in.
+	// It is the minimum lifetime defined in the Docker token authentication specification.
+	defaultTokenLifetime = 60 * time.Second
+	// tokenRefreshLeeway is the time before the expiry of a token at which a new token gets requested.
+	tokenRefreshLeeway = 10 * time.Second
+)
+
+var repositoryPathRegexp = regexp.MustCompile("^/v2/(.+)/(manifests|blobs|tags)/")
+
+type challenge struct {
+	params map[string]string
+	scheme string
+}
+
+type token struct {
+	expiresAt time.Time
+	lifetime  time.Duration
+	value     string
+}
+
+type tokenEntry struct {
+	mutex sync.Mutex
+	token *token
+}
+
+// AuthTokenTransport authenticates requests against a registry.
+// It answers Basic and Bearer challenges sent in the WWW-Authenticate header of a response.
+// Bearer tokens are cached per scope, honor expires_in and are refreshed shortly before they expire.
+// It is safe for concurrent use.
 type AuthTokenTransport struct {
+	Password  string
 	Transport http.RoundTripper
-	authToken string
-	expiredAt time.Time
+	Username  string
+
+	challenges map[string]*challenge
+	mutex      sync.Mutex
+	nowFunc    func() time.Time
+	tokens     map[string]*tokenEntry
 }
 
-// RoundTrip defines the round tripper for the error transport.
+// RoundTrip defines the round tripper for the auth token transport.
 func (t *AuthTokenTransport) RoundTrip(request *http.Request) (*http.Response, error) {
-	if t.authToken != "" && t.isFresh() && request.Header.Get("Authorization") == "" {
-		request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", t.authToken))
+	if request.Header.Get("Authorization") != "" {
+		return t.Transport.RoundTrip(request)
 	}
 
+	c := t.challengeOf(request.URL.Host)
+	if c != nil {
+		authorized, err := t.authorize(request, c, scopeOfPath(request.URL.Path))
+		if err != nil {
+			return nil, err
+		}
+
+		return t.roundTripWithChallenge(authorized)
+	}
+
+	return t.roundTripWithChallenge(request)
+}
+
+// roundTripWithChallenge sends the request and retries it once if the registry answers with a challenge.
+func (t *AuthTokenTransport) roundTripWithChallenge(request *http.Request) (*http.Response, error) {
 	resp, err := t.Transport.RoundTrip(request)
-	if err == nil {
-		authToken := resp.Header.Get("request-token")
-		if authToken != "" {
-			t.authToken = authToken
-			t.expiredAt = time.Now().Add(4 * time.Minute)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	c, err := parseChallenge(resp.Header.Get("WWW-Authenticate"))
+	if err != nil || c == nil {
+		return resp, nil
+	}
+
+	if request.Body != nil && request.GetBody == nil {
+		return resp, nil
+	}
+
+	t.setChallenge(request.URL.Host, c)
+	scope := c.params["scope"]
+	if scope == "" {
+		scope = scopeOfPath(request.URL.Path)
+	}
+
+	// The registry rejected the cached token sent with the request, e.g. because it has been revoked.
+	// Evict it so that the retry does not send it again.
+	if rejected := request.Header.Get("Authorization"); strings.HasPrefix(rejected, "Bearer ") {
+		t.evictToken(c, scope, strings.TrimPrefix(rejected, "Bearer "))
+	}
+
+	retry, err := t.authorize(request, c, scope)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	resp.Body.Close()
+	return t.Transport.RoundTrip(retry)
+}
+
+// authorize returns a copy of request with an Authorization header that answers the challenge c.
+func (t *AuthTokenTransport) authorize(request *http.Request, c *challenge, scope string) (*http.Request, error) {
+	r, err := cloneRequest(request)
+	if err != nil {
+		return nil, err
+	}
+
+	switch c.scheme {
+	case "basic":
+		if t.Username != "" || t.Password != "" {
+			r.SetBasicAuth(t.Username, t.Password)
+		}
+	case "bearer":
+		tk, err := t.token(c, scope)
+		if err != nil {
+			return nil, err
+		}
+
+		r.Header.Set("Authorization", "Bearer "+tk)
+	}
+
+	return r, nil
+}
+
+// token returns a cached token for scope or requests a new one if the cached token expires soon.
+func (t *AuthTokenTransport) token(c *challenge, scope string) (string, error) {
+	key := tokenKey(c, scope)
+	t.mutex.Lock()
+	if t.tokens == nil {
+		t.tokens = map[string]*tokenEntry{}
+	}
+
+	entry, ok := t.tokens[key]
+	if !ok {
+		entry = &tokenEntry{}
+		t.tokens[key] = entry
+	}
+	t.mutex.Unlock()
+
+	entry.mutex.Lock()
+	defer entry.mutex.Unlock()
+	if entry.token != nil && t.isFresh(entry.token) {
+		return entry.token.value, nil
+	}
+
+	tk, err := t.fetchToken(c, scope)
+	if err != nil {
+		return "", err
+	}
+
+	entry.token = tk
+	return tk.value, nil
+}
+
+// evictToken removes the cached token of scope if it is the token that the registry rejected.
+// A token that a concurrent request has fetched in the meantime is kept.
+func (t *AuthTokenTransport) evictToken(c *challenge, scope, value string) {
+	t.mutex.Lock()
+	entry, ok := t.tokens[tokenKey(c, scope)]
+	t.mutex.Unlock()
+	if !ok {
+		return
+	}
+
+	entry.mutex.Lock()
+	defer entry.mutex.Unlock()
+	if entry.token != nil && entry.token.value == value {
+		entry.token = nil
+	}
+}
+
+func (t *AuthTokenTransport) fetchToken(c *challenge, scope string) (*token, error) {
+	realm, err := url.Parse(c.params["realm"])
+	if err != nil {
+		return nil, fmt.Errorf("parsing realm of auth challenge: %s", err)
+	}
+
+	q := realm.Query()
+	if c.params["service"] != "" {
+		q.Set("service", c.params["service"])
+	}
+
+	for _, s := range strings.Fields(scope) {
+		q.Add("scope", s)
+	}
+
+	realm.RawQuery = q.Encode()
+	req, err := http.NewRequest("GET", realm.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.Username != "" || t.Password != "" {
+		req.SetBasicAuth(t.Username, t.Password)
+	}
+
+	log.Debugf("Requesting token for scope %s from %s", scope, realm.Host)
+	resp, err := t.Transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("requesting token for scope %s failed with status code %d", scope, resp.StatusCode)
+	}
+
+	result := struct {
+		AccessToken string    `json:"access_token"`
+		ExpiresIn   int       `json:"expires_in"`
+		IssuedAt    time.Time `json:"issued_at"`
+		Token       string    `json:"token"`
+	}{}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return nil, fmt.Errorf("decoding token response: %s", err)
+	}
+
+	tk := &token{value: result.Token}
+	if tk.value == "" {
+		tk.value = result.AccessToken
+	}
+
+	if tk.value == "" {
+		return nil, fmt.Errorf("token server returned an empty token for scope %s", scope)
+	}
+
+	tk.lifetime = defaultTokenLifetime
+	if result.ExpiresIn > 0 {
+		tk.lifetime = time.Duration(result.ExpiresIn) * time.Second
+	}
+
+	// issued_at is ignored because the clocks of the token server and imagespy might differ.
+	tk.expiresAt = t.now().Add(tk.lifetime)
+	return tk, nil
+}
+
+func (t *AuthTokenTransport) isFresh(tk *token) bool {
+	leeway := tokenRefreshLeeway
+	if tk.lifetime/2 < leeway {
+		leeway = tk.lifetime / 2
+	}
+
+	return t.now().Add(leeway).Before(tk.expiresAt)
+}
+
+func (t *AuthTokenTransport) challengeOf(host string) *challenge {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.challenges[host]
+}
+
+func (t *AuthTokenTransport) setChallenge(host string, c *challenge) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.challenges == nil {
+		t.challenges = map[string]*challenge{}
+	}
+
+	t.challenges[host] = c
+}
+
+func (t *AuthTokenTransport) now() time.Time {
+	if t.nowFunc != nil {
+		return t.nowFunc()
+	}
+
+	return time.Now()
+}
+
+func tokenKey(c *challenge, scope string) string {
+	return c.params["realm"] + "|" + c.params["service"] + "|" + scope
+}
+
+// scopeOfPath derives the scope of a token from the path of a request to the registry API.
+func scopeOfPath(path string) string {
+	if path == "/v2/_catalog" {
+		return "registry:catalog:*"
+	}
+
+	matches := repositoryPathRegexp.FindStringSubmatch(path)
+	if len(matches) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("repository:%s:pull", matches[1])
+}
+
+// parseChallenge parses the value of a WWW-Authenticate header, e.g.
+// Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/golang:pull".
+func parseChallenge(header string) (*challenge, error) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return nil, nil
+	}
+
+	parts := strings.SplitN(header, " ", 2)
+	c := &challenge{
+		params: map[string]string{},
+		scheme: strings.ToLower(parts[0]),
+	}
+	if c.scheme != "basic" && c.scheme != "bearer" {
+		return nil, fmt.Errorf("unsupported auth scheme %s", parts[0])
+	}
+
+	if len(parts) == 1 {
+		return c, nil
+	}
+
+	rest := parts[1]
+	for rest != "" {
+		rest = strings.TrimLeft(rest, " ,")
+		eq := strings.Index(rest, "=")
+		if eq == -1 {
+			break
+		}
+
+		key := strings.ToLower(strings.TrimSpace(rest[:eq]))
+		rest = rest[eq+1:]
+		var value string
+		if strings.HasPrefix(rest, "\"") {
+			end := strings.Index(rest[1:], "\"")
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated quoted value of %s in auth challenge", key)
+			}
+
+			value = rest[1 : end+1]
+			rest = rest[end+2:]
+		} else {
+			end := strings.Index(rest, ",")
+			if end == -1 {
+				end = len(rest)
+			}
+
+			value = strings.TrimSpace(rest[:end])
+			rest = rest[end:]
 		}
+
+		c.params[key] = value
 	}
 
-	return resp, err
+	if c.scheme == "bearer" && c.params["realm"] == "" {
+		return nil, fmt.Errorf("bearer auth challenge does not contain a realm")
+	}
+
+	return c, nil
 }
 
-func (t *AuthTokenTransport) isFresh() bool {
-	return time.Now().Before(t.expiredAt)
+// cloneRequest returns a copy of r with its own headers and a fresh body, as a RoundTripper must not modify a request.
+func cloneRequest(r *http.Request) (*http.Request, error) {
+	c := new(http.Request)
+	*c = *r
+	c.Header = make(http.Header, len(r.Header))
+	for k, v := range r.Header {
+		c.Header[k] = append([]string(nil), v...)
+	}
+
+	if r.Body != nil && r.GetBody != nil {
+		body, err := r.GetBody()
+		if err != nil {
+			return nil, err
+		}
+
+		c.Body = body
+	}
+
+	return c, nil
 }
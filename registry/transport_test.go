@@ -0,0 +1,239 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testTokenServer struct {
+	expiresIn int
+	mutex     sync.Mutex
+	requests  []string
+}
+
+func (s *testTokenServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	username, password, _ := r.BasicAuth()
+	if username != "reguser" || password != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	s.mutex.Lock()
+	s.requests = append(s.requests, r.URL.Query().Get("service")+" "+r.URL.Query().Get("scope"))
+	n := len(s.requests)
+	s.mutex.Unlock()
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"expires_in": s.expiresIn,
+		"token":      fmt.Sprintf("%s-%d", r.URL.Query().Get("scope"), n),
+	})
+}
+
+func (s *testTokenServer) requestCount() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.requests)
+}
+
+// newTestTokenRegistry starts a registry that only accepts tokens issued by tokenServer for the scope of a request.
+func newTestTokenRegistry(tokenServer *httptest.Server) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scope := scopeOfPath(r.URL.Path)
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer "+scope+"-") {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="unit-test",scope="%s"`, tokenServer.URL, scope))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Write([]byte("ok"))
+	}))
+}
+
+func TestAuthTokenTransport_Bearer(t *testing.T) {
+	ts := &testTokenServer{expiresIn: 300}
+	tokenSrv := httptest.NewServer(ts)
+	defer tokenSrv.Close()
+	registrySrv := newTestTokenRegistry(tokenSrv)
+	defer registrySrv.Close()
+
+	now := time.Date(2019, 6, 20, 10, 0, 0, 0, time.UTC)
+	transport := &AuthTokenTransport{
+		Password:  "secret",
+		Transport: http.DefaultTransport,
+		Username:  "reguser",
+		nowFunc:   func() time.Time { return now },
+	}
+	client := &http.Client{Transport: transport}
+	get := func(path string) {
+		resp, err := client.Get(registrySrv.URL + path)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode, path)
+	}
+
+	get("/v2/unit/test/manifests/latest")
+	get("/v2/unit/test/blobs/sha256:abc")
+	get("/v2/other/test/manifests/latest")
+	get("/v2/_catalog")
+	assert.Equal(t, []string{
+		"unit-test repository:unit/test:pull",
+		"unit-test repository:other/test:pull",
+		"unit-test registry:catalog:*",
+	}, ts.requests)
+
+	now = now.Add(285 * time.Second)
+	get("/v2/unit/test/manifests/latest")
+	assert.Equal(t, 3, ts.requestCount(), "token is still valid")
+
+	now = now.Add(6 * time.Second)
+	get("/v2/unit/test/manifests/latest")
+	assert.Equal(t, 4, ts.requestCount(), "token is refreshed ahead of its expiry")
+}
+
+func TestAuthTokenTransport_Concurrent(t *testing.T) {
+	ts := &testTokenServer{}
+	tokenSrv := httptest.NewServer(ts)
+	defer tokenSrv.Close()
+	registrySrv := newTestTokenRegistry(tokenSrv)
+	defer registrySrv.Close()
+
+	client := &http.Client{
+		Transport: &AuthTokenTransport{
+			Password:  "secret",
+			Transport: http.DefaultTransport,
+			Username:  "reguser",
+		},
+	}
+	// Prime the transport with the challenge of the registry.
+	resp, err := client.Get(registrySrv.URL + "/v2/unit/test/manifests/latest")
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp, err := client.Get(fmt.Sprintf("%s/v2/unit/test%d/manifests/latest", registrySrv.URL, i%2))
+			if assert.NoError(t, err) {
+				resp.Body.Close()
+				assert.Equal(t, http.StatusOK, resp.StatusCode)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	assert.Equal(t, 3, ts.requestCount())
+}
+
+func TestAuthTokenTransport_RejectedToken(t *testing.T) {
+	ts := &testTokenServer{expiresIn: 300}
+	tokenSrv := httptest.NewServer(ts)
+	defer tokenSrv.Close()
+	revoked := ""
+	registrySrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scope := scopeOfPath(r.URL.Path)
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Bearer "+scope+"-") || auth == "Bearer "+revoked {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="unit-test",scope="%s"`, tokenSrv.URL, scope))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Write([]byte("ok"))
+	}))
+	defer registrySrv.Close()
+
+	client := &http.Client{
+		Transport: &AuthTokenTransport{
+			Password:  "secret",
+			Transport: http.DefaultTransport,
+			Username:  "reguser",
+		},
+	}
+	resp, err := client.Get(registrySrv.URL + "/v2/unit/test/manifests/latest")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	revoked = "repository:unit/test:pull-1"
+	resp, err = client.Get(registrySrv.URL + "/v2/unit/test/manifests/latest")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "retry uses a new token")
+	assert.Equal(t, 2, ts.requestCount())
+}
+
+func TestAuthTokenTransport_Basic(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "reguser" || password != "secret" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="Registry Realm"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	client := &http.Client{
+		Transport: &AuthTokenTransport{
+			Password:  "secret",
+			Transport: http.DefaultTransport,
+			Username:  "reguser",
+		},
+	}
+	resp, err := client.Get(srv.URL + "/v2/unit/test/manifests/latest")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestParseChallenge(t *testing.T) {
+	testcases := []struct {
+		header         string
+		expectedScheme string
+		expectedParams map[string]string
+		expectedErr    bool
+	}{
+		{
+			header:         `Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/golang:pull"`,
+			expectedScheme: "bearer",
+			expectedParams: map[string]string{"realm": "https://auth.docker.io/token", "service": "registry.docker.io", "scope": "repository:library/golang:pull"},
+		},
+		{
+			header:         `Bearer realm="https://ghcr.io/token", service="ghcr.io", scope="repository:a/b:pull,push"`,
+			expectedScheme: "bearer",
+			expectedParams: map[string]string{"realm": "https://ghcr.io/token", "service": "ghcr.io", "scope": "repository:a/b:pull,push"},
+		},
+		{
+			header:         `Basic realm="Registry Realm"`,
+			expectedScheme: "basic",
+			expectedParams: map[string]string{"realm": "Registry Realm"},
+		},
+		{header: `Bearer service="registry.docker.io"`, expectedErr: true},
+		{header: `Negotiate`, expectedErr: true},
+		{header: `Bearer realm="https://auth.docker.io/token`, expectedErr: true},
+	}
+
+	for _, tc := range testcases {
+		c, err := parseChallenge(tc.header)
+		if tc.expectedErr {
+			assert.Error(t, err, tc.header)
+			continue
+		}
+
+		require.NoError(t, err, tc.header)
+		assert.Equal(t, tc.expectedScheme, c.scheme, tc.header)
+		assert.Equal(t, tc.expectedParams, c.params, tc.header)
+	}
+}
@@ -1,6 +1,9 @@
 package registry
 
 import (
+	"encoding/json"
+	"time"
+
 	"github.com/docker/distribution/manifest/schema1"
 	dockerImage "github.com/docker/docker/image"
 	imageV1 "github.com/docker/docker/image/v1"
@@ -9,18 +12,49 @@ import (
 )
 
 type ConfigV1 struct {
-	digest  digest.Digest
-	history []dockerImage.History
+	created      time.Time
+	digest       digest.Digest
+	entrypoint   []string
+	env          []string
+	exposedPorts []string
+	history      []dockerImage.History
+	labels       map[string]string
+	user         string
+}
+
+func (c *ConfigV1) Created() time.Time {
+	return c.created
+}
+
+// DiffIDs returns nil because schema1 manifests do not reference the uncompressed layers.
+func (c *ConfigV1) DiffIDs() []digest.Digest {
+	return nil
 }
 
 func (c *ConfigV1) Digest() digest.Digest {
 	return c.digest
 }
 
+func (c *ConfigV1) Entrypoint() []string {
+	return c.entrypoint
+}
+
+func (c *ConfigV1) Env() []string {
+	return c.env
+}
+
+func (c *ConfigV1) ExposedPorts() []string {
+	return c.exposedPorts
+}
+
 func (c *ConfigV1) History() ([]dockerImage.History, error) {
 	return c.history, nil
 }
 
+func (c *ConfigV1) Labels() map[string]string {
+	return c.labels
+}
+
 func (c *ConfigV1) MediaType() string {
 	return ""
 }
@@ -29,6 +63,10 @@ func (c *ConfigV1) Size() int {
 	return 0
 }
 
+func (c *ConfigV1) User() string {
+	return c.user
+}
+
 type PlatformV1 struct {
 	digest    digest.Digest
 	image     Image
@@ -98,11 +136,30 @@ func NewManifestV1(p *PlatformV1, m *schema1.SignedManifest, manifestDigest dige
 		entries = append([]dockerImage.History{e}, entries...)
 	}
 
+	config := &ConfigV1{
+		digest:  manifestDigest,
+		history: entries,
+	}
+	// The first entry of the history describes the topmost layer and contains the config of the image.
+	if len(m.History) > 0 {
+		v1Image := &dockerImage.V1Image{}
+		err := json.Unmarshal([]byte(m.History[0].V1Compatibility), v1Image)
+		if err != nil {
+			return nil, err
+		}
+
+		config.created = v1Image.Created
+		if v1Image.Config != nil {
+			config.entrypoint = v1Image.Config.Entrypoint
+			config.env = v1Image.Config.Env
+			config.exposedPorts = exposedPortsOf(v1Image.Config)
+			config.labels = v1Image.Config.Labels
+			config.user = v1Image.Config.User
+		}
+	}
+
 	return &ManifestV1{
-		config: &ConfigV1{
-			digest:  manifestDigest,
-			history: entries,
-		},
+		config:      config,
 		layers:      layers,
 		platform:    p,
 		rawManifest: m,
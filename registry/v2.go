@@ -1,29 +1,100 @@
 package registry
 
 import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
 	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest"
+	"github.com/docker/distribution/manifest/ocischema"
 	"github.com/docker/distribution/manifest/schema2"
+	"github.com/docker/docker/api/types/container"
 	dockerImage "github.com/docker/docker/image"
-	imageV1 "github.com/docker/docker/image/v1"
 	reg "github.com/genuinetools/reg/registry"
 	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 )
 
 type ConfigV2 struct {
-	digest    digest.Digest
-	history   []dockerImage.History
-	mediaType string
-	size      int
+	created      time.Time
+	diffIDs      []digest.Digest
+	digest       digest.Digest
+	entrypoint   []string
+	env          []string
+	exposedPorts []string
+	history      []dockerImage.History
+	labels       map[string]string
+	mediaType    string
+	size         int
+	user         string
+}
+
+// newConfigV2 parses the config blob b that is referenced by the descriptor d of a manifest.
+func newConfigV2(d distribution.Descriptor, b []byte) (*ConfigV2, error) {
+	imageConfig := &dockerImage.Image{}
+	err := json.Unmarshal(b, imageConfig)
+	if err != nil {
+		return nil, fmt.Errorf("parsing config %s: %s", d.Digest, err)
+	}
+
+	c := &ConfigV2{
+		created:   imageConfig.Created,
+		digest:    d.Digest,
+		history:   imageConfig.History,
+		mediaType: d.MediaType,
+		size:      int(d.Size),
+	}
+	if imageConfig.Config != nil {
+		c.entrypoint = imageConfig.Config.Entrypoint
+		c.env = imageConfig.Config.Env
+		c.exposedPorts = exposedPortsOf(imageConfig.Config)
+		c.labels = imageConfig.Config.Labels
+		c.user = imageConfig.Config.User
+	}
+
+	if imageConfig.RootFS != nil {
+		for _, diffID := range imageConfig.RootFS.DiffIDs {
+			c.diffIDs = append(c.diffIDs, digest.Digest(diffID))
+		}
+	}
+
+	return c, nil
+}
+
+func (c *ConfigV2) Created() time.Time {
+	return c.created
+}
+
+func (c *ConfigV2) DiffIDs() []digest.Digest {
+	return c.diffIDs
 }
 
 func (c *ConfigV2) Digest() digest.Digest {
 	return c.digest
 }
 
+func (c *ConfigV2) Entrypoint() []string {
+	return c.entrypoint
+}
+
+func (c *ConfigV2) Env() []string {
+	return c.env
+}
+
+func (c *ConfigV2) ExposedPorts() []string {
+	return c.exposedPorts
+}
+
 func (c *ConfigV2) History() ([]dockerImage.History, error) {
 	return c.history, nil
 }
 
+func (c *ConfigV2) Labels() map[string]string {
+	return c.labels
+}
+
 func (c *ConfigV2) MediaType() string {
 	return c.mediaType
 }
@@ -32,6 +103,25 @@ func (c *ConfigV2) Size() int {
 	return c.size
 }
 
+func (c *ConfigV2) User() string {
+	return c.user
+}
+
+// exposedPortsOf returns the exposed ports of a container config in the format "<port>/<protocol>", sorted ascending.
+func exposedPortsOf(c *container.Config) []string {
+	if len(c.ExposedPorts) == 0 {
+		return nil
+	}
+
+	ports := []string{}
+	for p := range c.ExposedPorts {
+		ports = append(ports, string(p))
+	}
+
+	sort.Strings(ports)
+	return ports
+}
+
 type LayerV2 struct {
 	rawLayer distribution.Descriptor
 }
@@ -69,27 +159,17 @@ func (m *ManifestV2) Layers() []Layer {
 
 func (m *ManifestV2) Config() (Config, error) {
 	if m.config == nil {
-		mV1, err := m.regClient.ManifestV1(m.platform.image.parsed.Path, m.platform.image.parsed.Tag)
+		b, err := fetchBlob(m.regClient, m.platform.image.parsed.Path, m.rawManifest.Config.Digest)
 		if err != nil {
 			return nil, err
 		}
 
-		entries := []dockerImage.History{}
-		for _, entry := range mV1.History {
-			e, err := imageV1.HistoryFromConfig([]byte(entry.V1Compatibility), false)
-			if err != nil {
-				return nil, err
-			}
-
-			entries = append([]dockerImage.History{e}, entries...)
+		c, err := newConfigV2(m.rawManifest.Config, b)
+		if err != nil {
+			return nil, err
 		}
 
-		m.config = &ConfigV2{
-			digest:    m.rawManifest.Config.Digest,
-			history:   entries,
-			mediaType: m.rawManifest.Config.MediaType,
-			size:      int(m.rawManifest.Config.Size),
-		}
+		m.config = c
 	}
 
 	return m.config, nil
@@ -131,6 +211,27 @@ func NewManifestV2(m schema2.Manifest, p *PlatformV2) *ManifestV2 {
 	}
 }
 
+// NewManifestOCI creates a ManifestV2 from an OCI image manifest.
+// Both formats share the same structure and differ only in their media types.
+func NewManifestOCI(m ocischema.Manifest, p *PlatformV2) *ManifestV2 {
+	mediaType := m.MediaType
+	if mediaType == "" {
+		mediaType = ocispec.MediaTypeImageManifest
+	}
+
+	return NewManifestV2(
+		schema2.Manifest{
+			Versioned: manifest.Versioned{
+				MediaType:     mediaType,
+				SchemaVersion: m.SchemaVersion,
+			},
+			Config: m.Config,
+			Layers: m.Layers,
+		},
+		p,
+	)
+}
+
 type PlatformV2 struct {
 	architecture string
 	digest       digest.Digest
@@ -158,12 +259,19 @@ func (p *PlatformV2) Features() []string {
 
 func (p *PlatformV2) Manifest() (Manifest, error) {
 	if p.manifest == nil {
-		m, err := p.regClient.ManifestV2(p.image.parsed.Path, p.digest.String())
+		rawManifest, _, err := fetchManifest(p.regClient, p.image.parsed.Path, p.digest.String())
 		if err != nil {
 			return nil, err
 		}
 
-		p.manifest = NewManifestV2(m, p)
+		switch m := rawManifest.(type) {
+		case *schema2.DeserializedManifest:
+			p.manifest = NewManifestV2(m.Manifest, p)
+		case *ocischema.DeserializedManifest:
+			p.manifest = NewManifestOCI(m.Manifest, p)
+		default:
+			return nil, fmt.Errorf("manifest %s of platform %s/%s has an unsupported type", p.digest, p.os, p.architecture)
+		}
 	}
 
 	return p.manifest, nil
@@ -1,10 +1,13 @@
 package registry
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/opencontainers/go-digest"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/docker/distribution"
 	"github.com/docker/distribution/manifest"
@@ -40,3 +43,66 @@ func TestNewManifestV2(t *testing.T) {
 		actualLayerDigests,
 	)
 }
+
+func TestManifestV2_Config(t *testing.T) {
+	config := mustMarshal(t, map[string]interface{}{
+		"architecture": "amd64",
+		"created":      "2019-06-03T08:30:00Z",
+		"os":           "linux",
+		"config": map[string]interface{}{
+			"Entrypoint":   []string{"/bin/prometheus"},
+			"Env":          []string{"PATH=/bin"},
+			"ExposedPorts": map[string]interface{}{"9090/tcp": struct{}{}, "8080/tcp": struct{}{}},
+			"Labels":       map[string]string{"org.opencontainers.image.revision": "abc123"},
+			"User":         "nobody",
+		},
+		"history": []map[string]interface{}{
+			{"created": "2019-06-01T10:00:00Z", "created_by": "ADD file:abc in /"},
+			{"created": "2019-06-03T08:30:00Z", "created_by": "ENTRYPOINT [\"/bin/prometheus\"]", "empty_layer": true},
+		},
+		"rootfs": map[string]interface{}{
+			"type":     "layers",
+			"diff_ids": []string{digest.FromString("uncompressed").String()},
+		},
+	})
+	configDigest := digest.FromBytes(config)
+	m := mustMarshal(t, map[string]interface{}{
+		"schemaVersion": 2,
+		"mediaType":     schema2.MediaTypeManifest,
+		"config":        map[string]interface{}{"mediaType": schema2.MediaTypeImageConfig, "digest": configDigest, "size": len(config)},
+		"layers": []map[string]interface{}{
+			{"mediaType": schema2.MediaTypeLayer, "digest": digest.FromString("compressed"), "size": 512},
+		},
+	})
+
+	srv := newTestRegistry(
+		t,
+		map[string]testRegistryContent{"v2.3.2": {mediaType: schema2.MediaTypeManifest, payload: m}},
+		map[digest.Digest][]byte{configDigest: config},
+	)
+	defer srv.Close()
+
+	img, err := NewImage(strings.TrimPrefix(srv.URL, "https://")+"/unit/test:v2.3.2", Opts{Insecure: true})
+	require.NoError(t, err)
+	platforms, err := img.Platforms()
+	require.NoError(t, err)
+	require.Len(t, platforms, 1)
+	manifest, err := platforms[0].Manifest()
+	require.NoError(t, err)
+	c, err := manifest.Config()
+	require.NoError(t, err)
+
+	assert.Equal(t, time.Date(2019, 6, 3, 8, 30, 0, 0, time.UTC), c.Created().UTC())
+	assert.Equal(t, []digest.Digest{digest.FromString("uncompressed")}, c.DiffIDs())
+	assert.Equal(t, configDigest, c.Digest())
+	assert.Equal(t, []string{"/bin/prometheus"}, c.Entrypoint())
+	assert.Equal(t, []string{"PATH=/bin"}, c.Env())
+	assert.Equal(t, []string{"8080/tcp", "9090/tcp"}, c.ExposedPorts())
+	assert.Equal(t, "nobody", c.User())
+	assert.Equal(t, map[string]string{"org.opencontainers.image.revision": "abc123"}, c.Labels())
+	assert.Equal(t, schema2.MediaTypeImageConfig, c.MediaType())
+	history, err := c.History()
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.True(t, history[1].EmptyLayer)
+}
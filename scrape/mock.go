@@ -33,6 +33,34 @@ func (m *MockScraper) EXPECT() *MockScraperMockRecorder {
 	return m.recorder
 }
 
+// DeleteImage mocks base method
+func (m *MockScraper) DeleteImage(name, digest string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteImage", name, digest)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteImage indicates an expected call of DeleteImage
+func (mr *MockScraperMockRecorder) DeleteImage(name, digest interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteImage", reflect.TypeOf((*MockScraper)(nil).DeleteImage), name, digest)
+}
+
+// DeleteTag mocks base method
+func (m *MockScraper) DeleteTag(name, tag string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteTag", name, tag)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteTag indicates an expected call of DeleteTag
+func (mr *MockScraperMockRecorder) DeleteTag(name, tag interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTag", reflect.TypeOf((*MockScraper)(nil).DeleteTag), name, tag)
+}
+
 // ScrapeImage mocks base method
 func (m *MockScraper) ScrapeImage(i registry.Image) error {
 	m.ctrl.T.Helper()
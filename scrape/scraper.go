@@ -34,22 +34,293 @@ var (
 )
 
 type Scraper interface {
+	// DeleteImage removes the image identified by name and digest after it has been deleted from the registry.
+	DeleteImage(name string, digest string) error
+	// DeleteTag marks the tag of the repository name as untagged after it has been deleted from the registry.
+	DeleteTag(name string, tag string) error
 	ScrapeImage(i registry.Image) error
 	ScrapeLatestImage(i registry.Image) error
 }
 
-func NewScraper(s store.Store) Scraper {
+// NewScraper returns a Scraper that selects the version parsers of a repository from rules.
+func NewScraper(s store.Store, rules *versionparser.Rules) Scraper {
 	return &async{
+		rules:    rules,
 		store:    s,
 		timeFunc: func() time.Time { return time.Now().UTC() },
 	}
 }
 
 type async struct {
+	rules    *versionparser.Rules
 	store    store.Store
 	timeFunc func() time.Time
 }
 
+func (a *async) DeleteImage(name string, digest string) error {
+	image, err := a.store.Images().Get(store.ImageGetOptions{Digest: digest, Name: name})
+	if err != nil {
+		if err == store.ErrDoesNotExist {
+			return nil
+		}
+
+		return fmt.Errorf("DeleteImage - reading image %s@%s: %s", name, digest, err)
+	}
+
+	tags, err := a.store.Tags().List(store.TagListOptions{ImageID: image.ID})
+	if err != nil {
+		return fmt.Errorf("DeleteImage - reading tags of image %d: %s", image.ID, err)
+	}
+
+	platforms, err := a.store.Platforms().List(store.PlatformListOptions{ImageID: image.ID})
+	if err != nil {
+		return fmt.Errorf("DeleteImage - reading platforms of image %d: %s", image.ID, err)
+	}
+
+	layers := []*store.Layer{}
+	for _, p := range platforms {
+		platformLayers, err := a.store.Layers().List(store.LayerListOptions{PlatformID: p.ID})
+		if err != nil {
+			return fmt.Errorf("DeleteImage - reading layers of platform %d: %s", p.ID, err)
+		}
+
+		layers = append(layers, platformLayers...)
+	}
+
+	tx, err := a.store.Transaction()
+	if err != nil {
+		return err
+	}
+
+	for _, t := range tags {
+		removed := *t
+		removed.IsLatest = false
+		removed.IsTagged = false
+		err := a.recordTagEvents(tx, stateOfTag(t), &removed, image)
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("DeleteImage - recording removal of tag %d: %s", t.ID, err)
+		}
+	}
+
+	err = tx.Images().Delete(image)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("DeleteImage - deleting image %d: %s", image.ID, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, t := range tags {
+		if t.IsLatest {
+			err := a.updateLatestTag(name, t.Distinction)
+			if err != nil {
+				return fmt.Errorf("DeleteImage - updating latest tag of distinction %s: %s", t.Distinction, err)
+			}
+		}
+	}
+
+	for _, l := range layers {
+		l.SourceImageIDs = removeImageID(l.SourceImageIDs, image.ID)
+		err := a.updateSourceImagesOfLayer(l)
+		if err != nil {
+			log.Errorf("failed to update source image of layer %d: %s", l.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func (a *async) DeleteTag(name string, tag string) error {
+	b := true
+	tags, err := a.store.Tags().List(store.TagListOptions{ImageName: name, IsTagged: &b})
+	if err != nil {
+		return fmt.Errorf("DeleteTag - reading tags of image %s: %s", name, err)
+	}
+
+	for _, t := range tags {
+		if t.Name != tag {
+			continue
+		}
+
+		image, err := a.store.Images().Get(store.ImageGetOptions{ID: t.ImageID})
+		if err != nil {
+			return fmt.Errorf("DeleteTag - reading image %d of tag %d: %s", t.ImageID, t.ID, err)
+		}
+
+		previous := stateOfTag(t)
+		t.IsLatest = false
+		t.IsTagged = false
+		err = a.updateTag(a.store, t, previous, image)
+		if err != nil {
+			return fmt.Errorf("DeleteTag - updating tag %d: %s", t.ID, err)
+		}
+
+		if previous.isLatest {
+			err := a.updateLatestTag(name, t.Distinction)
+			if err != nil {
+				return fmt.Errorf("DeleteTag - updating latest tag of distinction %s: %s", t.Distinction, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// updateLatestTag marks the greatest tagged version of a distinction as the latest one.
+// It is used after the previous latest tag has been removed and the registry cannot be asked for the latest version.
+func (a *async) updateLatestTag(name string, distinction string) error {
+	b := true
+	tags, err := a.store.Tags().List(store.TagListOptions{Distinction: distinction, ImageName: name, IsTagged: &b})
+	if err != nil {
+		return err
+	}
+
+	vps := a.rules.ForRepository(name)
+	created := map[int]time.Time{}
+	// All tags of a distinction share the type of their version parser.
+	if len(tags) > 0 {
+		if _, ok := vps.FindForVersion(tags[0].Name).(versionparser.CreatedOrdered); ok {
+			imageIDs := []int{}
+			for _, t := range tags {
+				imageIDs = append(imageIDs, t.ImageID)
+			}
+
+			created, err = a.createdOfImages(imageIDs)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	var latest *store.Tag
+	var latestVP versionparser.VersionParser
+	for _, t := range tags {
+		if vps.IsIgnored(t.Name) {
+			continue
+		}
+
+		vp := withCreated(vps.FindForVersion(t.Name), created[t.ImageID])
+		if latest == nil {
+			latest = t
+			latestVP = vp
+			continue
+		}
+
+		isGreater, err := vp.IsGreaterThan(latestVP)
+		if err != nil {
+			continue
+		}
+
+		if isGreater {
+			latest = t
+			latestVP = vp
+		}
+	}
+
+	for _, t := range tags {
+		isLatest := t == latest
+		if t.IsLatest == isLatest {
+			continue
+		}
+
+		image, err := a.store.Images().Get(store.ImageGetOptions{ID: t.ImageID})
+		if err != nil {
+			return err
+		}
+
+		previous := stateOfTag(t)
+		t.IsLatest = isLatest
+		err = a.updateTag(a.store, t, previous, image)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// tagState is the part of a tag that the tag event log tracks.
+type tagState struct {
+	isLatest bool
+	isTagged bool
+}
+
+func stateOfTag(t *store.Tag) tagState {
+	return tagState{isLatest: t.IsLatest, isTagged: t.IsTagged}
+}
+
+// createTag creates the tag and records it in the tag event log.
+func (a *async) createTag(s store.Store, t *store.Tag, image *store.Image) error {
+	err := s.Tags().Create(t)
+	if err != nil {
+		return err
+	}
+
+	return a.recordTagEvents(s, tagState{}, t, image)
+}
+
+// updateTag updates the tag and records how it changed compared to previous in the tag event log.
+func (a *async) updateTag(s store.Store, t *store.Tag, previous tagState, image *store.Image) error {
+	err := s.Tags().Update(t)
+	if err != nil {
+		return err
+	}
+
+	return a.recordTagEvents(s, previous, t, image)
+}
+
+// recordTagEvents appends one event per change between previous and the current state of the tag to the tag event log.
+func (a *async) recordTagEvents(s store.Store, previous tagState, t *store.Tag, image *store.Image) error {
+	actions := []string{}
+	if !previous.isTagged && t.IsTagged {
+		actions = append(actions, store.TagEventActionTag)
+	}
+
+	if !previous.isLatest && t.IsLatest {
+		actions = append(actions, store.TagEventActionSetLatest)
+	}
+
+	if previous.isLatest && !t.IsLatest {
+		actions = append(actions, store.TagEventActionUnsetLatest)
+	}
+
+	if previous.isTagged && !t.IsTagged {
+		actions = append(actions, store.TagEventActionUntag)
+	}
+
+	for _, action := range actions {
+		err := s.TagEvents().Create(&store.TagEvent{
+			Action:      action,
+			CreatedAt:   a.timeFunc(),
+			Digest:      image.Digest,
+			Distinction: t.Distinction,
+			ImageName:   image.Name,
+			TagName:     t.Name,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func removeImageID(ids []int, id int) []int {
+	result := []int{}
+	for _, i := range ids {
+		if i != id {
+			result = append(result, i)
+		}
+	}
+
+	return result
+}
+
 func (a *async) ScrapeImage(i registry.Image) error {
 	start := time.Now()
 	defer func() { promScrapeDuration.Observe(time.Since(start).Seconds()) }()
@@ -63,7 +334,13 @@ func (a *async) ScrapeImage(i registry.Image) error {
 		return fmt.Errorf("ScrapeImage: retrieving tag failed: %s", err)
 	}
 
-	vp := versionparser.FindForVersion(tagRef)
+	vps := a.rules.ForRepository(i.Repository().FullName())
+	if vps.IsIgnored(tagRef) {
+		log.Debugf("ScrapeImage: ignoring tag %s of %s", tagRef, i.Repository().FullName())
+		return nil
+	}
+
+	vp := vps.FindForVersion(tagRef)
 	image, err := a.store.Images().Get(store.ImageGetOptions{Digest: digest})
 	if err == nil {
 		newTag := &store.Tag{
@@ -87,7 +364,7 @@ func (a *async) ScrapeImage(i registry.Image) error {
 		}
 
 		if !tagExists {
-			err := a.store.Tags().Create(newTag)
+			err := a.createTag(a.store, newTag, image)
 			if err != nil {
 				return err
 			}
@@ -129,11 +406,39 @@ func (a *async) ScrapeLatestImage(i registry.Image) error {
 		return fmt.Errorf("ScrapeLatestImage - getting tag of registry image: %s", err)
 	}
 
-	latestVP := versionparser.FindForVersion(regImgTag)
+	vps := a.rules.ForRepository(i.Repository().FullName())
+	if vps.IsIgnored(regImgTag) {
+		log.Debugf("ScrapeLatestImage: ignoring tag %s of %s", regImgTag, i.Repository().FullName())
+		return nil
+	}
+
+	latestVP := vps.FindForVersion(regImgTag)
+	created := map[string]time.Time{}
+	if _, ok := latestVP.(versionparser.CreatedOrdered); ok {
+		created, err = a.createdOfTags(i.Repository().FullName(), latestVP.Distinction())
+		if err != nil {
+			return fmt.Errorf("ScrapeLatestImage - reading the time images of distinction %s have been created: %s", latestVP.Distinction(), err)
+		}
+
+		if _, ok := created[regImgTag]; !ok {
+			created[regImgTag], err = createdOfRegistryImage(i)
+			if err != nil {
+				return fmt.Errorf("ScrapeLatestImage - reading the time registry image %s:%s has been created: %s", i.Repository().FullName(), regImgTag, err)
+			}
+		}
+
+		latestVP = withCreated(latestVP, created[regImgTag])
+	}
+
+	// A tag that has been scraped before keeps the distinction it has been stored with.
+	distinction, err := a.storedDistinction(i.Repository().FullName(), regImgTag, latestVP.Distinction())
+	if err != nil {
+		return fmt.Errorf("ScrapeLatestImage - reading the distinction of tag %s: %s", regImgTag, err)
+	}
 
 	b := true
 	currentTag, err := a.store.Tags().Get(store.TagGetOptions{
-		Distinction: latestVP.Distinction(),
+		Distinction: distinction,
 		ImageName:   i.Repository().FullName(),
 		IsLatest:    &b,
 		Name:        regImgTag,
@@ -161,11 +466,27 @@ func (a *async) ScrapeLatestImage(i registry.Image) error {
 			return fmt.Errorf("ScrapeLatestImage - getting tag of registry image %s: %s", regImageItem.Repository().FullName(), err)
 		}
 
-		currentVP := versionparser.FindForVersion(currentImageTag)
+		if vps.IsIgnored(currentImageTag) {
+			continue
+		}
+
+		currentVP := vps.FindForVersion(currentImageTag)
 		if currentVP.Distinction() != latestVP.Distinction() {
 			continue
 		}
 
+		if _, ok := currentVP.(versionparser.CreatedOrdered); ok {
+			// Images that have not been scraped yet are compared by the time the registry reports.
+			if _, ok := created[currentImageTag]; !ok {
+				created[currentImageTag], err = createdOfRegistryImage(regImageItem)
+				if err != nil {
+					return fmt.Errorf("ScrapeLatestImage - reading the time registry image %s:%s has been created: %s", regImageItem.Repository().FullName(), currentImageTag, err)
+				}
+			}
+
+			currentVP = withCreated(currentVP, created[currentImageTag])
+		}
+
 		currentIsGreater, err := currentVP.IsGreaterThan(latestVP)
 		if err != nil {
 			continue
@@ -231,7 +552,7 @@ func (a *async) ScrapeLatestImage(i registry.Image) error {
 				Name:        latestVP.String(),
 			}
 
-			err := a.store.Tags().Create(latestTag)
+			err := a.createTag(a.store, latestTag, latestImage)
 			if err != nil {
 				return fmt.Errorf("ScrapeLatestImage - creating latest tag %s for image %s: %s", latestTag.Name, latestImage.Name, err)
 			}
@@ -241,20 +562,22 @@ func (a *async) ScrapeLatestImage(i registry.Image) error {
 	}
 
 	if latestTag.IsLatest == false {
+		previous := stateOfTag(latestTag)
 		latestTag.IsLatest = true
-		err := a.store.Tags().Update(latestTag)
+		err := a.updateTag(a.store, latestTag, previous, latestImage)
 		if err != nil {
 			return err
 		}
 	}
 
 	if currentTag != nil && currentTag.IsLatest == true {
+		previous := stateOfTag(currentTag)
 		currentTag.IsLatest = false
 		if currentTag.Name == latestTag.Name {
 			currentTag.IsTagged = false
 		}
 
-		err := a.store.Tags().Update(currentTag)
+		err := a.updateTag(a.store, currentTag, previous, currentImage)
 		if err != nil {
 			return err
 		}
@@ -271,22 +594,169 @@ func (a *async) ScrapeLatestImage(i registry.Image) error {
 	return nil
 }
 
-func (a *async) CreateStoreImageFromRegistryImage(distinction string, regImg registry.Image) (*store.Image, []*store.Layer, error) {
-	tx, err := a.store.Transaction()
+// storedDistinction returns the distinction of a tagged tag of an image.
+// The distinction of a tag that has not been scraped yet is the given one.
+func (a *async) storedDistinction(name string, tag string, distinction string) (string, error) {
+	b := true
+	tags, err := a.store.Tags().List(store.TagListOptions{ImageName: name, IsTagged: &b, Names: []string{tag}})
 	if err != nil {
-		tx.Rollback()
-		return nil, nil, err
+		return "", err
+	}
+
+	if len(tags) == 0 {
+		return distinction, nil
+	}
+
+	return tags[0].Distinction, nil
+}
+
+// createdOfImages returns the time the images have been created, keyed by the ID of an image.
+// The time of an image is the time its most recent platform has been created.
+func (a *async) createdOfImages(imageIDs []int) (map[int]time.Time, error) {
+	result := map[int]time.Time{}
+	if len(imageIDs) == 0 {
+		return result, nil
+	}
+
+	platforms, err := a.store.Platforms().List(store.PlatformListOptions{ImageIDs: imageIDs})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range platforms {
+		if p.Created.After(result[p.ImageID]) {
+			result[p.ImageID] = p.Created
+		}
+	}
+
+	return result, nil
+}
+
+// createdOfTags returns the time the images of the tags of a distinction have been created, keyed by the name of a tag.
+// Tags that have not been scraped yet are not part of the result.
+func (a *async) createdOfTags(name string, distinction string) (map[string]time.Time, error) {
+	b := true
+	tags, err := a.store.Tags().List(store.TagListOptions{Distinction: distinction, ImageName: name, IsTagged: &b})
+	if err != nil {
+		return nil, err
+	}
+
+	imageIDs := []int{}
+	for _, t := range tags {
+		imageIDs = append(imageIDs, t.ImageID)
+	}
+
+	createdOfImages, err := a.createdOfImages(imageIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]time.Time{}
+	for _, t := range tags {
+		if c, ok := createdOfImages[t.ImageID]; ok {
+			result[t.Name] = c
+		}
+	}
+
+	return result, nil
+}
+
+// createdOfRegistryImage returns the time the most recent platform of an image in the registry has been created.
+func createdOfRegistryImage(regImg registry.Image) (time.Time, error) {
+	regPlatforms, err := readRegistryPlatforms(regImg)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	result := time.Time{}
+	for _, rp := range regPlatforms {
+		if rp.config.Created().After(result) {
+			result = rp.config.Created()
+		}
+	}
+
+	return result, nil
+}
+
+// withCreated passes the time the image of a tag has been created to version parsers that order versions by it.
+// Version parsers of tags which time is not known are returned unchanged and cannot be compared.
+func withCreated(vp versionparser.VersionParser, created time.Time) versionparser.VersionParser {
+	co, ok := vp.(versionparser.CreatedOrdered)
+	if !ok || created.IsZero() {
+		return vp
+	}
+
+	return co.WithCreated(created)
+}
+
+// registryPlatform holds the data of a platform that has been read from the registry.
+type registryPlatform struct {
+	config       registry.Config
+	layerDigests []string
+	manifest     registry.Manifest
+	platform     registry.Platform
+}
+
+// readRegistryPlatforms reads the manifests, configs and layers of all platforms of an image from the registry.
+func readRegistryPlatforms(regImg registry.Image) ([]*registryPlatform, error) {
+	regPlatforms, err := regImg.Platforms()
+	if err != nil {
+		return nil, err
 	}
 
+	result := []*registryPlatform{}
+	for _, p := range regPlatforms {
+		regManifest, err := p.Manifest()
+		if err != nil {
+			return nil, err
+		}
+
+		regManifestConfig, err := regManifest.Config()
+		if err != nil {
+			return nil, err
+		}
+
+		rp := &registryPlatform{config: regManifestConfig, manifest: regManifest, platform: p}
+		for _, l := range regManifest.Layers() {
+			layerDigest, err := l.Digest()
+			if err != nil {
+				return nil, err
+			}
+
+			rp.layerDigests = append(rp.layerDigests, layerDigest)
+		}
+
+		result = append(result, rp)
+	}
+
+	return result, nil
+}
+
+// CreateStoreImageFromRegistryImage stores an image and its platforms and layers.
+// All data is read from the registry before the transaction starts to keep the transaction short.
+func (a *async) CreateStoreImageFromRegistryImage(distinction string, regImg registry.Image) (*store.Image, []*store.Layer, error) {
 	imageDigest, err := regImg.Digest()
 	if err != nil {
-		tx.Rollback()
 		return nil, nil, err
 	}
 
 	imageSV, err := regImg.SchemaVersion()
 	if err != nil {
-		tx.Rollback()
+		return nil, nil, err
+	}
+
+	tagName, err := regImg.Tag()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	regPlatforms, err := readRegistryPlatforms(regImg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tx, err := a.store.Transaction()
+	if err != nil {
 		return nil, nil, err
 	}
 
@@ -303,12 +773,6 @@ func (a *async) CreateStoreImageFromRegistryImage(distinction string, regImg reg
 		return nil, nil, err
 	}
 
-	tagName, err := regImg.Tag()
-	if err != nil {
-		tx.Rollback()
-		return nil, nil, err
-	}
-
 	tag := &store.Tag{
 		Distinction: distinction,
 		ImageID:     image.ID,
@@ -316,13 +780,7 @@ func (a *async) CreateStoreImageFromRegistryImage(distinction string, regImg reg
 		IsTagged:    true,
 		Name:        tagName,
 	}
-	err = tx.Tags().Create(tag)
-	if err != nil {
-		tx.Rollback()
-		return nil, nil, err
-	}
-
-	regPlatforms, err := regImg.Platforms()
+	err = a.createTag(tx, tag, image)
 	if err != nil {
 		tx.Rollback()
 		return nil, nil, err
@@ -332,33 +790,25 @@ func (a *async) CreateStoreImageFromRegistryImage(distinction string, regImg reg
 	layerClient := tx.Layers()
 	layerPositionClient := tx.LayerPositions()
 	platformClient := tx.Platforms()
-	for _, p := range regPlatforms {
-		regManifest, err := p.Manifest()
-		if err != nil {
-			tx.Rollback()
-			return nil, nil, err
-		}
-
-		regManifestConfig, err := regManifest.Config()
-		if err != nil {
-			tx.Rollback()
-			return nil, nil, err
-		}
-
-		history, err := regManifestConfig.History()
-		if err != nil {
-			tx.Rollback()
-			return nil, nil, err
+	for _, rp := range regPlatforms {
+		p := rp.platform
+		regManifest := rp.manifest
+		regManifestConfig := rp.config
+		var platformSize int64
+		for _, l := range regManifest.Layers() {
+			platformSize += int64(l.Size())
 		}
 
 		platform := &store.Platform{
 			Architecture:   p.Architecture(),
 			CreatedAt:      a.timeFunc(),
-			Created:        history[len(history)-1].Created,
+			Created:        regManifestConfig.Created(),
 			ImageID:        image.ID,
 			ManifestDigest: regManifestConfig.Digest().String(),
+			MediaType:      regManifest.MediaType(),
 			OS:             p.OS(),
 			OSVersion:      p.OSVersion(),
+			Size:           platformSize,
 			Variant:        p.Variant(),
 		}
 
@@ -383,14 +833,27 @@ func (a *async) CreateStoreImageFromRegistryImage(distinction string, regImg reg
 			return nil, nil, err
 		}
 
+		platformConfig := &store.PlatformConfig{
+			Entrypoint:   regManifestConfig.Entrypoint(),
+			Env:          regManifestConfig.Env(),
+			ExposedPorts: regManifestConfig.ExposedPorts(),
+			Labels:       regManifestConfig.Labels(),
+			PlatformID:   platform.ID,
+			User:         regManifestConfig.User(),
+		}
+		err = platformClient.CreateConfig(platformConfig)
+		if err != nil {
+			log.Errorf("unable to create config of platform %s for image %d: %s", platform.ManifestDigest, image.ID, err)
+			tx.Rollback()
+			return nil, nil, err
+		}
+
 		for idx, l := range regManifest.Layers() {
-			layerDigest, err := l.Digest()
-			if err != nil {
-				tx.Rollback()
-				return nil, nil, err
+			layer := &store.Layer{
+				Digest:    rp.layerDigests[idx],
+				MediaType: l.MediaType(),
+				Size:      int64(l.Size()),
 			}
-
-			layer := &store.Layer{Digest: layerDigest}
 			err = layerClient.Create(layer)
 			if err != nil {
 				log.Errorf("unable to create layer %s for platform %s: %s", layer.Digest, platform.ManifestDigest, err)
@@ -413,6 +876,7 @@ func (a *async) CreateStoreImageFromRegistryImage(distinction string, regImg reg
 	err = tx.Commit()
 	if err != nil {
 		tx.Rollback()
+		return nil, nil, err
 	}
 
 	return image, layers, nil
@@ -0,0 +1,310 @@
+package scrape
+
+import (
+	"testing"
+	"time"
+
+	"github.com/imagespy/api/registry"
+	registryMock "github.com/imagespy/api/registry/mock"
+	"github.com/imagespy/api/store"
+	"github.com/imagespy/api/store/memory"
+	"github.com/imagespy/api/versionparser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testCreated = time.Date(2019, 6, 20, 10, 0, 0, 0, time.UTC)
+
+func newTestScraper() *async {
+	now := testCreated
+	return &async{
+		rules: versionparser.NewRules(versionparser.Opts{}),
+		store: memory.New(),
+		timeFunc: func() time.Time {
+			now = now.Add(time.Minute)
+			return now
+		},
+	}
+}
+
+// newTestImage returns a registry image with one platform that consists of the layers.
+func newTestImage(name string, tag string, digest string, created time.Time, layers ...string) *registryMock.Image {
+	p := registryMock.NewPlatform("amd64", digest+"-platform", layers, digest+"-config", "linux", created)
+	return registryMock.NewImage(digest, name, []registry.Platform{p}, 2, tag)
+}
+
+// scrape scrapes an image and the latest image of its distinction, like a push event does.
+func scrape(t *testing.T, a *async, i registry.Image) {
+	require.NoError(t, a.ScrapeImage(i))
+	require.NoError(t, a.ScrapeLatestImage(i))
+}
+
+// tagsByName returns all tags of the images of name, including untagged ones.
+func tagsByName(t *testing.T, s store.Store, name string) map[string]*store.Tag {
+	tags, err := s.Tags().List(store.TagListOptions{ImageName: name})
+	require.NoError(t, err)
+	result := map[string]*store.Tag{}
+	for _, tag := range tags {
+		result[tag.Name] = tag
+	}
+
+	return result
+}
+
+func sourceImageIDsOfLayer(t *testing.T, s store.Store, digest string) []int {
+	l, err := s.Layers().Get(store.LayerGetOptions{Digest: digest})
+	require.NoError(t, err)
+	return l.SourceImageIDs
+}
+
+func TestAsync_DeleteTag(t *testing.T) {
+	a := newTestScraper()
+	rm := registryMock.NewRegistry()
+	i1 := newTestImage("unit.test/app", "1.0.0", "sha256:100", testCreated, "sha256:base", "sha256:l100")
+	i2 := newTestImage("unit.test/app", "1.0.1", "sha256:101", testCreated, "sha256:base", "sha256:l101")
+	rm.AddImage(i1)
+	rm.AddImage(i2)
+	scrape(t, a, i1)
+
+	tags := tagsByName(t, a.store, "unit.test/app")
+	require.True(t, tags["1.0.1"].IsLatest)
+	require.False(t, tags["1.0.0"].IsLatest)
+
+	err := a.DeleteTag("unit.test/app", "1.0.1")
+	require.NoError(t, err)
+	tags = tagsByName(t, a.store, "unit.test/app")
+	assert.False(t, tags["1.0.1"].IsTagged, "deleted tag is untagged")
+	assert.False(t, tags["1.0.1"].IsLatest, "deleted tag is not the latest tag")
+	assert.True(t, tags["1.0.0"].IsTagged)
+	assert.True(t, tags["1.0.0"].IsLatest, "greatest remaining version becomes the latest tag")
+
+	err = a.DeleteTag("unit.test/app", "2.0.0")
+	assert.NoError(t, err, "unknown tag")
+}
+
+func TestAsync_DeleteImage(t *testing.T) {
+	a := newTestScraper()
+	rm := registryMock.NewRegistry()
+	base := newTestImage("unit.test/base", "1", "sha256:base1", testCreated, "sha256:base")
+	i1 := newTestImage("unit.test/app", "1.0.0", "sha256:100", testCreated, "sha256:base", "sha256:l100")
+	i2 := newTestImage("unit.test/app", "1.0.1", "sha256:101", testCreated, "sha256:base", "sha256:l101")
+	rm.AddImage(base)
+	rm.AddImage(i1)
+	rm.AddImage(i2)
+	scrape(t, a, base)
+	scrape(t, a, i1)
+
+	baseImage, err := a.store.Images().Get(store.ImageGetOptions{Digest: "sha256:base1"})
+	require.NoError(t, err)
+	image1, err := a.store.Images().Get(store.ImageGetOptions{Digest: "sha256:100"})
+	require.NoError(t, err)
+	image2, err := a.store.Images().Get(store.ImageGetOptions{Digest: "sha256:101"})
+	require.NoError(t, err)
+	require.Equal(t, []int{baseImage.ID}, sourceImageIDsOfLayer(t, a.store, "sha256:base"))
+	require.Equal(t, []int{image2.ID}, sourceImageIDsOfLayer(t, a.store, "sha256:l101"))
+
+	err = a.DeleteImage("unit.test/app", "sha256:101")
+	require.NoError(t, err)
+	_, err = a.store.Images().Get(store.ImageGetOptions{ID: image2.ID})
+	assert.Equal(t, store.ErrDoesNotExist, err)
+	tags := tagsByName(t, a.store, "unit.test/app")
+	assert.NotContains(t, tags, "1.0.1", "tags of the image are removed")
+	assert.True(t, tags["1.0.0"].IsLatest, "greatest remaining version becomes the latest tag")
+	assert.Empty(t, sourceImageIDsOfLayer(t, a.store, "sha256:l101"), "deleted image is no source image of its layers")
+
+	err = a.DeleteImage("unit.test/base", "sha256:base1")
+	require.NoError(t, err)
+	assert.Equal(t, []int{image1.ID}, sourceImageIDsOfLayer(t, a.store, "sha256:base"), "image with the least layers becomes the source image")
+
+	err = a.DeleteImage("unit.test/app", "sha256:999")
+	assert.NoError(t, err, "unknown image")
+}
+
+func TestAsync_UpdateLatestTag(t *testing.T) {
+	a := newTestScraper()
+	image := &store.Image{Digest: "sha256:100", Name: "unit.test/app"}
+	require.NoError(t, a.store.Images().Create(image))
+	for _, tag := range []*store.Tag{
+		{Distinction: "majorMinorPatch", ImageID: image.ID, IsTagged: false, Name: "1.2.0"},
+		{Distinction: "majorMinorPatch", ImageID: image.ID, IsLatest: true, IsTagged: true, Name: "1.0.0"},
+		{Distinction: "majorMinorPatch", ImageID: image.ID, IsTagged: true, Name: "1.1.0"},
+		{Distinction: "majorMinorPatch", ImageID: image.ID, IsTagged: true, Name: "0.9.0"},
+		{Distinction: "static", ImageID: image.ID, IsLatest: true, IsTagged: true, Name: "latest"},
+	} {
+		require.NoError(t, a.store.Tags().Create(tag))
+	}
+
+	err := a.updateLatestTag("unit.test/app", "majorMinorPatch")
+	require.NoError(t, err)
+	tags := tagsByName(t, a.store, "unit.test/app")
+	assert.False(t, tags["1.2.0"].IsLatest, "untagged tag is not the latest tag")
+	assert.True(t, tags["1.1.0"].IsLatest)
+	assert.False(t, tags["1.0.0"].IsLatest, "previous latest tag is unset")
+	assert.False(t, tags["0.9.0"].IsLatest)
+	assert.True(t, tags["latest"].IsLatest, "tags of other distinctions are not changed")
+}
+
+// eventsOfTag returns the action and digest of every event of a tag, oldest first.
+func eventsOfTag(t *testing.T, s store.Store, name string, tag string) []string {
+	events, err := s.TagEvents().List(store.TagEventListOptions{ImageName: name, TagName: tag})
+	require.NoError(t, err)
+	result := []string{}
+	for i := len(events) - 1; i >= 0; i-- {
+		result = append(result, events[i].Action+" "+events[i].Digest)
+	}
+
+	return result
+}
+
+func TestAsync_TagEvents(t *testing.T) {
+	a := newTestScraper()
+	rm := registryMock.NewRegistry()
+	i1 := newTestImage("unit.test/app", "1.0.0", "sha256:100", testCreated, "sha256:l100")
+	rm.AddImage(i1)
+	scrape(t, a, i1)
+	assert.Equal(t, []string{"tag sha256:100", "set_latest sha256:100"}, eventsOfTag(t, a.store, "unit.test/app", "1.0.0"), "create")
+
+	i2 := newTestImage("unit.test/app", "1.0.1", "sha256:101", testCreated, "sha256:l101")
+	rm.AddImage(i2)
+	scrape(t, a, i2)
+	// The updater scrapes the latest image of every latest tag.
+	require.NoError(t, a.ScrapeLatestImage(i1))
+	assert.Equal(t, []string{"tag sha256:100", "set_latest sha256:100", "unset_latest sha256:100"}, eventsOfTag(t, a.store, "unit.test/app", "1.0.0"), "move latest away")
+	assert.Equal(t, []string{"tag sha256:101", "set_latest sha256:101"}, eventsOfTag(t, a.store, "unit.test/app", "1.0.1"), "move latest to")
+
+	latest1 := newTestImage("unit.test/app", "latest", "sha256:latest1", testCreated, "sha256:latest1")
+	rm.AddImage(latest1)
+	scrape(t, a, latest1)
+	// The registry only knows the most recent push of a tag.
+	movedRegistry := registryMock.NewRegistry()
+	latest2 := newTestImage("unit.test/app", "latest", "sha256:latest2", testCreated, "sha256:latest2")
+	movedRegistry.AddImage(latest2)
+	scrape(t, a, latest2)
+	assert.Equal(t, []string{
+		"tag sha256:latest1",
+		"set_latest sha256:latest1",
+		"tag sha256:latest2",
+		"set_latest sha256:latest2",
+		"unset_latest sha256:latest1",
+		"untag sha256:latest1",
+	}, eventsOfTag(t, a.store, "unit.test/app", "latest"), "move tag to another digest")
+
+	require.NoError(t, a.DeleteTag("unit.test/app", "1.0.1"))
+	assert.Equal(t, []string{"tag sha256:101", "set_latest sha256:101", "unset_latest sha256:101", "untag sha256:101"}, eventsOfTag(t, a.store, "unit.test/app", "1.0.1"), "untag")
+	assert.Equal(t, []string{"tag sha256:100", "set_latest sha256:100", "unset_latest sha256:100", "set_latest sha256:100"}, eventsOfTag(t, a.store, "unit.test/app", "1.0.0"), "latest moves back to the remaining tag")
+
+	require.NoError(t, a.DeleteImage("unit.test/app", "sha256:100"))
+	assert.Equal(t, []string{
+		"tag sha256:100",
+		"set_latest sha256:100",
+		"unset_latest sha256:100",
+		"set_latest sha256:100",
+		"unset_latest sha256:100",
+		"untag sha256:100",
+	}, eventsOfTag(t, a.store, "unit.test/app", "1.0.0"), "delete image")
+}
+
+func TestAsync_ScrapeLatestImage_StoredDistinction(t *testing.T) {
+	a := newTestScraper()
+	// The tag has been scraped by earlier version parsers that kept the version of the variant in the distinction.
+	image := &store.Image{Digest: "sha256:100", Name: "unit.test/app"}
+	require.NoError(t, a.store.Images().Create(image))
+	require.NoError(t, a.store.Tags().Create(&store.Tag{Distinction: "majorMinorPatch-alpine3.9", ImageID: image.ID, IsLatest: true, IsTagged: true, Name: "1.2.3-alpine3.9"}))
+
+	rm := registryMock.NewRegistry()
+	i1 := newTestImage("unit.test/app", "1.2.3-alpine3.9", "sha256:100", testCreated, "sha256:l100")
+	rm.AddImage(i1)
+	rm.AddImage(newTestImage("unit.test/app", "1.2.3-alpine3.10", "sha256:101", testCreated, "sha256:l101"))
+	require.NoError(t, a.ScrapeLatestImage(i1))
+
+	tags := tagsByName(t, a.store, "unit.test/app")
+	assert.False(t, tags["1.2.3-alpine3.9"].IsLatest, "latest tag of the stored distinction is unset")
+	assert.Equal(t, "majorMinorPatch-alpine3.9", tags["1.2.3-alpine3.9"].Distinction)
+	require.Contains(t, tags, "1.2.3-alpine3.10")
+	assert.True(t, tags["1.2.3-alpine3.10"].IsLatest)
+	assert.Equal(t, "majorMinorPatch-alpine", tags["1.2.3-alpine3.10"].Distinction)
+}
+
+func TestWithCreated(t *testing.T) {
+	vps := versionparser.NewRules(versionparser.Opts{}).ForRepository("unit.test/app")
+	sha := vps.FindForVersion("a1b2c3d")
+	assert.Equal(t, sha, withCreated(sha, time.Time{}), "unknown time")
+	semVer := vps.FindForVersion("1.0.0")
+	assert.Equal(t, semVer, withCreated(semVer, testCreated), "version parser that is not ordered by time")
+
+	older := withCreated(sha, testCreated)
+	newer := withCreated(vps.FindForVersion("b2c3d4e"), testCreated.Add(time.Hour))
+	result, err := newer.IsGreaterThan(older)
+	require.NoError(t, err)
+	assert.True(t, result)
+	_, err = newer.IsGreaterThan(sha)
+	assert.Equal(t, versionparser.ErrCreatedUnknown, err)
+}
+
+func TestAsync_createdOfTags(t *testing.T) {
+	a := newTestScraper()
+	image := &store.Image{Digest: "sha256:100", Name: "unit.test/app"}
+	require.NoError(t, a.store.Images().Create(image))
+	for _, p := range []*store.Platform{
+		{Architecture: "amd64", Created: testCreated, ImageID: image.ID, ManifestDigest: "sha256:100-amd64", OS: "linux"},
+		{Architecture: "arm64", Created: testCreated.Add(time.Hour), ImageID: image.ID, ManifestDigest: "sha256:100-arm64", OS: "linux"},
+	} {
+		require.NoError(t, a.store.Platforms().Create(p))
+	}
+
+	withoutPlatforms := &store.Image{Digest: "sha256:101", Name: "unit.test/app"}
+	require.NoError(t, a.store.Images().Create(withoutPlatforms))
+	for _, tag := range []*store.Tag{
+		{Distinction: "gitSHA", ImageID: image.ID, IsTagged: true, Name: "a1b2c3d"},
+		{Distinction: "gitSHA", ImageID: image.ID, IsTagged: false, Name: "b2c3d4e"},
+		{Distinction: "majorMinorPatch", ImageID: image.ID, IsTagged: true, Name: "1.0.0"},
+		{Distinction: "gitSHA", ImageID: withoutPlatforms.ID, IsTagged: true, Name: "c3d4e5f"},
+	} {
+		require.NoError(t, a.store.Tags().Create(tag))
+	}
+
+	created, err := a.createdOfTags("unit.test/app", "gitSHA")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]time.Time{"a1b2c3d": testCreated.Add(time.Hour)}, created, "most recent platform of tagged tags of the distinction")
+}
+
+func TestAsync_UpdateLatestTag_CreatedOrdered(t *testing.T) {
+	a := newTestScraper()
+	tags := map[string]*store.Tag{}
+	for idx, name := range []string{"a1b2c3d", "b2c3d4e", "c3d4e5f"} {
+		image := &store.Image{Digest: "sha256:10" + name, Name: "unit.test/app"}
+		require.NoError(t, a.store.Images().Create(image))
+		// The second tag belongs to the most recently created image.
+		created := testCreated.Add(time.Duration(idx) * time.Hour)
+		if idx == 1 {
+			created = testCreated.Add(24 * time.Hour)
+		}
+
+		require.NoError(t, a.store.Platforms().Create(&store.Platform{Architecture: "amd64", Created: created, ImageID: image.ID, ManifestDigest: image.Digest + "-config", OS: "linux"}))
+		tags[name] = &store.Tag{Distinction: "gitSHA", ImageID: image.ID, IsLatest: idx == 2, IsTagged: true, Name: name}
+		require.NoError(t, a.store.Tags().Create(tags[name]))
+	}
+
+	require.NoError(t, a.updateLatestTag("unit.test/app", "gitSHA"))
+	result := tagsByName(t, a.store, "unit.test/app")
+	assert.False(t, result["a1b2c3d"].IsLatest)
+	assert.True(t, result["b2c3d4e"].IsLatest, "tag of the most recently created image")
+	assert.False(t, result["c3d4e5f"].IsLatest, "previous latest tag is unset")
+}
+
+func TestAsync_ScrapeLatestImage_CreatedOrdered(t *testing.T) {
+	a := newTestScraper()
+	rm := registryMock.NewRegistry()
+	pushed := newTestImage("unit.test/app", "b2c3d4e", "sha256:101", testCreated, "sha256:l101")
+	rm.AddImage(pushed)
+	// Neither image has been scraped, the time they have been created is read from the registry.
+	rm.AddImage(newTestImage("unit.test/app", "a1b2c3d", "sha256:100", testCreated.Add(-time.Hour), "sha256:l100"))
+	rm.AddImage(newTestImage("unit.test/app", "c3d4e5f", "sha256:102", testCreated.Add(time.Hour), "sha256:l102"))
+	scrape(t, a, pushed)
+
+	tags := tagsByName(t, a.store, "unit.test/app")
+	require.Contains(t, tags, "c3d4e5f")
+	assert.True(t, tags["c3d4e5f"].IsLatest, "most recently created image in the registry")
+	assert.False(t, tags["b2c3d4e"].IsLatest)
+	assert.NotContains(t, tags, "a1b2c3d", "older image is not scraped")
+}
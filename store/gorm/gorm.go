@@ -1,8 +1,10 @@
 package gorm
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/golang-migrate/migrate"
 	"github.com/imagespy/api/store"
@@ -20,6 +22,42 @@ func (sourceImageOfLayer) TableName() string {
 	return "imagespy_layer_source_images"
 }
 
+// featureOfPlatform is a feature together with the ID of a platform that has the feature.
+type featureOfPlatform struct {
+	store.Feature
+	PlatformID int
+}
+
+// osFeatureOfPlatform is an OS feature together with the ID of a platform that has the OS feature.
+type osFeatureOfPlatform struct {
+	store.OSFeature
+	PlatformID int
+}
+
+type platformConfig struct {
+	store.Model
+	Entrypoint   string
+	Env          string
+	ExposedPorts string
+	PlatformID   int
+	UserName     string
+}
+
+func (platformConfig) TableName() string {
+	return "imagespy_platformconfig"
+}
+
+type label struct {
+	store.Model
+	Name       string
+	PlatformID int
+	Value      string
+}
+
+func (label) TableName() string {
+	return "imagespy_label"
+}
+
 type gorm struct {
 	db *gormlib.DB
 }
@@ -28,6 +66,10 @@ func (g *gorm) Images() store.ImageStore {
 	return &gormImage{db: g.db}
 }
 
+func (g *gorm) Jobs() store.JobStore {
+	return &gormJob{db: g.db}
+}
+
 func (g *gorm) Layers() store.LayerStore {
 	return &gormLayer{db: g.db}
 }
@@ -40,6 +82,10 @@ func (g *gorm) Platforms() store.PlatformStore {
 	return &gormPlatform{db: g.db}
 }
 
+func (g *gorm) TagEvents() store.TagEventStore {
+	return &gormTagEvent{db: g.db}
+}
+
 func (g *gorm) Tags() store.TagStore {
 	return &gormTag{db: g.db}
 }
@@ -121,11 +167,7 @@ func (gi *gormImage) Get(o store.ImageGetOptions) (*store.Image, error) {
 
 	if o.TagIsLatest != nil {
 		whereQuery = append(whereQuery, "imagespy_tag.is_latest = ?")
-		if *o.TagIsLatest {
-			whereValues = append(whereValues, "1")
-		} else {
-			whereValues = append(whereValues, "0")
-		}
+		whereValues = append(whereValues, *o.TagIsLatest)
 		joinWithTag = true
 	}
 
@@ -161,13 +203,44 @@ func (gi *gormImage) List(o store.ImageListOptions) ([]*store.Image, error) {
 		imageWhereValues = append(imageWhereValues, o.Digest)
 	}
 
+	if len(o.Digests) > 0 {
+		imageWhereQuery = append(imageWhereQuery, "imagespy_image.digest IN (?)")
+		imageWhereValues = append(imageWhereValues, o.Digests)
+	}
+
+	if len(o.IDs) > 0 {
+		imageWhereQuery = append(imageWhereQuery, "imagespy_image.id IN (?)")
+		imageWhereValues = append(imageWhereValues, o.IDs)
+	}
+
 	if o.Name != "" {
 		imageWhereQuery = append(imageWhereQuery, "imagespy_image.name = ?")
 		imageWhereValues = append(imageWhereValues, o.Name)
 	}
 
+	if !o.CreatedSince.IsZero() {
+		imageWhereQuery = append(imageWhereQuery, "imagespy_image.created_at >= ?")
+		imageWhereValues = append(imageWhereValues, o.CreatedSince)
+	}
+
+	if !o.CreatedUntil.IsZero() {
+		imageWhereQuery = append(imageWhereQuery, "imagespy_image.created_at < ?")
+		imageWhereValues = append(imageWhereValues, o.CreatedUntil)
+	}
+
+	if o.TagDistinction != "" {
+		// A subquery instead of a join because an image can have more than one tag with the same distinction.
+		imageWhereQuery = append(imageWhereQuery, "imagespy_image.id IN (SELECT imagespy_tag.image_id FROM imagespy_tag WHERE imagespy_tag.distinction = ?)")
+		imageWhereValues = append(imageWhereValues, o.TagDistinction)
+	}
+
+	query := gi.db.Where(strings.Join(imageWhereQuery, " AND "), imageWhereValues...).Order("id desc")
+	if o.Limit > 0 {
+		query = query.Limit(o.Limit).Offset(o.Offset)
+	}
+
 	images := []*store.Image{}
-	result := gi.db.Where(strings.Join(imageWhereQuery, " AND "), imageWhereValues...).Order("id desc").Find(&images)
+	result := query.Find(&images)
 	if result.Error != nil {
 		return nil, result.Error
 	}
@@ -175,6 +248,61 @@ func (gi *gormImage) List(o store.ImageListOptions) ([]*store.Image, error) {
 	return images, nil
 }
 
+func (gi *gormImage) ListRepositories(o store.RepositoryListOptions) ([]*store.Repository, error) {
+	whereQuery := []string{}
+	whereValues := []interface{}{}
+	// LIKE is case-insensitive in MySQL and SQLite but case-sensitive in PostgreSQL. LOWER() makes all databases behave the same.
+	if o.NameContains != "" {
+		whereQuery = append(whereQuery, "LOWER(imagespy_image.name) LIKE ? ESCAPE '!'")
+		whereValues = append(whereValues, "%"+escapeLike(strings.ToLower(o.NameContains))+"%")
+	}
+
+	if o.NamePrefix != "" {
+		whereQuery = append(whereQuery, "LOWER(imagespy_image.name) LIKE ? ESCAPE '!'")
+		whereValues = append(whereValues, escapeLike(strings.ToLower(o.NamePrefix))+"%")
+	}
+
+	order := "name ASC"
+	if o.OrderBy == store.RepositoryOrderScrapedAt {
+		order = "scraped_at DESC, name ASC"
+	}
+
+	query := gi.db.Table("imagespy_image").
+		Select("imagespy_image.name AS name, COUNT(DISTINCT imagespy_image.id) AS image_count, COUNT(DISTINCT imagespy_tag.name) AS tag_count, MAX(imagespy_image.scraped_at) AS scraped_at").
+		Joins("LEFT JOIN imagespy_tag ON imagespy_tag.image_id = imagespy_image.id AND imagespy_tag.is_tagged = ?", true).
+		Where(strings.Join(whereQuery, " AND "), whereValues...).
+		Group("imagespy_image.name").
+		Order(order)
+	if o.Limit > 0 {
+		query = query.Limit(o.Limit).Offset(o.Offset)
+	}
+
+	rows, err := query.Rows()
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	repositories := []*store.Repository{}
+	for rows.Next() {
+		r := &store.Repository{}
+		var scrapedAt interface{}
+		err := rows.Scan(&r.Name, &r.ImageCount, &r.TagCount, &scrapedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		r.ScrapedAt, err = parseAggregatedTime(scrapedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		repositories = append(repositories, r)
+	}
+
+	return repositories, rows.Err()
+}
+
 func (gi *gormImage) Update(i *store.Image) error {
 	result := gi.db.Save(i)
 	if result.Error != nil {
@@ -184,31 +312,197 @@ func (gi *gormImage) Update(i *store.Image) error {
 	return nil
 }
 
+func (gi *gormImage) Delete(i *store.Image) error {
+	platformTables := []string{
+		"imagespy_label",
+		"imagespy_layerofplatform",
+		"imagespy_platform_features",
+		"imagespy_platform_os_features",
+		"imagespy_platformconfig",
+	}
+	for _, table := range platformTables {
+		result := gi.db.Exec("delete from "+table+" where platform_id in (select id from imagespy_platform where image_id = ?)", i.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+	}
+
+	imageTables := []string{
+		"imagespy_layer_source_images",
+		"imagespy_platform",
+		"imagespy_tag",
+	}
+	for _, table := range imageTables {
+		result := gi.db.Exec("delete from "+table+" where image_id = ?", i.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+	}
+
+	result := gi.db.Exec("delete from imagespy_image where id = ?", i.ID)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
 // FindByLayerIDHavingLayerCountGreaterThan finds all images which have the layer identified by the given ID and which total number of layers is greater than the given count.
 func (gi *gormImage) FindByLayerIDHavingLayerCountGreaterThan(layerID, count int) ([]*store.Image, error) {
-	rows, err := gi.db.Raw("select i.* from imagespy_layerofplatform as lop inner join imagespy_platform as p on lop.platform_id = p.id inner join imagespy_image as i on i.id = p.image_id where lop.platform_id in (select platform_id from imagespy_layerofplatform where layer_id = ?) group by platform_id having count(lop.id) > ? order by i.name", layerID, count).
-		Rows()
-	if err != nil {
-		if rows != nil {
-			rows.Close()
+	platformIDs := gi.db.Table("imagespy_layerofplatform").
+		Select("imagespy_layerofplatform.platform_id").
+		Where("imagespy_layerofplatform.platform_id IN ?", gi.db.Table("imagespy_layerofplatform").Select("platform_id").Where("layer_id = ?", layerID).SubQuery()).
+		Group("imagespy_layerofplatform.platform_id").
+		Having("count(imagespy_layerofplatform.id) > ?", count).
+		SubQuery()
+
+	images := []*store.Image{}
+	result := gi.db.Joins("inner join imagespy_platform on imagespy_platform.image_id = imagespy_image.id").
+		Where("imagespy_platform.id IN ?", platformIDs).
+		Order("imagespy_image.name").
+		Find(&images)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return images, nil
+}
+
+type gormJob struct {
+	db *gormlib.DB
+}
+
+func (g *gormJob) Claim(now time.Time, leaseUntil time.Time) (*store.Job, error) {
+	tx := g.db.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	j := &store.Job{}
+	query := tx
+	// SKIP LOCKED lets multiple servers claim jobs concurrently without waiting for each other.
+	// SQLite does not support row locks. It locks the whole database for the duration of the transaction instead.
+	if tx.Dialect().GetName() != dialectSQLite {
+		query = query.Set("gorm:query_option", "FOR UPDATE SKIP LOCKED")
+	}
+
+	result := query.
+		Where("imagespy_job.status IN (?) AND imagespy_job.next_run_at <= ?", []string{store.JobStatusPending, store.JobStatusRunning}, now).
+		Order("imagespy_job.next_run_at asc").
+		Take(j)
+	if result.Error != nil {
+		tx.Rollback()
+		if result.Error == gormlib.ErrRecordNotFound {
+			return nil, store.ErrDoesNotExist
 		}
+
+		return nil, result.Error
+	}
+
+	j.Attempts++
+	j.NextRunAt = leaseUntil
+	j.Status = store.JobStatusRunning
+	result = tx.Save(j)
+	if result.Error != nil {
+		tx.Rollback()
+		return nil, result.Error
+	}
+
+	result = tx.Commit()
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return j, nil
+}
+
+func (g *gormJob) CountByStatus() (map[string]int, error) {
+	rows, err := g.db.Raw("select status, count(*) from imagespy_job group by status").Rows()
+	if err != nil {
 		return nil, err
 	}
 
-	images := []*store.Image{}
+	defer rows.Close()
+	counts := map[string]int{}
 	for rows.Next() {
-		var result store.Image
-		err := gi.db.ScanRows(rows, &result)
+		var status string
+		var count int
+		err := rows.Scan(&status, &count)
 		if err != nil {
-			rows.Close()
-			return nil, nil
+			return nil, err
 		}
 
-		images = append(images, &result)
+		counts[status] = count
 	}
 
-	rows.Close()
-	return images, nil
+	return counts, rows.Err()
+}
+
+func (g *gormJob) Create(j *store.Job) error {
+	if j.ID != 0 {
+		return fmt.Errorf("Job already created")
+	}
+
+	j.Status = store.JobStatusPending
+	result := g.db.FirstOrCreate(j, store.Job{Action: j.Action, Name: j.Name, Reference: j.Reference, Status: store.JobStatusPending})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func (g *gormJob) DeleteDone(updatedBefore time.Time) (int, error) {
+	result := g.db.
+		Where("imagespy_job.status = ? AND imagespy_job.updated_at < ?", store.JobStatusDone, updatedBefore).
+		Delete(&store.Job{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return int(result.RowsAffected), nil
+}
+
+func (g *gormJob) Get(o store.JobGetOptions) (*store.Job, error) {
+	j := &store.Job{}
+	result := g.db.Where("imagespy_job.id = ?", o.ID).Take(j)
+	if result.Error != nil {
+		if result.Error == gormlib.ErrRecordNotFound {
+			return nil, store.ErrDoesNotExist
+		}
+
+		return nil, result.Error
+	}
+
+	return j, nil
+}
+
+func (g *gormJob) List(o store.JobListOptions) ([]*store.Job, error) {
+	query := g.db
+	if o.Status != "" {
+		query = query.Where("imagespy_job.status = ?", o.Status)
+	}
+
+	if o.Limit > 0 {
+		query = query.Limit(o.Limit)
+	}
+
+	jobs := []*store.Job{}
+	result := query.Order("imagespy_job.id desc").Find(&jobs)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return jobs, nil
+}
+
+func (g *gormJob) Update(j *store.Job) error {
+	result := g.db.Save(j)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
 }
 
 type gormLayer struct {
@@ -216,7 +510,9 @@ type gormLayer struct {
 }
 
 func (g *gormLayer) Create(l *store.Layer) error {
-	result := g.db.FirstOrCreate(l, store.Layer{Digest: l.Digest})
+	result := g.db.Where(store.Layer{Digest: l.Digest}).
+		Assign(store.Layer{MediaType: l.MediaType, Size: l.Size}).
+		FirstOrCreate(l)
 	if result.Error != nil {
 		return result.Error
 	}
@@ -279,35 +575,37 @@ func (g *gormLayer) List(o store.LayerListOptions) ([]*store.Layer, error) {
 			Where("imagespy_layerofplatform.platform_id = ?", o.PlatformID)
 	}
 
+	if len(o.IDs) > 0 {
+		query = query.Where("imagespy_layer.id IN (?)", o.IDs)
+	}
+
 	layers := []*store.Layer{}
 	result := query.Find(&layers)
 	if result.Error != nil {
 		return nil, result.Error
 	}
 
-	type sourceImageIDsResult struct {
-		ImageID int
+	if len(layers) == 0 {
+		return layers, nil
 	}
 
-	for _, l := range layers {
-		rows, err := g.db.Raw("select image_id from imagespy_layer_source_images where layer_id = ?", l.ID).Rows()
-		if err != nil {
-			rows.Close()
-			return nil, err
-		}
-
-		for rows.Next() {
-			var result sourceImageIDsResult
-			err := g.db.ScanRows(rows, &result)
-			if err != nil {
-				rows.Close()
-				return nil, nil
-			}
+	// Load the source images of all layers in one query instead of one query per layer.
+	layerIDs := make([]int, len(layers))
+	layersByID := make(map[int]*store.Layer, len(layers))
+	for i, l := range layers {
+		layerIDs[i] = l.ID
+		layersByID[l.ID] = l
+	}
 
-			l.SourceImageIDs = append(l.SourceImageIDs, result.ImageID)
-		}
+	sourceImageIDs := []*sourceImageOfLayer{}
+	siolResult := g.db.Where("imagespy_layer_source_images.layer_id IN (?)", layerIDs).Order("id").Find(&sourceImageIDs)
+	if siolResult.Error != nil {
+		return nil, siolResult.Error
+	}
 
-		rows.Close()
+	for _, sid := range sourceImageIDs {
+		l := layersByID[sid.LayerID]
+		l.SourceImageIDs = append(l.SourceImageIDs, sid.ImageID)
 	}
 
 	return layers, nil
@@ -377,6 +675,100 @@ func (g *gormPlatform) Create(p *store.Platform) error {
 	return nil
 }
 
+func (g *gormPlatform) CreateConfig(c *store.PlatformConfig) error {
+	if c.ID != 0 {
+		return fmt.Errorf("Platform config already created")
+	}
+
+	entrypoint, err := json.Marshal(c.Entrypoint)
+	if err != nil {
+		return err
+	}
+
+	env, err := json.Marshal(c.Env)
+	if err != nil {
+		return err
+	}
+
+	exposedPorts, err := json.Marshal(c.ExposedPorts)
+	if err != nil {
+		return err
+	}
+
+	pc := &platformConfig{
+		Entrypoint:   string(entrypoint),
+		Env:          string(env),
+		ExposedPorts: string(exposedPorts),
+		PlatformID:   c.PlatformID,
+		UserName:     c.User,
+	}
+	result := g.db.Create(pc)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	for name, value := range c.Labels {
+		result := g.db.Create(&label{Name: name, PlatformID: c.PlatformID, Value: value})
+		if result.Error != nil {
+			return result.Error
+		}
+	}
+
+	c.ID = pc.ID
+	return nil
+}
+
+func (g *gormPlatform) GetConfig(o store.PlatformConfigGetOptions) (*store.PlatformConfig, error) {
+	if o.PlatformID == 0 {
+		return nil, fmt.Errorf("required field PlatformID not set")
+	}
+
+	pc := &platformConfig{}
+	result := g.db.Where("imagespy_platformconfig.platform_id = ?", o.PlatformID).Take(pc)
+	if result.Error != nil {
+		if result.Error == gormlib.ErrRecordNotFound {
+			return nil, store.ErrDoesNotExist
+		}
+
+		return nil, result.Error
+	}
+
+	c := &store.PlatformConfig{
+		Model:      pc.Model,
+		PlatformID: pc.PlatformID,
+		User:       pc.UserName,
+	}
+	err := json.Unmarshal([]byte(pc.Entrypoint), &c.Entrypoint)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal([]byte(pc.Env), &c.Env)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal([]byte(pc.ExposedPorts), &c.ExposedPorts)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := []*label{}
+	result = g.db.Where("imagespy_label.platform_id = ?", o.PlatformID).Find(&labels)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	if len(labels) > 0 {
+		c.Labels = map[string]string{}
+		for _, l := range labels {
+			c.Labels[l.Name] = l.Value
+		}
+	}
+
+	return c, nil
+}
+
 func (g *gormPlatform) Get(o store.PlatformGetOptions) (*store.Platform, error) {
 	whereQuery := []string{}
 	whereValues := []interface{}{}
@@ -427,35 +819,118 @@ func (g *gormPlatform) List(o store.PlatformListOptions) ([]*store.Platform, err
 			Where("imagespy_layer.digest = ?", o.LayerDigest)
 	}
 
+	if o.ImageID != 0 {
+		query = query.Where("imagespy_platform.image_id = ?", o.ImageID)
+	}
+
+	if len(o.ImageIDs) > 0 {
+		query = query.Where("imagespy_platform.image_id IN (?)", o.ImageIDs)
+	}
+
+	if o.LabelName != "" {
+		query = query.Joins("inner join imagespy_label on imagespy_label.platform_id = imagespy_platform.id").
+			Where("imagespy_label.name = ?", o.LabelName)
+		if o.LabelValue != "" {
+			query = query.Where("imagespy_label.value = ?", o.LabelValue)
+		}
+	}
+
 	result := query.Find(&platforms)
 	if result.Error != nil {
 		return nil, result.Error
 	}
 
-	for _, p := range platforms {
-		features := []*store.Feature{}
-		featuresResult := g.db.Joins("inner join imagespy_platform_features on imagespy_platform_features.feature_id = imagespy_feature.id").
-			Where("imagespy_platform_features.platform_id = ?", p.ID).
-			Find(&features)
-		if featuresResult.Error != nil {
-			return nil, featuresResult.Error
-		}
+	if len(platforms) == 0 {
+		return platforms, nil
+	}
 
-		p.Features = features
-		osFeatures := []*store.OSFeature{}
-		osFeaturesResult := g.db.Joins("inner join imagespy_platform_os_features on imagespy_platform_os_features.osfeature_id = imagespy_osfeature.id").
-			Where("imagespy_platform_os_features.platform_id = ?", p.ID).
-			Find(&osFeatures)
-		if osFeaturesResult.Error != nil {
-			return nil, osFeaturesResult.Error
-		}
+	// Load the features and OS features of all platforms in one query each instead of two queries per platform.
+	platformIDs := make([]int, len(platforms))
+	platformsByID := make(map[int]*store.Platform, len(platforms))
+	for i, p := range platforms {
+		p.Features = []*store.Feature{}
+		p.OSFeatures = []*store.OSFeature{}
+		platformIDs[i] = p.ID
+		platformsByID[p.ID] = p
+	}
+
+	features := []*featureOfPlatform{}
+	featuresResult := g.db.Table("imagespy_feature").
+		Select("imagespy_feature.*, imagespy_platform_features.platform_id").
+		Joins("inner join imagespy_platform_features on imagespy_platform_features.feature_id = imagespy_feature.id").
+		Where("imagespy_platform_features.platform_id IN (?)", platformIDs).
+		Order("imagespy_feature.id").
+		Scan(&features)
+	if featuresResult.Error != nil {
+		return nil, featuresResult.Error
+	}
+
+	for _, f := range features {
+		feature := f.Feature
+		p := platformsByID[f.PlatformID]
+		p.Features = append(p.Features, &feature)
+	}
+
+	osFeatures := []*osFeatureOfPlatform{}
+	osFeaturesResult := g.db.Table("imagespy_osfeature").
+		Select("imagespy_osfeature.*, imagespy_platform_os_features.platform_id").
+		Joins("inner join imagespy_platform_os_features on imagespy_platform_os_features.osfeature_id = imagespy_osfeature.id").
+		Where("imagespy_platform_os_features.platform_id IN (?)", platformIDs).
+		Order("imagespy_osfeature.id").
+		Scan(&osFeatures)
+	if osFeaturesResult.Error != nil {
+		return nil, osFeaturesResult.Error
+	}
 
-		p.OSFeatures = osFeatures
+	for _, f := range osFeatures {
+		osFeature := f.OSFeature
+		p := platformsByID[f.PlatformID]
+		p.OSFeatures = append(p.OSFeatures, &osFeature)
 	}
 
 	return platforms, nil
 }
 
+type gormTagEvent struct {
+	db *gormlib.DB
+}
+
+func (g *gormTagEvent) Create(e *store.TagEvent) error {
+	if e.ID != 0 {
+		return fmt.Errorf("TagEvent already created")
+	}
+
+	result := g.db.Create(e)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func (g *gormTagEvent) List(o store.TagEventListOptions) ([]*store.TagEvent, error) {
+	query := g.db
+	if o.ImageName != "" {
+		query = query.Where("imagespy_tag_event.image_name = ?", o.ImageName)
+	}
+
+	if o.TagName != "" {
+		query = query.Where("imagespy_tag_event.tag_name = ?", o.TagName)
+	}
+
+	if o.Limit > 0 {
+		query = query.Limit(o.Limit).Offset(o.Offset)
+	}
+
+	events := []*store.TagEvent{}
+	result := query.Order("imagespy_tag_event.id desc").Find(&events)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return events, nil
+}
+
 type gormTag struct {
 	db *gormlib.DB
 }
@@ -490,11 +965,7 @@ func (g *gormTag) Get(o store.TagGetOptions) (*store.Tag, error) {
 
 	if o.IsLatest != nil {
 		whereQuery = append(whereQuery, "imagespy_tag.is_latest = ?")
-		if *o.IsLatest {
-			whereValues = append(whereValues, 1)
-		} else {
-			whereValues = append(whereValues, 0)
-		}
+		whereValues = append(whereValues, *o.IsLatest)
 	}
 
 	if o.Name != "" {
@@ -519,30 +990,52 @@ func (g *gormTag) List(o store.TagListOptions) ([]*store.Tag, error) {
 	tags := []*store.Tag{}
 	whereQuery := []string{}
 	whereValues := []interface{}{}
+	if o.Distinction != "" {
+		whereQuery = append(whereQuery, "imagespy_tag.distinction = ?")
+		whereValues = append(whereValues, o.Distinction)
+	}
+
 	if o.ImageID != 0 {
 		whereQuery = append(whereQuery, "imagespy_tag.image_id = ?")
 		whereValues = append(whereValues, o.ImageID)
 	}
 
+	if len(o.ImageIDs) > 0 {
+		whereQuery = append(whereQuery, "imagespy_tag.image_id IN (?)")
+		whereValues = append(whereValues, o.ImageIDs)
+	}
+
 	if o.IsLatest != nil {
 		whereQuery = append(whereQuery, "imagespy_tag.is_latest = ?")
-		if *o.IsLatest {
-			whereValues = append(whereValues, 1)
-		} else {
-			whereValues = append(whereValues, 0)
-		}
+		whereValues = append(whereValues, *o.IsLatest)
 	}
 
 	if o.IsTagged != nil {
 		whereQuery = append(whereQuery, "imagespy_tag.is_tagged = ?")
-		if *o.IsTagged {
-			whereValues = append(whereValues, 1)
-		} else {
-			whereValues = append(whereValues, 0)
-		}
+		whereValues = append(whereValues, *o.IsTagged)
+	}
+
+	if len(o.Names) > 0 {
+		whereQuery = append(whereQuery, "imagespy_tag.name IN (?)")
+		whereValues = append(whereValues, o.Names)
+	}
+
+	query := g.db
+	if o.ImageName != "" {
+		whereQuery = append(whereQuery, "imagespy_image.name = ?")
+		whereValues = append(whereValues, o.ImageName)
 	}
 
-	result := g.db.Where(strings.Join(whereQuery, " AND "), whereValues...).Find(&tags)
+	if len(o.ImageNames) > 0 {
+		whereQuery = append(whereQuery, "imagespy_image.name IN (?)")
+		whereValues = append(whereValues, o.ImageNames)
+	}
+
+	if o.ImageName != "" || len(o.ImageNames) > 0 {
+		query = query.Joins("inner join imagespy_image on imagespy_image.id = imagespy_tag.image_id")
+	}
+
+	result := query.Where(strings.Join(whereQuery, " AND "), whereValues...).Find(&tags)
 	if result.Error != nil {
 		return nil, result.Error
 	}
@@ -559,8 +1052,15 @@ func (g *gormTag) Update(t *store.Tag) error {
 	return nil
 }
 
+// Migrate executes the migrations of the dialect of the connection.
+// migrationsPath contains one directory of migrations per dialect, e.g. "file:///migrations" contains "mysql", "postgres" and "sqlite3".
 func Migrate(connection string, migrationsPath string) error {
-	mig, err := migrate.New(migrationsPath, "mysql://"+connection+"&multiStatements=true")
+	c, err := parseConnection(connection)
+	if err != nil {
+		return err
+	}
+
+	mig, err := migrate.New(strings.TrimSuffix(migrationsPath, "/")+"/"+c.dialect, c.migrateURL())
 	if err != nil {
 		return err
 	}
@@ -574,12 +1074,23 @@ func Migrate(connection string, migrationsPath string) error {
 	return nil
 }
 
+// New connects to the database. The dialect is selected by the scheme of the connection string.
 func New(connection string) (store.Store, error) {
-	db, err := gormlib.Open("mysql", connection)
+	c, err := parseConnection(connection)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := gormlib.Open(c.dialect, c.dsn)
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to connect to database")
 	}
 
+	if c.dialect == dialectSQLite {
+		// SQLite allows only one writer at a time. Concurrent writes of the queue workers fail with "database is locked" otherwise.
+		db.DB().SetMaxOpenConns(1)
+	}
+
 	// db.LogMode(true)
 	return &gorm{db: db}, nil
 }
@@ -0,0 +1,154 @@
+package gorm
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/golang-migrate/migrate/database/sqlite3"
+	_ "github.com/golang-migrate/migrate/source/file"
+	"github.com/imagespy/api/store"
+	"github.com/imagespy/api/store/storetest"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+)
+
+func newSQLiteStore(tb testing.TB, dir, name string) store.Store {
+	migrations, err := filepath.Abs("migrations")
+	require.NoError(tb, err)
+
+	connection := fmt.Sprintf("sqlite3://%s/%s.db", dir, name)
+	require.NoError(tb, Migrate(connection, "file://"+migrations))
+	s, err := New(connection)
+	require.NoError(tb, err)
+	return s
+}
+
+func TestGorm_SQLite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "imagespy-gorm")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	count := 0
+	storetest.Run(t, func(t *testing.T) store.Store {
+		count++
+		return newSQLiteStore(t, dir, fmt.Sprintf("%d", count))
+	})
+}
+
+const benchmarkLayerCount = 50
+
+// setupBenchmark creates a platform with benchmarkLayerCount layers.
+// Every layer has its own tagged source image, like the layers of a deep chain of base images.
+func setupBenchmark(b *testing.B) (store.Store, *store.Platform, func()) {
+	dir, err := ioutil.TempDir("", "imagespy-gorm-benchmark")
+	require.NoError(b, err)
+
+	s := newSQLiteStore(b, dir, "benchmark")
+	createdAt := time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC)
+	image := &store.Image{CreatedAt: createdAt, Digest: "sha256:image", Name: "unit.test/benchmark", SchemaVersion: 2, ScrapedAt: createdAt}
+	require.NoError(b, s.Images().Create(image))
+	p := &store.Platform{
+		Architecture:   "amd64",
+		CreatedAt:      createdAt,
+		Features:       []*store.Feature{{CreatedAt: createdAt, Name: "sse4"}},
+		ImageID:        image.ID,
+		ManifestDigest: "sha256:manifest",
+		OS:             "linux",
+	}
+	require.NoError(b, s.Platforms().Create(p))
+	for n := 0; n < benchmarkLayerCount; n++ {
+		source := &store.Image{CreatedAt: createdAt, Digest: fmt.Sprintf("sha256:source%d", n), Name: fmt.Sprintf("unit.test/source%d", n), SchemaVersion: 2, ScrapedAt: createdAt}
+		require.NoError(b, s.Images().Create(source))
+		require.NoError(b, s.Tags().Create(&store.Tag{Distinction: "major", ImageID: source.ID, IsLatest: true, IsTagged: true, Name: "1"}))
+		l := &store.Layer{Digest: fmt.Sprintf("sha256:layer%d", n), SourceImageIDs: []int{source.ID}}
+		require.NoError(b, s.Layers().Create(l))
+		require.NoError(b, s.LayerPositions().Create(&store.LayerPosition{LayerID: l.ID, PlatformID: p.ID, Position: n}))
+	}
+
+	return s, p, func() {
+		s.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func benchmarkLayerIDs(b *testing.B, s store.Store, p *store.Platform) []int {
+	positions, err := s.LayerPositions().List(store.LayerPositionListOptions{PlatformID: p.ID})
+	require.NoError(b, err)
+	ids := []int{}
+	for _, lp := range positions {
+		ids = append(ids, lp.LayerID)
+	}
+
+	return ids
+}
+
+func BenchmarkGorm_LayersOneByOne(b *testing.B) {
+	s, p, teardown := setupBenchmark(b)
+	defer teardown()
+	layerIDs := benchmarkLayerIDs(b, s, p)
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		isTagged := true
+		for _, id := range layerIDs {
+			l, err := s.Layers().Get(store.LayerGetOptions{ID: id})
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			for _, sourceImageID := range l.SourceImageIDs {
+				if _, err := s.Images().Get(store.ImageGetOptions{ID: sourceImageID}); err != nil {
+					b.Fatal(err)
+				}
+
+				if _, err := s.Tags().List(store.TagListOptions{ImageID: sourceImageID, IsTagged: &isTagged}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkGorm_LayersBatch(b *testing.B) {
+	s, p, teardown := setupBenchmark(b)
+	defer teardown()
+	layerIDs := benchmarkLayerIDs(b, s, p)
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		isTagged := true
+		layers, err := s.Layers().List(store.LayerListOptions{IDs: layerIDs})
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		sourceImageIDs := []int{}
+		for _, l := range layers {
+			sourceImageIDs = append(sourceImageIDs, l.SourceImageIDs...)
+		}
+
+		if _, err := s.Images().List(store.ImageListOptions{IDs: sourceImageIDs}); err != nil {
+			b.Fatal(err)
+		}
+
+		if _, err := s.Tags().List(store.TagListOptions{ImageIDs: sourceImageIDs, IsTagged: &isTagged}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGorm_LayerListOfPlatform(b *testing.B) {
+	s, p, teardown := setupBenchmark(b)
+	defer teardown()
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if _, err := s.Layers().List(store.LayerListOptions{PlatformID: p.ID}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1 @@
+ALTER TABLE `imagespy_platform` DROP COLUMN `media_type`;
@@ -0,0 +1 @@
+ALTER TABLE `imagespy_platform` ADD COLUMN `media_type` varchar(255) NOT NULL DEFAULT '';
@@ -0,0 +1,22 @@
+CREATE TABLE `imagespy_platformconfig` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `entrypoint` text NOT NULL,
+  `env` text NOT NULL,
+  `exposed_ports` text NOT NULL,
+  `platform_id` int(11) NOT NULL,
+  `user_name` varchar(255) NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `imagespy_platformconfig_platform_id_uniq` (`platform_id`),
+  CONSTRAINT `imagespy_platformconfig_platform_id_fk_imagespy_platform_id` FOREIGN KEY (`platform_id`) REFERENCES `imagespy_platform` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+CREATE TABLE `imagespy_label` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `name` varchar(255) NOT NULL,
+  `platform_id` int(11) NOT NULL,
+  `value` text NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `imagespy_label_platform_id_name_uniq` (`platform_id`,`name`),
+  KEY `imagespy_label_name_idx` (`name`),
+  CONSTRAINT `imagespy_label_platform_id_fk_imagespy_platform_id` FOREIGN KEY (`platform_id`) REFERENCES `imagespy_platform` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
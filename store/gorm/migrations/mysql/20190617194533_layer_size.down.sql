@@ -0,0 +1,3 @@
+ALTER TABLE `imagespy_platform` DROP COLUMN `size`;
+ALTER TABLE `imagespy_layer` DROP COLUMN `size`;
+ALTER TABLE `imagespy_layer` DROP COLUMN `media_type`;
@@ -0,0 +1,3 @@
+ALTER TABLE `imagespy_layer` ADD COLUMN `media_type` varchar(255) NOT NULL DEFAULT '';
+ALTER TABLE `imagespy_layer` ADD COLUMN `size` bigint(20) NOT NULL DEFAULT 0;
+ALTER TABLE `imagespy_platform` ADD COLUMN `size` bigint(20) NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+DROP TABLE `imagespy_job`;
@@ -0,0 +1,15 @@
+CREATE TABLE `imagespy_job` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `action` varchar(32) NOT NULL,
+  `attempts` int(11) NOT NULL,
+  `created_at` datetime(6) NOT NULL,
+  `last_error` text NOT NULL,
+  `name` varchar(255) NOT NULL,
+  `next_run_at` datetime(6) NOT NULL,
+  `reference` varchar(255) NOT NULL,
+  `status` varchar(16) NOT NULL,
+  `updated_at` datetime(6) NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `imagespy_job_status_next_run_at_idx` (`status`,`next_run_at`),
+  KEY `imagespy_job_action_name_reference_status_idx` (`action`,`name`,`reference`,`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
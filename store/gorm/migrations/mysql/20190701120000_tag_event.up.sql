@@ -0,0 +1,24 @@
+CREATE TABLE `imagespy_tag_event` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `action` varchar(16) NOT NULL,
+  `created_at` datetime(6) NOT NULL,
+  `digest` varchar(71) NOT NULL,
+  `distinction` varchar(64) NOT NULL,
+  `image_name` varchar(255) NOT NULL,
+  `tag_name` varchar(50) NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `imagespy_tag_event_image_name_tag_name_idx` (`image_name`,`tag_name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- Start the log with the current state of all tags. The time of the first scrape of an image is the best guess of when a tag has been set.
+INSERT INTO `imagespy_tag_event` (`action`, `created_at`, `digest`, `distinction`, `image_name`, `tag_name`)
+SELECT 'tag', `imagespy_image`.`created_at`, `imagespy_image`.`digest`, `imagespy_tag`.`distinction`, `imagespy_image`.`name`, `imagespy_tag`.`name`
+FROM `imagespy_tag` INNER JOIN `imagespy_image` ON `imagespy_image`.`id` = `imagespy_tag`.`image_id`
+WHERE `imagespy_tag`.`is_tagged`
+ORDER BY `imagespy_tag`.`id`;
+
+INSERT INTO `imagespy_tag_event` (`action`, `created_at`, `digest`, `distinction`, `image_name`, `tag_name`)
+SELECT 'set_latest', `imagespy_image`.`created_at`, `imagespy_image`.`digest`, `imagespy_tag`.`distinction`, `imagespy_image`.`name`, `imagespy_tag`.`name`
+FROM `imagespy_tag` INNER JOIN `imagespy_image` ON `imagespy_image`.`id` = `imagespy_tag`.`image_id`
+WHERE `imagespy_tag`.`is_latest`
+ORDER BY `imagespy_tag`.`id`;
@@ -0,0 +1,103 @@
+CREATE TABLE imagespy_feature (
+  id serial NOT NULL,
+  created_at timestamp(6) NOT NULL,
+  name varchar(255) NOT NULL,
+  PRIMARY KEY (id)
+);
+
+CREATE TABLE imagespy_image (
+  created_at timestamp(6) NOT NULL,
+  digest varchar(71) NOT NULL,
+  id serial NOT NULL,
+  name varchar(255) NOT NULL,
+  schema_version integer NOT NULL,
+  scraped_at timestamp(6) DEFAULT NULL,
+  PRIMARY KEY (id),
+  CONSTRAINT imagespy_image_digest_name_uniq UNIQUE (digest, name)
+);
+
+CREATE TABLE imagespy_layer (
+  digest varchar(255) NOT NULL,
+  id serial NOT NULL,
+  media_type varchar(255) NOT NULL DEFAULT '',
+  size bigint NOT NULL DEFAULT 0,
+  PRIMARY KEY (id),
+  CONSTRAINT imagespy_layer_digest_uniq UNIQUE (digest)
+);
+
+CREATE TABLE imagespy_platform (
+  id serial NOT NULL,
+  architecture varchar(255) NOT NULL,
+  created timestamp(6) DEFAULT NULL,
+  created_at timestamp(6) NOT NULL,
+  manifest_digest varchar(255) NOT NULL,
+  media_type varchar(255) NOT NULL DEFAULT '',
+  os varchar(255) NOT NULL,
+  os_version varchar(255) DEFAULT NULL,
+  size bigint NOT NULL DEFAULT 0,
+  variant varchar(255) DEFAULT NULL,
+  image_id integer NOT NULL REFERENCES imagespy_image (id),
+  PRIMARY KEY (id),
+  CONSTRAINT imagespy_platform_image_id_manifest_digest_uniq UNIQUE (image_id, manifest_digest)
+);
+
+CREATE TABLE imagespy_tag (
+  distinction varchar(64) NOT NULL,
+  id serial NOT NULL,
+  is_latest boolean NOT NULL,
+  is_tagged boolean NOT NULL,
+  name varchar(50) NOT NULL,
+  image_id integer NOT NULL REFERENCES imagespy_image (id),
+  PRIMARY KEY (id),
+  CONSTRAINT imagespy_tag_distinction_image_id_name_uniq UNIQUE (distinction, image_id, name)
+);
+
+CREATE INDEX imagespy_tag_image_id_idx ON imagespy_tag (image_id);
+
+CREATE TABLE imagespy_layer_source_images (
+  id serial NOT NULL,
+  layer_id integer NOT NULL REFERENCES imagespy_layer (id),
+  image_id integer NOT NULL REFERENCES imagespy_image (id),
+  PRIMARY KEY (id),
+  CONSTRAINT imagespy_layer_source_images_layer_id_image_id_uniq UNIQUE (layer_id, image_id)
+);
+
+CREATE INDEX imagespy_layer_source_images_image_id_idx ON imagespy_layer_source_images (image_id);
+
+CREATE TABLE imagespy_layerofplatform (
+  id serial NOT NULL,
+  position integer NOT NULL,
+  layer_id integer NOT NULL REFERENCES imagespy_layer (id),
+  platform_id integer NOT NULL REFERENCES imagespy_platform (id),
+  PRIMARY KEY (id),
+  CONSTRAINT imagespy_layerofplatform_layer_id_platform_id_uniq UNIQUE (layer_id, platform_id)
+);
+
+CREATE INDEX imagespy_layerofplatform_platform_id_idx ON imagespy_layerofplatform (platform_id);
+
+CREATE TABLE imagespy_osfeature (
+  id serial NOT NULL,
+  created_at timestamp(6) NOT NULL,
+  name varchar(255) NOT NULL,
+  PRIMARY KEY (id)
+);
+
+CREATE TABLE imagespy_platform_features (
+  id serial NOT NULL,
+  platform_id integer NOT NULL REFERENCES imagespy_platform (id),
+  feature_id integer NOT NULL REFERENCES imagespy_feature (id),
+  PRIMARY KEY (id),
+  CONSTRAINT imagespy_platform_features_platform_id_feature_id_uniq UNIQUE (platform_id, feature_id)
+);
+
+CREATE INDEX imagespy_platform_features_feature_id_idx ON imagespy_platform_features (feature_id);
+
+CREATE TABLE imagespy_platform_os_features (
+  id serial NOT NULL,
+  platform_id integer NOT NULL REFERENCES imagespy_platform (id),
+  osfeature_id integer NOT NULL REFERENCES imagespy_osfeature (id),
+  PRIMARY KEY (id),
+  CONSTRAINT imagespy_platform_os_features_platform_id_osfeature_id_uniq UNIQUE (platform_id, osfeature_id)
+);
+
+CREATE INDEX imagespy_platform_os_features_osfeature_id_idx ON imagespy_platform_os_features (osfeature_id);
@@ -0,0 +1,21 @@
+CREATE TABLE imagespy_platformconfig (
+  id serial NOT NULL,
+  entrypoint text NOT NULL,
+  env text NOT NULL,
+  exposed_ports text NOT NULL,
+  platform_id integer NOT NULL REFERENCES imagespy_platform (id),
+  user_name varchar(255) NOT NULL,
+  PRIMARY KEY (id),
+  CONSTRAINT imagespy_platformconfig_platform_id_uniq UNIQUE (platform_id)
+);
+
+CREATE TABLE imagespy_label (
+  id serial NOT NULL,
+  name varchar(255) NOT NULL,
+  platform_id integer NOT NULL REFERENCES imagespy_platform (id),
+  value text NOT NULL,
+  PRIMARY KEY (id),
+  CONSTRAINT imagespy_label_platform_id_name_uniq UNIQUE (platform_id, name)
+);
+
+CREATE INDEX imagespy_label_name_idx ON imagespy_label (name);
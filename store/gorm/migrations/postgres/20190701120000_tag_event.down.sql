@@ -0,0 +1 @@
+DROP TABLE imagespy_tag_event;
@@ -0,0 +1,10 @@
+DROP TABLE imagespy_platform_os_features;
+DROP TABLE imagespy_platform_features;
+DROP TABLE imagespy_osfeature;
+DROP TABLE imagespy_layerofplatform;
+DROP TABLE imagespy_layer_source_images;
+DROP TABLE imagespy_tag;
+DROP TABLE imagespy_platform;
+DROP TABLE imagespy_layer;
+DROP TABLE imagespy_image;
+DROP TABLE imagespy_feature;
@@ -0,0 +1,93 @@
+CREATE TABLE imagespy_feature (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  created_at datetime NOT NULL,
+  name varchar(255) NOT NULL
+);
+
+CREATE TABLE imagespy_image (
+  created_at datetime NOT NULL,
+  digest varchar(71) NOT NULL,
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  name varchar(255) NOT NULL,
+  schema_version integer NOT NULL,
+  scraped_at datetime DEFAULT NULL,
+  CONSTRAINT imagespy_image_digest_name_uniq UNIQUE (digest, name)
+);
+
+CREATE TABLE imagespy_layer (
+  digest varchar(255) NOT NULL,
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  media_type varchar(255) NOT NULL DEFAULT '',
+  size bigint NOT NULL DEFAULT 0,
+  CONSTRAINT imagespy_layer_digest_uniq UNIQUE (digest)
+);
+
+CREATE TABLE imagespy_platform (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  architecture varchar(255) NOT NULL,
+  created datetime DEFAULT NULL,
+  created_at datetime NOT NULL,
+  manifest_digest varchar(255) NOT NULL,
+  media_type varchar(255) NOT NULL DEFAULT '',
+  os varchar(255) NOT NULL,
+  os_version varchar(255) DEFAULT NULL,
+  size bigint NOT NULL DEFAULT 0,
+  variant varchar(255) DEFAULT NULL,
+  image_id integer NOT NULL REFERENCES imagespy_image (id),
+  CONSTRAINT imagespy_platform_image_id_manifest_digest_uniq UNIQUE (image_id, manifest_digest)
+);
+
+CREATE TABLE imagespy_tag (
+  distinction varchar(64) NOT NULL,
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  is_latest boolean NOT NULL,
+  is_tagged boolean NOT NULL,
+  name varchar(50) NOT NULL,
+  image_id integer NOT NULL REFERENCES imagespy_image (id),
+  CONSTRAINT imagespy_tag_distinction_image_id_name_uniq UNIQUE (distinction, image_id, name)
+);
+
+CREATE INDEX imagespy_tag_image_id_idx ON imagespy_tag (image_id);
+
+CREATE TABLE imagespy_layer_source_images (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  layer_id integer NOT NULL REFERENCES imagespy_layer (id),
+  image_id integer NOT NULL REFERENCES imagespy_image (id),
+  CONSTRAINT imagespy_layer_source_images_layer_id_image_id_uniq UNIQUE (layer_id, image_id)
+);
+
+CREATE INDEX imagespy_layer_source_images_image_id_idx ON imagespy_layer_source_images (image_id);
+
+CREATE TABLE imagespy_layerofplatform (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  position integer NOT NULL,
+  layer_id integer NOT NULL REFERENCES imagespy_layer (id),
+  platform_id integer NOT NULL REFERENCES imagespy_platform (id),
+  CONSTRAINT imagespy_layerofplatform_layer_id_platform_id_uniq UNIQUE (layer_id, platform_id)
+);
+
+CREATE INDEX imagespy_layerofplatform_platform_id_idx ON imagespy_layerofplatform (platform_id);
+
+CREATE TABLE imagespy_osfeature (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  created_at datetime NOT NULL,
+  name varchar(255) NOT NULL
+);
+
+CREATE TABLE imagespy_platform_features (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  platform_id integer NOT NULL REFERENCES imagespy_platform (id),
+  feature_id integer NOT NULL REFERENCES imagespy_feature (id),
+  CONSTRAINT imagespy_platform_features_platform_id_feature_id_uniq UNIQUE (platform_id, feature_id)
+);
+
+CREATE INDEX imagespy_platform_features_feature_id_idx ON imagespy_platform_features (feature_id);
+
+CREATE TABLE imagespy_platform_os_features (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  platform_id integer NOT NULL REFERENCES imagespy_platform (id),
+  osfeature_id integer NOT NULL REFERENCES imagespy_osfeature (id),
+  CONSTRAINT imagespy_platform_os_features_platform_id_osfeature_id_uniq UNIQUE (platform_id, osfeature_id)
+);
+
+CREATE INDEX imagespy_platform_os_features_osfeature_id_idx ON imagespy_platform_os_features (osfeature_id);
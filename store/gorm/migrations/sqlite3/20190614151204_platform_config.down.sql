@@ -0,0 +1,2 @@
+DROP TABLE imagespy_label;
+DROP TABLE imagespy_platformconfig;
@@ -0,0 +1,19 @@
+CREATE TABLE imagespy_platformconfig (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  entrypoint text NOT NULL,
+  env text NOT NULL,
+  exposed_ports text NOT NULL,
+  platform_id integer NOT NULL REFERENCES imagespy_platform (id),
+  user_name varchar(255) NOT NULL,
+  CONSTRAINT imagespy_platformconfig_platform_id_uniq UNIQUE (platform_id)
+);
+
+CREATE TABLE imagespy_label (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  name varchar(255) NOT NULL,
+  platform_id integer NOT NULL REFERENCES imagespy_platform (id),
+  value text NOT NULL,
+  CONSTRAINT imagespy_label_platform_id_name_uniq UNIQUE (platform_id, name)
+);
+
+CREATE INDEX imagespy_label_name_idx ON imagespy_label (name);
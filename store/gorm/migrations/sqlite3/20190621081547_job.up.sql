@@ -0,0 +1,15 @@
+CREATE TABLE imagespy_job (
+  id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  action varchar(32) NOT NULL,
+  attempts integer NOT NULL,
+  created_at datetime NOT NULL,
+  last_error text NOT NULL,
+  name varchar(255) NOT NULL,
+  next_run_at datetime NOT NULL,
+  reference varchar(255) NOT NULL,
+  status varchar(16) NOT NULL,
+  updated_at datetime NOT NULL
+);
+
+CREATE INDEX imagespy_job_status_next_run_at_idx ON imagespy_job (status, next_run_at);
+CREATE INDEX imagespy_job_action_name_reference_status_idx ON imagespy_job (action, name, reference, status);
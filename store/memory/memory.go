@@ -0,0 +1,1590 @@
+// Package memory implements a store.Store that keeps all models in memory.
+// It is meant for tests, demos and local development. All data is lost when the process exits.
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/imagespy/api/store"
+)
+
+var (
+	errNestedTransaction = errors.New("nested transactions are not supported")
+	errTransactionDone   = errors.New("transaction has already been committed or rolled back")
+)
+
+type label struct {
+	store.Model
+	Name       string
+	PlatformID int
+	Value      string
+}
+
+type platformFeature struct {
+	store.Model
+	FeatureID  int
+	PlatformID int
+}
+
+type platformOSFeature struct {
+	store.Model
+	OSFeatureID int
+	PlatformID  int
+}
+
+type sourceImageOfLayer struct {
+	store.Model
+	ImageID int
+	LayerID int
+}
+
+// tables holds the models. Every model is stored as a copy that is never modified after it has been inserted.
+// Updates replace the stored copy. This allows a rollback to restore the previous copy.
+// Every write records the affected row first, see record.
+type tables struct {
+	changes            *changes
+	features           map[int]*store.Feature
+	ids                map[string]int
+	images             map[int]*store.Image
+	jobs               map[int]*store.Job
+	labels             map[int]*label
+	layerPositions     map[int]*store.LayerPosition
+	layers             map[int]*store.Layer
+	osFeatures         map[int]*store.OSFeature
+	platformConfigs    map[int]*store.PlatformConfig
+	platformFeatures   map[int]*platformFeature
+	platformOSFeatures map[int]*platformOSFeature
+	platforms          map[int]*store.Platform
+	sourceImages       map[int]*sourceImageOfLayer
+	tagEvents          map[int]*store.TagEvent
+	tags               map[int]*store.Tag
+}
+
+func newTables() *tables {
+	return &tables{
+		features:           map[int]*store.Feature{},
+		ids:                map[string]int{},
+		images:             map[int]*store.Image{},
+		jobs:               map[int]*store.Job{},
+		labels:             map[int]*label{},
+		layerPositions:     map[int]*store.LayerPosition{},
+		layers:             map[int]*store.Layer{},
+		osFeatures:         map[int]*store.OSFeature{},
+		platformConfigs:    map[int]*store.PlatformConfig{},
+		platformFeatures:   map[int]*platformFeature{},
+		platformOSFeatures: map[int]*platformOSFeature{},
+		platforms:          map[int]*store.Platform{},
+		sourceImages:       map[int]*sourceImageOfLayer{},
+		tagEvents:          map[int]*store.TagEvent{},
+		tags:               map[int]*store.Tag{},
+	}
+}
+
+// changes records the rows a transaction modifies, so that a rollback can restore them.
+type changes struct {
+	ids map[string]int
+	// rows maps the name of a table to the previous rows by their ID.
+	// The previous row is the zero value if the row did not exist.
+	rows map[string]map[int]reflect.Value
+}
+
+// begin starts recording the changes of a transaction.
+func (t *tables) begin() {
+	ids := map[string]int{}
+	for k, v := range t.ids {
+		ids[k] = v
+	}
+
+	t.changes = &changes{ids: ids, rows: map[string]map[int]reflect.Value{}}
+}
+
+// end stops recording changes. It restores the recorded rows if rollback is true.
+func (t *tables) end(rollback bool) {
+	if rollback {
+		for name, rows := range t.changes.rows {
+			table := t.table(name)
+			for id, row := range rows {
+				table.SetMapIndex(reflect.ValueOf(id), row)
+			}
+		}
+
+		t.ids = t.changes.ids
+	}
+
+	t.changes = nil
+}
+
+// record remembers the current row of a table before it gets modified.
+// It does nothing if no transaction is running or if the row has been recorded already.
+func (t *tables) record(name string, id int) {
+	if t.changes == nil {
+		return
+	}
+
+	rows, ok := t.changes.rows[name]
+	if !ok {
+		rows = map[int]reflect.Value{}
+		t.changes.rows[name] = rows
+	}
+
+	if _, ok := rows[id]; ok {
+		return
+	}
+
+	rows[id] = t.table(name).MapIndex(reflect.ValueOf(id))
+}
+
+func (t *tables) table(name string) reflect.Value {
+	switch name {
+	case "features":
+		return reflect.ValueOf(t.features)
+	case "images":
+		return reflect.ValueOf(t.images)
+	case "jobs":
+		return reflect.ValueOf(t.jobs)
+	case "labels":
+		return reflect.ValueOf(t.labels)
+	case "layerPositions":
+		return reflect.ValueOf(t.layerPositions)
+	case "layers":
+		return reflect.ValueOf(t.layers)
+	case "osFeatures":
+		return reflect.ValueOf(t.osFeatures)
+	case "platformConfigs":
+		return reflect.ValueOf(t.platformConfigs)
+	case "platformFeatures":
+		return reflect.ValueOf(t.platformFeatures)
+	case "platformOSFeatures":
+		return reflect.ValueOf(t.platformOSFeatures)
+	case "platforms":
+		return reflect.ValueOf(t.platforms)
+	case "sourceImages":
+		return reflect.ValueOf(t.sourceImages)
+	case "tagEvents":
+		return reflect.ValueOf(t.tagEvents)
+	case "tags":
+		return reflect.ValueOf(t.tags)
+	}
+
+	panic("unknown table " + name)
+}
+
+// nextID mimics the auto increment of a column of a database.
+func (t *tables) nextID(table string) int {
+	t.ids[table]++
+	return t.ids[table]
+}
+
+// deletePlatform removes a platform and all data attached to it.
+func (t *tables) deletePlatform(id int) {
+	for k, v := range t.labels {
+		if v.PlatformID == id {
+			t.record("labels", k)
+			delete(t.labels, k)
+		}
+	}
+
+	for k, v := range t.layerPositions {
+		if v.PlatformID == id {
+			t.record("layerPositions", k)
+			delete(t.layerPositions, k)
+		}
+	}
+
+	for k, v := range t.platformConfigs {
+		if v.PlatformID == id {
+			t.record("platformConfigs", k)
+			delete(t.platformConfigs, k)
+		}
+	}
+
+	for k, v := range t.platformFeatures {
+		if v.PlatformID == id {
+			t.record("platformFeatures", k)
+			delete(t.platformFeatures, k)
+		}
+	}
+
+	for k, v := range t.platformOSFeatures {
+		if v.PlatformID == id {
+			t.record("platformOSFeatures", k)
+			delete(t.platformOSFeatures, k)
+		}
+	}
+
+	t.record("platforms", id)
+	delete(t.platforms, id)
+}
+
+func containsInt(values []int, v int) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsString(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sourceImageIDsOfLayer returns the IDs of the source images of a layer in the order they have been added.
+func (t *tables) sourceImageIDsOfLayer(layerID int) []int {
+	links := []*sourceImageOfLayer{}
+	for _, s := range t.sourceImages {
+		if s.LayerID == layerID {
+			links = append(links, s)
+		}
+	}
+
+	sort.Slice(links, func(i, j int) bool { return links[i].ID < links[j].ID })
+	var ids []int
+	for _, s := range links {
+		ids = append(ids, s.ImageID)
+	}
+
+	return ids
+}
+
+type database struct {
+	mutex   sync.RWMutex
+	nowFunc func() time.Time
+	tables  *tables
+}
+
+// conn gives the stores of a memory store or of a transaction access to the tables.
+type conn struct {
+	db *database
+	tx *memoryTransaction
+}
+
+// read calls f with the tables. Writes of other goroutines are blocked until f returns.
+func (c *conn) read(f func(t *tables) error) error {
+	if c.tx != nil {
+		return c.tx.run(f)
+	}
+
+	c.db.mutex.RLock()
+	defer c.db.mutex.RUnlock()
+	return f(c.db.tables)
+}
+
+// write calls f with the tables. Other goroutines are blocked until f returns.
+func (c *conn) write(f func(t *tables) error) error {
+	if c.tx != nil {
+		return c.tx.run(f)
+	}
+
+	c.db.mutex.Lock()
+	defer c.db.mutex.Unlock()
+	return f(c.db.tables)
+}
+
+type memory struct {
+	conn *conn
+}
+
+// New returns an empty in-memory store.
+func New() store.Store {
+	return &memory{
+		conn: &conn{
+			db: &database{nowFunc: time.Now, tables: newTables()},
+		},
+	}
+}
+
+func (m *memory) Close() error {
+	return nil
+}
+
+func (m *memory) Images() store.ImageStore {
+	return &memoryImage{conn: m.conn}
+}
+
+func (m *memory) Jobs() store.JobStore {
+	return &memoryJob{conn: m.conn}
+}
+
+func (m *memory) Layers() store.LayerStore {
+	return &memoryLayer{conn: m.conn}
+}
+
+func (m *memory) LayerPositions() store.LayerPositionStore {
+	return &memoryLayerPosition{conn: m.conn}
+}
+
+func (m *memory) Platforms() store.PlatformStore {
+	return &memoryPlatform{conn: m.conn}
+}
+
+func (m *memory) TagEvents() store.TagEventStore {
+	return &memoryTagEvent{conn: m.conn}
+}
+
+func (m *memory) Tags() store.TagStore {
+	return &memoryTag{conn: m.conn}
+}
+
+// Transaction locks the store until the transaction is committed or rolled back.
+// Other goroutines that access the store wait for the transaction to finish.
+// The transaction records the rows it modifies to restore them on rollback.
+func (m *memory) Transaction() (store.StoreTransaction, error) {
+	if m.conn.tx != nil {
+		return nil, errNestedTransaction
+	}
+
+	m.conn.db.mutex.Lock()
+	m.conn.db.tables.begin()
+	mt := &memoryTransaction{}
+	mt.conn = &conn{db: m.conn.db, tx: mt}
+	return mt, nil
+}
+
+type memoryTransaction struct {
+	memory
+	done  bool
+	mutex sync.Mutex
+}
+
+func (mt *memoryTransaction) Commit() error {
+	return mt.finish(false)
+}
+
+func (mt *memoryTransaction) Rollback() error {
+	return mt.finish(true)
+}
+
+func (mt *memoryTransaction) finish(rollback bool) error {
+	mt.mutex.Lock()
+	defer mt.mutex.Unlock()
+	if mt.done {
+		return errTransactionDone
+	}
+
+	mt.conn.db.tables.end(rollback)
+	mt.done = true
+	mt.conn.db.mutex.Unlock()
+	return nil
+}
+
+// run calls f with the tables of the store. The transaction already holds the lock of the store.
+func (mt *memoryTransaction) run(f func(t *tables) error) error {
+	mt.mutex.Lock()
+	defer mt.mutex.Unlock()
+	if mt.done {
+		return errTransactionDone
+	}
+
+	return f(mt.conn.db.tables)
+}
+
+type memoryImage struct {
+	conn *conn
+}
+
+func (mi *memoryImage) Create(i *store.Image) error {
+	if i.ID != 0 {
+		return fmt.Errorf("Image already created")
+	}
+
+	return mi.conn.write(func(t *tables) error {
+		for _, existing := range t.images {
+			if existing.Digest == i.Digest && existing.Name == i.Name && existing.SchemaVersion == i.SchemaVersion {
+				*i = *existing
+				return nil
+			}
+		}
+
+		if i.CreatedAt.IsZero() {
+			i.CreatedAt = mi.conn.db.nowFunc()
+		}
+
+		i.ID = t.nextID("image")
+		c := *i
+		t.record("images", i.ID)
+		t.images[i.ID] = &c
+		return nil
+	})
+}
+
+func (mi *memoryImage) Delete(i *store.Image) error {
+	return mi.conn.write(func(t *tables) error {
+		for _, p := range t.platforms {
+			if p.ImageID == i.ID {
+				t.deletePlatform(p.ID)
+			}
+		}
+
+		for k, v := range t.sourceImages {
+			if v.ImageID == i.ID {
+				t.record("sourceImages", k)
+				delete(t.sourceImages, k)
+			}
+		}
+
+		for k, v := range t.tags {
+			if v.ImageID == i.ID {
+				t.record("tags", k)
+				delete(t.tags, k)
+			}
+		}
+
+		t.record("images", i.ID)
+		delete(t.images, i.ID)
+		return nil
+	})
+}
+
+// FindByLayerIDHavingLayerCountGreaterThan finds all images which have the layer identified by the given ID and which total number of layers is greater than the given count.
+func (mi *memoryImage) FindByLayerIDHavingLayerCountGreaterThan(layerID, count int) ([]*store.Image, error) {
+	images := []*store.Image{}
+	err := mi.conn.read(func(t *tables) error {
+		layerCounts := map[int]int{}
+		for _, lp := range t.layerPositions {
+			layerCounts[lp.PlatformID]++
+		}
+
+		platformIDs := map[int]struct{}{}
+		for _, lp := range t.layerPositions {
+			if lp.LayerID == layerID && layerCounts[lp.PlatformID] > count {
+				platformIDs[lp.PlatformID] = struct{}{}
+			}
+		}
+
+		for platformID := range platformIDs {
+			p, ok := t.platforms[platformID]
+			if !ok {
+				continue
+			}
+
+			i, ok := t.images[p.ImageID]
+			if !ok {
+				continue
+			}
+
+			c := *i
+			images = append(images, &c)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(images, func(i, j int) bool {
+		if images[i].Name == images[j].Name {
+			return images[i].ID < images[j].ID
+		}
+
+		return images[i].Name < images[j].Name
+	})
+	return images, nil
+}
+
+func (mi *memoryImage) Get(o store.ImageGetOptions) (*store.Image, error) {
+	if o.Digest == "" && o.ID == 0 && o.Name == "" {
+		return nil, fmt.Errorf("Digest, ID and Name not set in ImageGetOptions")
+	}
+
+	joinWithTag := o.TagDistinction != "" || o.TagIsLatest != nil || o.TagName != ""
+	var result *store.Image
+	err := mi.conn.read(func(t *tables) error {
+		candidates := []*store.Image{}
+		for _, i := range t.images {
+			if o.Digest != "" && i.Digest != o.Digest {
+				continue
+			}
+
+			if o.ID != 0 && i.ID != o.ID {
+				continue
+			}
+
+			if o.Name != "" && i.Name != o.Name {
+				continue
+			}
+
+			if joinWithTag && !hasMatchingTag(t, i.ID, o) {
+				continue
+			}
+
+			candidates = append(candidates, i)
+		}
+
+		if len(candidates) == 0 {
+			return store.ErrDoesNotExist
+		}
+
+		sort.Slice(candidates, func(i, j int) bool {
+			if candidates[i].CreatedAt.Equal(candidates[j].CreatedAt) {
+				return candidates[i].ID < candidates[j].ID
+			}
+
+			return candidates[i].CreatedAt.After(candidates[j].CreatedAt)
+		})
+		c := *candidates[0]
+		result = &c
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// hasMatchingTag returns true if the image has a tag that matches all tag options.
+func hasMatchingTag(t *tables, imageID int, o store.ImageGetOptions) bool {
+	for _, tag := range t.tags {
+		if tag.ImageID != imageID {
+			continue
+		}
+
+		if o.TagDistinction != "" && tag.Distinction != o.TagDistinction {
+			continue
+		}
+
+		if o.TagIsLatest != nil && tag.IsLatest != *o.TagIsLatest {
+			continue
+		}
+
+		if o.TagName != "" && tag.Name != o.TagName {
+			continue
+		}
+
+		return true
+	}
+
+	return false
+}
+
+func (mi *memoryImage) List(o store.ImageListOptions) ([]*store.Image, error) {
+	images := []*store.Image{}
+	err := mi.conn.read(func(t *tables) error {
+		for _, i := range t.images {
+			if o.Digest != "" && i.Digest != o.Digest {
+				continue
+			}
+
+			if len(o.Digests) > 0 && !containsString(o.Digests, i.Digest) {
+				continue
+			}
+
+			if len(o.IDs) > 0 && !containsInt(o.IDs, i.ID) {
+				continue
+			}
+
+			if o.Name != "" && i.Name != o.Name {
+				continue
+			}
+
+			if !o.CreatedSince.IsZero() && i.CreatedAt.Before(o.CreatedSince) {
+				continue
+			}
+
+			if !o.CreatedUntil.IsZero() && !i.CreatedAt.Before(o.CreatedUntil) {
+				continue
+			}
+
+			if o.TagDistinction != "" && !hasTagWithDistinction(t, i.ID, o.TagDistinction) {
+				continue
+			}
+
+			c := *i
+			images = append(images, &c)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(images, func(i, j int) bool { return images[i].ID > images[j].ID })
+	if o.Limit > 0 {
+		if o.Offset >= len(images) {
+			return []*store.Image{}, nil
+		}
+
+		images = images[o.Offset:]
+		if len(images) > o.Limit {
+			images = images[:o.Limit]
+		}
+	}
+
+	return images, nil
+}
+
+func hasTagWithDistinction(t *tables, imageID int, distinction string) bool {
+	for _, tag := range t.tags {
+		if tag.ImageID == imageID && tag.Distinction == distinction {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (mi *memoryImage) ListRepositories(o store.RepositoryListOptions) ([]*store.Repository, error) {
+	repositories := []*store.Repository{}
+	err := mi.conn.read(func(t *tables) error {
+		byName := map[string]*store.Repository{}
+		tagNames := map[string]map[string]struct{}{}
+		for _, i := range t.images {
+			if o.NameContains != "" && !strings.Contains(strings.ToLower(i.Name), strings.ToLower(o.NameContains)) {
+				continue
+			}
+
+			if o.NamePrefix != "" && !strings.HasPrefix(strings.ToLower(i.Name), strings.ToLower(o.NamePrefix)) {
+				continue
+			}
+
+			r, ok := byName[i.Name]
+			if !ok {
+				r = &store.Repository{Name: i.Name}
+				byName[i.Name] = r
+				tagNames[i.Name] = map[string]struct{}{}
+				repositories = append(repositories, r)
+			}
+
+			r.ImageCount++
+			if i.ScrapedAt.After(r.ScrapedAt) {
+				r.ScrapedAt = i.ScrapedAt
+			}
+		}
+
+		for _, tag := range t.tags {
+			if !tag.IsTagged {
+				continue
+			}
+
+			i, ok := t.images[tag.ImageID]
+			if !ok {
+				continue
+			}
+
+			if names, ok := tagNames[i.Name]; ok {
+				names[tag.Name] = struct{}{}
+			}
+		}
+
+		for _, r := range repositories {
+			r.TagCount = len(tagNames[r.Name])
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(repositories, func(i, j int) bool {
+		if o.OrderBy == store.RepositoryOrderScrapedAt && !repositories[i].ScrapedAt.Equal(repositories[j].ScrapedAt) {
+			return repositories[i].ScrapedAt.After(repositories[j].ScrapedAt)
+		}
+
+		return repositories[i].Name < repositories[j].Name
+	})
+	if o.Limit > 0 {
+		if o.Offset >= len(repositories) {
+			return []*store.Repository{}, nil
+		}
+
+		repositories = repositories[o.Offset:]
+		if len(repositories) > o.Limit {
+			repositories = repositories[:o.Limit]
+		}
+	}
+
+	return repositories, nil
+}
+
+func (mi *memoryImage) Update(i *store.Image) error {
+	return mi.conn.write(func(t *tables) error {
+		if i.ID == 0 {
+			i.ID = t.nextID("image")
+		}
+
+		c := *i
+		t.record("images", i.ID)
+		t.images[i.ID] = &c
+		return nil
+	})
+}
+
+type memoryJob struct {
+	conn *conn
+}
+
+func (mj *memoryJob) Claim(now time.Time, leaseUntil time.Time) (*store.Job, error) {
+	var result *store.Job
+	err := mj.conn.write(func(t *tables) error {
+		var next *store.Job
+		for _, j := range t.jobs {
+			if j.Status != store.JobStatusPending && j.Status != store.JobStatusRunning {
+				continue
+			}
+
+			if j.NextRunAt.After(now) {
+				continue
+			}
+
+			if next == nil || j.NextRunAt.Before(next.NextRunAt) || (j.NextRunAt.Equal(next.NextRunAt) && j.ID < next.ID) {
+				next = j
+			}
+		}
+
+		if next == nil {
+			return store.ErrDoesNotExist
+		}
+
+		c := *next
+		c.Attempts++
+		c.NextRunAt = leaseUntil
+		c.Status = store.JobStatusRunning
+		c.UpdatedAt = mj.conn.db.nowFunc()
+		t.record("jobs", c.ID)
+		t.jobs[c.ID] = &c
+		r := c
+		result = &r
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (mj *memoryJob) CountByStatus() (map[string]int, error) {
+	counts := map[string]int{}
+	err := mj.conn.read(func(t *tables) error {
+		for _, j := range t.jobs {
+			counts[j.Status]++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+func (mj *memoryJob) Create(j *store.Job) error {
+	if j.ID != 0 {
+		return fmt.Errorf("Job already created")
+	}
+
+	return mj.conn.write(func(t *tables) error {
+		for _, existing := range t.jobs {
+			if existing.Action == j.Action && existing.Name == j.Name && existing.Reference == j.Reference && existing.Status == store.JobStatusPending {
+				*j = *existing
+				return nil
+			}
+		}
+
+		now := mj.conn.db.nowFunc()
+		if j.CreatedAt.IsZero() {
+			j.CreatedAt = now
+		}
+
+		if j.UpdatedAt.IsZero() {
+			j.UpdatedAt = now
+		}
+
+		j.ID = t.nextID("job")
+		j.Status = store.JobStatusPending
+		c := *j
+		t.record("jobs", j.ID)
+		t.jobs[j.ID] = &c
+		return nil
+	})
+}
+
+func (mj *memoryJob) DeleteDone(updatedBefore time.Time) (int, error) {
+	deleted := 0
+	err := mj.conn.write(func(t *tables) error {
+		for id, j := range t.jobs {
+			if j.Status == store.JobStatusDone && j.UpdatedAt.Before(updatedBefore) {
+				t.record("jobs", id)
+				delete(t.jobs, id)
+				deleted++
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return deleted, nil
+}
+
+func (mj *memoryJob) Get(o store.JobGetOptions) (*store.Job, error) {
+	var result *store.Job
+	err := mj.conn.read(func(t *tables) error {
+		j, ok := t.jobs[o.ID]
+		if !ok {
+			return store.ErrDoesNotExist
+		}
+
+		c := *j
+		result = &c
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (mj *memoryJob) List(o store.JobListOptions) ([]*store.Job, error) {
+	jobs := []*store.Job{}
+	err := mj.conn.read(func(t *tables) error {
+		for _, j := range t.jobs {
+			if o.Status != "" && j.Status != o.Status {
+				continue
+			}
+
+			c := *j
+			jobs = append(jobs, &c)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID > jobs[j].ID })
+	if o.Limit > 0 && len(jobs) > o.Limit {
+		jobs = jobs[:o.Limit]
+	}
+
+	return jobs, nil
+}
+
+func (mj *memoryJob) Update(j *store.Job) error {
+	return mj.conn.write(func(t *tables) error {
+		if j.ID == 0 {
+			j.ID = t.nextID("job")
+		}
+
+		j.UpdatedAt = mj.conn.db.nowFunc()
+		c := *j
+		t.record("jobs", j.ID)
+		t.jobs[j.ID] = &c
+		return nil
+	})
+}
+
+type memoryLayer struct {
+	conn *conn
+}
+
+func (ml *memoryLayer) Create(l *store.Layer) error {
+	return ml.conn.write(func(t *tables) error {
+		var stored *store.Layer
+		for _, existing := range t.layers {
+			if existing.Digest == l.Digest {
+				stored = existing
+				break
+			}
+		}
+
+		if stored == nil {
+			stored = &store.Layer{Digest: l.Digest}
+			stored.ID = t.nextID("layer")
+		} else {
+			c := *stored
+			stored = &c
+		}
+
+		stored.MediaType = l.MediaType
+		stored.Size = l.Size
+		stored.SourceImageIDs = nil
+		t.record("layers", stored.ID)
+		t.layers[stored.ID] = stored
+		l.ID = stored.ID
+		for _, imageID := range l.SourceImageIDs {
+			addSourceImage(t, l.ID, imageID)
+		}
+
+		return nil
+	})
+}
+
+// addSourceImage links an image to a layer if it is not linked already.
+func addSourceImage(t *tables, layerID, imageID int) {
+	for _, s := range t.sourceImages {
+		if s.LayerID == layerID && s.ImageID == imageID {
+			return
+		}
+	}
+
+	s := &sourceImageOfLayer{ImageID: imageID, LayerID: layerID}
+	s.ID = t.nextID("layer_source_images")
+	t.record("sourceImages", s.ID)
+	t.sourceImages[s.ID] = s
+}
+
+func (ml *memoryLayer) Get(o store.LayerGetOptions) (*store.Layer, error) {
+	var result *store.Layer
+	err := ml.conn.read(func(t *tables) error {
+		for _, l := range sortedLayers(t) {
+			if o.Digest != "" && l.Digest != o.Digest {
+				continue
+			}
+
+			if o.ID != 0 && l.ID != o.ID {
+				continue
+			}
+
+			c := *l
+			c.SourceImageIDs = t.sourceImageIDsOfLayer(l.ID)
+			result = &c
+			return nil
+		}
+
+		return store.ErrDoesNotExist
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (ml *memoryLayer) List(o store.LayerListOptions) ([]*store.Layer, error) {
+	layers := []*store.Layer{}
+	err := ml.conn.read(func(t *tables) error {
+		layerIDs := map[int]struct{}{}
+		for _, lp := range t.layerPositions {
+			if lp.PlatformID == o.PlatformID {
+				layerIDs[lp.LayerID] = struct{}{}
+			}
+		}
+
+		for _, l := range sortedLayers(t) {
+			if o.PlatformID != 0 {
+				if _, ok := layerIDs[l.ID]; !ok {
+					continue
+				}
+			}
+
+			if len(o.IDs) > 0 && !containsInt(o.IDs, l.ID) {
+				continue
+			}
+
+			c := *l
+			c.SourceImageIDs = t.sourceImageIDsOfLayer(l.ID)
+			layers = append(layers, &c)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return layers, nil
+}
+
+func sortedLayers(t *tables) []*store.Layer {
+	layers := []*store.Layer{}
+	for _, l := range t.layers {
+		layers = append(layers, l)
+	}
+
+	sort.Slice(layers, func(i, j int) bool { return layers[i].ID < layers[j].ID })
+	return layers
+}
+
+// Update replaces the source images of the layer. Other fields are not updated.
+func (ml *memoryLayer) Update(l *store.Layer) error {
+	return ml.conn.write(func(t *tables) error {
+		for k, v := range t.sourceImages {
+			if v.LayerID == l.ID {
+				t.record("sourceImages", k)
+				delete(t.sourceImages, k)
+			}
+		}
+
+		for _, imageID := range l.SourceImageIDs {
+			s := &sourceImageOfLayer{ImageID: imageID, LayerID: l.ID}
+			s.ID = t.nextID("layer_source_images")
+			t.record("sourceImages", s.ID)
+			t.sourceImages[s.ID] = s
+		}
+
+		return nil
+	})
+}
+
+type memoryLayerPosition struct {
+	conn *conn
+}
+
+func (mlp *memoryLayerPosition) Create(lp *store.LayerPosition) error {
+	return mlp.conn.write(func(t *tables) error {
+		for _, existing := range t.layerPositions {
+			if existing.LayerID == lp.LayerID && existing.PlatformID == lp.PlatformID && existing.Position == lp.Position {
+				*lp = *existing
+				return nil
+			}
+		}
+
+		lp.ID = t.nextID("layerofplatform")
+		c := *lp
+		t.record("layerPositions", lp.ID)
+		t.layerPositions[lp.ID] = &c
+		return nil
+	})
+}
+
+func (mlp *memoryLayerPosition) List(o store.LayerPositionListOptions) ([]*store.LayerPosition, error) {
+	positions := []*store.LayerPosition{}
+	err := mlp.conn.read(func(t *tables) error {
+		for _, lp := range t.layerPositions {
+			if o.LayerID != 0 && lp.LayerID != o.LayerID {
+				continue
+			}
+
+			if o.PlatformID != 0 && lp.PlatformID != o.PlatformID {
+				continue
+			}
+
+			c := *lp
+			positions = append(positions, &c)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(positions, func(i, j int) bool {
+		if positions[i].Position == positions[j].Position {
+			return positions[i].ID < positions[j].ID
+		}
+
+		return positions[i].Position < positions[j].Position
+	})
+	return positions, nil
+}
+
+type memoryPlatform struct {
+	conn *conn
+}
+
+func (mp *memoryPlatform) Create(p *store.Platform) error {
+	return mp.conn.write(func(t *tables) error {
+		for _, existing := range t.platforms {
+			if existing.ImageID == p.ImageID && existing.ManifestDigest == p.ManifestDigest {
+				return fmt.Errorf("platform with manifest %s of image %d already exists", p.ManifestDigest, p.ImageID)
+			}
+		}
+
+		now := mp.conn.db.nowFunc()
+		if p.CreatedAt.IsZero() {
+			p.CreatedAt = now
+		}
+
+		if p.ID == 0 {
+			p.ID = t.nextID("platform")
+		}
+
+		// Features are saved as associations of the platform, like gorm does.
+		for _, f := range p.Features {
+			if f.ID == 0 {
+				if f.CreatedAt.IsZero() {
+					f.CreatedAt = now
+				}
+
+				f.ID = t.nextID("feature")
+			}
+
+			c := *f
+			t.record("features", f.ID)
+			t.features[f.ID] = &c
+			pf := &platformFeature{FeatureID: f.ID, PlatformID: p.ID}
+			pf.ID = t.nextID("platform_features")
+			t.record("platformFeatures", pf.ID)
+			t.platformFeatures[pf.ID] = pf
+		}
+
+		for _, f := range p.OSFeatures {
+			if f.ID == 0 {
+				if f.CreatedAt.IsZero() {
+					f.CreatedAt = now
+				}
+
+				f.ID = t.nextID("osfeature")
+			}
+
+			c := *f
+			t.record("osFeatures", f.ID)
+			t.osFeatures[f.ID] = &c
+			pf := &platformOSFeature{OSFeatureID: f.ID, PlatformID: p.ID}
+			pf.ID = t.nextID("platform_os_features")
+			t.record("platformOSFeatures", pf.ID)
+			t.platformOSFeatures[pf.ID] = pf
+		}
+
+		c := *p
+		c.Features = nil
+		c.OSFeatures = nil
+		t.record("platforms", p.ID)
+		t.platforms[p.ID] = &c
+		return nil
+	})
+}
+
+func (mp *memoryPlatform) CreateConfig(c *store.PlatformConfig) error {
+	if c.ID != 0 {
+		return fmt.Errorf("Platform config already created")
+	}
+
+	return mp.conn.write(func(t *tables) error {
+		for _, existing := range t.platformConfigs {
+			if existing.PlatformID == c.PlatformID {
+				return fmt.Errorf("config of platform %d already exists", c.PlatformID)
+			}
+		}
+
+		c.ID = t.nextID("platformconfig")
+		stored := copyPlatformConfig(c)
+		stored.Labels = nil
+		t.record("platformConfigs", c.ID)
+		t.platformConfigs[c.ID] = stored
+		for name, value := range c.Labels {
+			l := &label{Name: name, PlatformID: c.PlatformID, Value: value}
+			l.ID = t.nextID("label")
+			t.record("labels", l.ID)
+			t.labels[l.ID] = l
+		}
+
+		return nil
+	})
+}
+
+func copyPlatformConfig(c *store.PlatformConfig) *store.PlatformConfig {
+	r := *c
+	r.Entrypoint = copyStrings(c.Entrypoint)
+	r.Env = copyStrings(c.Env)
+	r.ExposedPorts = copyStrings(c.ExposedPorts)
+	return &r
+}
+
+// copyStrings copies a slice and keeps the difference between a nil slice and an empty slice.
+func copyStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+
+	return append([]string{}, s...)
+}
+
+func (mp *memoryPlatform) Get(o store.PlatformGetOptions) (*store.Platform, error) {
+	var result *store.Platform
+	err := mp.conn.read(func(t *tables) error {
+		for _, p := range sortedPlatforms(t) {
+			if o.Architecture != "" && p.Architecture != o.Architecture {
+				continue
+			}
+
+			if o.ImageID != 0 && p.ImageID != o.ImageID {
+				continue
+			}
+
+			if o.OS != "" && p.OS != o.OS {
+				continue
+			}
+
+			if o.OSVersion != nil && p.OSVersion != *o.OSVersion {
+				continue
+			}
+
+			if o.Variant != nil && p.Variant != *o.Variant {
+				continue
+			}
+
+			c := *p
+			result = &c
+			return nil
+		}
+
+		return store.ErrDoesNotExist
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (mp *memoryPlatform) GetConfig(o store.PlatformConfigGetOptions) (*store.PlatformConfig, error) {
+	if o.PlatformID == 0 {
+		return nil, fmt.Errorf("required field PlatformID not set")
+	}
+
+	var result *store.PlatformConfig
+	err := mp.conn.read(func(t *tables) error {
+		for _, c := range t.platformConfigs {
+			if c.PlatformID != o.PlatformID {
+				continue
+			}
+
+			result = copyPlatformConfig(c)
+			for _, l := range t.labels {
+				if l.PlatformID != o.PlatformID {
+					continue
+				}
+
+				if result.Labels == nil {
+					result.Labels = map[string]string{}
+				}
+
+				result.Labels[l.Name] = l.Value
+			}
+
+			return nil
+		}
+
+		return store.ErrDoesNotExist
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (mp *memoryPlatform) List(o store.PlatformListOptions) ([]*store.Platform, error) {
+	platforms := []*store.Platform{}
+	err := mp.conn.read(func(t *tables) error {
+		for _, p := range sortedPlatforms(t) {
+			if o.ImageID != 0 && p.ImageID != o.ImageID {
+				continue
+			}
+
+			if len(o.ImageIDs) > 0 && !containsInt(o.ImageIDs, p.ImageID) {
+				continue
+			}
+
+			if o.LabelName != "" && !hasMatchingLabel(t, p.ID, o.LabelName, o.LabelValue) {
+				continue
+			}
+
+			if o.LayerDigest != "" && !hasLayer(t, p.ID, o.LayerDigest) {
+				continue
+			}
+
+			c := *p
+			c.Features = featuresOfPlatform(t, p.ID)
+			c.OSFeatures = osFeaturesOfPlatform(t, p.ID)
+			platforms = append(platforms, &c)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return platforms, nil
+}
+
+func sortedPlatforms(t *tables) []*store.Platform {
+	platforms := []*store.Platform{}
+	for _, p := range t.platforms {
+		platforms = append(platforms, p)
+	}
+
+	sort.Slice(platforms, func(i, j int) bool { return platforms[i].ID < platforms[j].ID })
+	return platforms
+}
+
+func hasLayer(t *tables, platformID int, digest string) bool {
+	for _, lp := range t.layerPositions {
+		if lp.PlatformID != platformID {
+			continue
+		}
+
+		l, ok := t.layers[lp.LayerID]
+		if ok && l.Digest == digest {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasMatchingLabel(t *tables, platformID int, name, value string) bool {
+	for _, l := range t.labels {
+		if l.PlatformID == platformID && l.Name == name && (value == "" || l.Value == value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func featuresOfPlatform(t *tables, platformID int) []*store.Feature {
+	features := []*store.Feature{}
+	for _, pf := range t.platformFeatures {
+		if pf.PlatformID != platformID {
+			continue
+		}
+
+		f, ok := t.features[pf.FeatureID]
+		if ok {
+			c := *f
+			features = append(features, &c)
+		}
+	}
+
+	sort.Slice(features, func(i, j int) bool { return features[i].ID < features[j].ID })
+	return features
+}
+
+func osFeaturesOfPlatform(t *tables, platformID int) []*store.OSFeature {
+	features := []*store.OSFeature{}
+	for _, pf := range t.platformOSFeatures {
+		if pf.PlatformID != platformID {
+			continue
+		}
+
+		f, ok := t.osFeatures[pf.OSFeatureID]
+		if ok {
+			c := *f
+			features = append(features, &c)
+		}
+	}
+
+	sort.Slice(features, func(i, j int) bool { return features[i].ID < features[j].ID })
+	return features
+}
+
+type memoryTagEvent struct {
+	conn *conn
+}
+
+func (me *memoryTagEvent) Create(e *store.TagEvent) error {
+	if e.ID != 0 {
+		return fmt.Errorf("TagEvent already created")
+	}
+
+	return me.conn.write(func(t *tables) error {
+		if e.CreatedAt.IsZero() {
+			e.CreatedAt = me.conn.db.nowFunc()
+		}
+
+		e.ID = t.nextID("tag_event")
+		c := *e
+		t.record("tagEvents", e.ID)
+		t.tagEvents[e.ID] = &c
+		return nil
+	})
+}
+
+func (me *memoryTagEvent) List(o store.TagEventListOptions) ([]*store.TagEvent, error) {
+	events := []*store.TagEvent{}
+	err := me.conn.read(func(t *tables) error {
+		for _, e := range t.tagEvents {
+			if o.ImageName != "" && e.ImageName != o.ImageName {
+				continue
+			}
+
+			if o.TagName != "" && e.TagName != o.TagName {
+				continue
+			}
+
+			c := *e
+			events = append(events, &c)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(events, func(i, j int) bool { return events[i].ID > events[j].ID })
+	if o.Limit > 0 {
+		if o.Offset >= len(events) {
+			return []*store.TagEvent{}, nil
+		}
+
+		events = events[o.Offset:]
+		if len(events) > o.Limit {
+			events = events[:o.Limit]
+		}
+	}
+
+	return events, nil
+}
+
+type memoryTag struct {
+	conn *conn
+}
+
+func (mt *memoryTag) Create(tag *store.Tag) error {
+	return mt.conn.write(func(t *tables) error {
+		for _, existing := range t.tags {
+			if existing.Distinction == tag.Distinction && existing.ImageID == tag.ImageID && existing.Name == tag.Name {
+				return fmt.Errorf("tag %s of distinction %s of image %d already exists", tag.Name, tag.Distinction, tag.ImageID)
+			}
+		}
+
+		if tag.ID == 0 {
+			tag.ID = t.nextID("tag")
+		}
+
+		c := *tag
+		t.record("tags", tag.ID)
+		t.tags[tag.ID] = &c
+		return nil
+	})
+}
+
+func (mt *memoryTag) Get(o store.TagGetOptions) (*store.Tag, error) {
+	if o.ImageName == "" {
+		return nil, fmt.Errorf("required field ImageName not set")
+	}
+
+	var result *store.Tag
+	err := mt.conn.read(func(t *tables) error {
+		for _, tag := range sortedTags(t) {
+			i, ok := t.images[tag.ImageID]
+			if !ok || i.Name != o.ImageName {
+				continue
+			}
+
+			if o.ImageID != 0 && tag.ImageID != o.ImageID {
+				continue
+			}
+
+			if o.Distinction != "" && tag.Distinction != o.Distinction {
+				continue
+			}
+
+			if o.IsLatest != nil && tag.IsLatest != *o.IsLatest {
+				continue
+			}
+
+			if o.Name != "" && tag.Name != o.Name {
+				continue
+			}
+
+			c := *tag
+			result = &c
+			return nil
+		}
+
+		return store.ErrDoesNotExist
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (mt *memoryTag) List(o store.TagListOptions) ([]*store.Tag, error) {
+	tags := []*store.Tag{}
+	err := mt.conn.read(func(t *tables) error {
+		for _, tag := range sortedTags(t) {
+			if o.Distinction != "" && tag.Distinction != o.Distinction {
+				continue
+			}
+
+			if o.ImageID != 0 && tag.ImageID != o.ImageID {
+				continue
+			}
+
+			if len(o.ImageIDs) > 0 && !containsInt(o.ImageIDs, tag.ImageID) {
+				continue
+			}
+
+			if o.ImageName != "" || len(o.ImageNames) > 0 {
+				i, ok := t.images[tag.ImageID]
+				if !ok {
+					continue
+				}
+
+				if o.ImageName != "" && i.Name != o.ImageName {
+					continue
+				}
+
+				if len(o.ImageNames) > 0 && !containsString(o.ImageNames, i.Name) {
+					continue
+				}
+			}
+
+			if o.IsLatest != nil && tag.IsLatest != *o.IsLatest {
+				continue
+			}
+
+			if o.IsTagged != nil && tag.IsTagged != *o.IsTagged {
+				continue
+			}
+
+			if len(o.Names) > 0 && !containsString(o.Names, tag.Name) {
+				continue
+			}
+
+			c := *tag
+			tags = append(tags, &c)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+func sortedTags(t *tables) []*store.Tag {
+	tags := []*store.Tag{}
+	for _, tag := range t.tags {
+		tags = append(tags, tag)
+	}
+
+	sort.Slice(tags, func(i, j int) bool { return tags[i].ID < tags[j].ID })
+	return tags
+}
+
+func (mt *memoryTag) Update(tag *store.Tag) error {
+	return mt.conn.write(func(t *tables) error {
+		if tag.ID == 0 {
+			tag.ID = t.nextID("tag")
+		}
+
+		c := *tag
+		t.record("tags", tag.ID)
+		t.tags[tag.ID] = &c
+		return nil
+	})
+}
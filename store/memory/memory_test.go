@@ -0,0 +1,110 @@
+package memory
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/imagespy/api/store"
+	"github.com/imagespy/api/store/storetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemory(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		return New()
+	})
+}
+
+func TestMemory_TransactionCommit(t *testing.T) {
+	s := New()
+	tx, err := s.Transaction()
+	require.NoError(t, err)
+
+	i := &store.Image{Digest: "sha256:abc", Name: "unit.test/commit"}
+	require.NoError(t, tx.Images().Create(i))
+	require.NoError(t, tx.Tags().Create(&store.Tag{ImageID: i.ID, Name: "1.0"}))
+	require.NoError(t, tx.Commit())
+
+	stored, err := s.Images().Get(store.ImageGetOptions{ID: i.ID})
+	require.NoError(t, err)
+	assert.Equal(t, i, stored)
+
+	tags, err := s.Tags().List(store.TagListOptions{ImageID: i.ID})
+	require.NoError(t, err)
+	assert.Len(t, tags, 1)
+}
+
+func TestMemory_TransactionRollback(t *testing.T) {
+	s := New()
+	existing := &store.Image{Digest: "sha256:abc", Name: "unit.test/rollback"}
+	require.NoError(t, s.Images().Create(existing))
+
+	tx, err := s.Transaction()
+	require.NoError(t, err)
+	require.NoError(t, tx.Images().Create(&store.Image{Digest: "sha256:def", Name: "unit.test/rollback"}))
+	existing.SchemaVersion = 2
+	require.NoError(t, tx.Images().Update(existing))
+	require.NoError(t, tx.Rollback())
+
+	images, err := s.Images().List(store.ImageListOptions{Name: "unit.test/rollback"})
+	require.NoError(t, err)
+	require.Len(t, images, 1)
+	assert.Equal(t, 0, images[0].SchemaVersion)
+
+	// IDs handed out by the rolled back transaction are reused, like the auto increment of an aborted transaction in SQLite.
+	next := &store.Image{Digest: "sha256:ghi", Name: "unit.test/rollback"}
+	require.NoError(t, s.Images().Create(next))
+	assert.Equal(t, 2, next.ID)
+}
+
+func TestMemory_TransactionDone(t *testing.T) {
+	s := New()
+	tx, err := s.Transaction()
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+
+	assert.Equal(t, errTransactionDone, tx.Commit())
+	assert.Equal(t, errTransactionDone, tx.Rollback())
+	_, err = tx.Images().List(store.ImageListOptions{})
+	assert.Equal(t, errTransactionDone, err)
+
+	_, err = tx.Transaction()
+	assert.Equal(t, errNestedTransaction, err)
+}
+
+func TestMemory_Concurrency(t *testing.T) {
+	s := New()
+	wg := &sync.WaitGroup{}
+	for n := 0; n < 10; n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			tx, err := s.Transaction()
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			i := &store.Image{Digest: fmt.Sprintf("sha256:%d", n), Name: "unit.test/concurrency"}
+			assert.NoError(t, tx.Images().Create(i))
+			if n%2 == 0 {
+				assert.NoError(t, tx.Rollback())
+				return
+			}
+
+			assert.NoError(t, tx.Commit())
+			assert.NoError(t, s.Jobs().Create(&store.Job{Action: store.JobActionScrape, Name: i.Name, Reference: i.Digest}))
+			_, err = s.Images().List(store.ImageListOptions{})
+			assert.NoError(t, err)
+		}(n)
+	}
+
+	wg.Wait()
+	images, err := s.Images().List(store.ImageListOptions{Name: "unit.test/concurrency"})
+	require.NoError(t, err)
+	assert.Len(t, images, 5)
+	counts, err := s.Jobs().CountByStatus()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{store.JobStatusPending: 5}, counts)
+}
@@ -8,6 +8,7 @@ import (
 	gomock "github.com/golang/mock/gomock"
 	store "github.com/imagespy/api/store"
 	reflect "reflect"
+	time "time"
 )
 
 // MockStore is a mock of Store interface
@@ -61,6 +62,20 @@ func (mr *MockStoreMockRecorder) Images() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Images", reflect.TypeOf((*MockStore)(nil).Images))
 }
 
+// Jobs mocks base method
+func (m *MockStore) Jobs() store.JobStore {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Jobs")
+	ret0, _ := ret[0].(store.JobStore)
+	return ret0
+}
+
+// Jobs indicates an expected call of Jobs
+func (mr *MockStoreMockRecorder) Jobs() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Jobs", reflect.TypeOf((*MockStore)(nil).Jobs))
+}
+
 // Layers mocks base method
 func (m *MockStore) Layers() store.LayerStore {
 	m.ctrl.T.Helper()
@@ -103,6 +118,20 @@ func (mr *MockStoreMockRecorder) Platforms() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Platforms", reflect.TypeOf((*MockStore)(nil).Platforms))
 }
 
+// TagEvents mocks base method
+func (m *MockStore) TagEvents() store.TagEventStore {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TagEvents")
+	ret0, _ := ret[0].(store.TagEventStore)
+	return ret0
+}
+
+// TagEvents indicates an expected call of TagEvents
+func (mr *MockStoreMockRecorder) TagEvents() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TagEvents", reflect.TypeOf((*MockStore)(nil).TagEvents))
+}
+
 // Tags mocks base method
 func (m *MockStore) Tags() store.TagStore {
 	m.ctrl.T.Helper()
@@ -183,6 +212,20 @@ func (mr *MockStoreTransactionMockRecorder) Images() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Images", reflect.TypeOf((*MockStoreTransaction)(nil).Images))
 }
 
+// Jobs mocks base method
+func (m *MockStoreTransaction) Jobs() store.JobStore {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Jobs")
+	ret0, _ := ret[0].(store.JobStore)
+	return ret0
+}
+
+// Jobs indicates an expected call of Jobs
+func (mr *MockStoreTransactionMockRecorder) Jobs() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Jobs", reflect.TypeOf((*MockStoreTransaction)(nil).Jobs))
+}
+
 // Layers mocks base method
 func (m *MockStoreTransaction) Layers() store.LayerStore {
 	m.ctrl.T.Helper()
@@ -225,6 +268,20 @@ func (mr *MockStoreTransactionMockRecorder) Platforms() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Platforms", reflect.TypeOf((*MockStoreTransaction)(nil).Platforms))
 }
 
+// TagEvents mocks base method
+func (m *MockStoreTransaction) TagEvents() store.TagEventStore {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TagEvents")
+	ret0, _ := ret[0].(store.TagEventStore)
+	return ret0
+}
+
+// TagEvents indicates an expected call of TagEvents
+func (mr *MockStoreTransactionMockRecorder) TagEvents() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TagEvents", reflect.TypeOf((*MockStoreTransaction)(nil).TagEvents))
+}
+
 // Tags mocks base method
 func (m *MockStoreTransaction) Tags() store.TagStore {
 	m.ctrl.T.Helper()
@@ -319,6 +376,20 @@ func (mr *MockImageStoreMockRecorder) Create(i interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockImageStore)(nil).Create), i)
 }
 
+// Delete mocks base method
+func (m *MockImageStore) Delete(i *store.Image) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", i)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete
+func (mr *MockImageStoreMockRecorder) Delete(i interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockImageStore)(nil).Delete), i)
+}
+
 // FindByLayerIDHavingLayerCountGreaterThan mocks base method
 func (m *MockImageStore) FindByLayerIDHavingLayerCountGreaterThan(layerID, count int) ([]*store.Image, error) {
 	m.ctrl.T.Helper()
@@ -364,6 +435,21 @@ func (mr *MockImageStoreMockRecorder) List(o interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockImageStore)(nil).List), o)
 }
 
+// ListRepositories mocks base method
+func (m *MockImageStore) ListRepositories(o store.RepositoryListOptions) ([]*store.Repository, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListRepositories", o)
+	ret0, _ := ret[0].([]*store.Repository)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListRepositories indicates an expected call of ListRepositories
+func (mr *MockImageStoreMockRecorder) ListRepositories(o interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRepositories", reflect.TypeOf((*MockImageStore)(nil).ListRepositories), o)
+}
+
 // Update mocks base method
 func (m *MockImageStore) Update(i *store.Image) error {
 	m.ctrl.T.Helper()
@@ -378,6 +464,132 @@ func (mr *MockImageStoreMockRecorder) Update(i interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockImageStore)(nil).Update), i)
 }
 
+// MockJobStore is a mock of JobStore interface
+type MockJobStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockJobStoreMockRecorder
+}
+
+// MockJobStoreMockRecorder is the mock recorder for MockJobStore
+type MockJobStoreMockRecorder struct {
+	mock *MockJobStore
+}
+
+// NewMockJobStore creates a new mock instance
+func NewMockJobStore(ctrl *gomock.Controller) *MockJobStore {
+	mock := &MockJobStore{ctrl: ctrl}
+	mock.recorder = &MockJobStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockJobStore) EXPECT() *MockJobStoreMockRecorder {
+	return m.recorder
+}
+
+// Claim mocks base method
+func (m *MockJobStore) Claim(now, leaseUntil time.Time) (*store.Job, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Claim", now, leaseUntil)
+	ret0, _ := ret[0].(*store.Job)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Claim indicates an expected call of Claim
+func (mr *MockJobStoreMockRecorder) Claim(now, leaseUntil interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Claim", reflect.TypeOf((*MockJobStore)(nil).Claim), now, leaseUntil)
+}
+
+// CountByStatus mocks base method
+func (m *MockJobStore) CountByStatus() (map[string]int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountByStatus")
+	ret0, _ := ret[0].(map[string]int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountByStatus indicates an expected call of CountByStatus
+func (mr *MockJobStoreMockRecorder) CountByStatus() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountByStatus", reflect.TypeOf((*MockJobStore)(nil).CountByStatus))
+}
+
+// Create mocks base method
+func (m *MockJobStore) Create(j *store.Job) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", j)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create
+func (mr *MockJobStoreMockRecorder) Create(j interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockJobStore)(nil).Create), j)
+}
+
+// DeleteDone mocks base method
+func (m *MockJobStore) DeleteDone(updatedBefore time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteDone", updatedBefore)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteDone indicates an expected call of DeleteDone
+func (mr *MockJobStoreMockRecorder) DeleteDone(updatedBefore interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteDone", reflect.TypeOf((*MockJobStore)(nil).DeleteDone), updatedBefore)
+}
+
+// Get mocks base method
+func (m *MockJobStore) Get(o store.JobGetOptions) (*store.Job, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", o)
+	ret0, _ := ret[0].(*store.Job)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get
+func (mr *MockJobStoreMockRecorder) Get(o interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockJobStore)(nil).Get), o)
+}
+
+// List mocks base method
+func (m *MockJobStore) List(o store.JobListOptions) ([]*store.Job, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", o)
+	ret0, _ := ret[0].([]*store.Job)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List
+func (mr *MockJobStoreMockRecorder) List(o interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockJobStore)(nil).List), o)
+}
+
+// Update mocks base method
+func (m *MockJobStore) Update(j *store.Job) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", j)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update
+func (mr *MockJobStoreMockRecorder) Update(j interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockJobStore)(nil).Update), j)
+}
+
 // MockLayerStore is a mock of LayerStore interface
 type MockLayerStore struct {
 	ctrl     *gomock.Controller
@@ -548,6 +760,20 @@ func (mr *MockPlatformStoreMockRecorder) Create(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockPlatformStore)(nil).Create), arg0)
 }
 
+// CreateConfig mocks base method
+func (m *MockPlatformStore) CreateConfig(arg0 *store.PlatformConfig) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateConfig", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateConfig indicates an expected call of CreateConfig
+func (mr *MockPlatformStoreMockRecorder) CreateConfig(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateConfig", reflect.TypeOf((*MockPlatformStore)(nil).CreateConfig), arg0)
+}
+
 // Get mocks base method
 func (m *MockPlatformStore) Get(o store.PlatformGetOptions) (*store.Platform, error) {
 	m.ctrl.T.Helper()
@@ -563,6 +789,21 @@ func (mr *MockPlatformStoreMockRecorder) Get(o interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockPlatformStore)(nil).Get), o)
 }
 
+// GetConfig mocks base method
+func (m *MockPlatformStore) GetConfig(o store.PlatformConfigGetOptions) (*store.PlatformConfig, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetConfig", o)
+	ret0, _ := ret[0].(*store.PlatformConfig)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetConfig indicates an expected call of GetConfig
+func (mr *MockPlatformStoreMockRecorder) GetConfig(o interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConfig", reflect.TypeOf((*MockPlatformStore)(nil).GetConfig), o)
+}
+
 // List mocks base method
 func (m *MockPlatformStore) List(o store.PlatformListOptions) ([]*store.Platform, error) {
 	m.ctrl.T.Helper()
@@ -578,6 +819,58 @@ func (mr *MockPlatformStoreMockRecorder) List(o interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockPlatformStore)(nil).List), o)
 }
 
+// MockTagEventStore is a mock of TagEventStore interface
+type MockTagEventStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockTagEventStoreMockRecorder
+}
+
+// MockTagEventStoreMockRecorder is the mock recorder for MockTagEventStore
+type MockTagEventStoreMockRecorder struct {
+	mock *MockTagEventStore
+}
+
+// NewMockTagEventStore creates a new mock instance
+func NewMockTagEventStore(ctrl *gomock.Controller) *MockTagEventStore {
+	mock := &MockTagEventStore{ctrl: ctrl}
+	mock.recorder = &MockTagEventStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockTagEventStore) EXPECT() *MockTagEventStoreMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method
+func (m *MockTagEventStore) Create(e *store.TagEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", e)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create
+func (mr *MockTagEventStoreMockRecorder) Create(e interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockTagEventStore)(nil).Create), e)
+}
+
+// List mocks base method
+func (m *MockTagEventStore) List(o store.TagEventListOptions) ([]*store.TagEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", o)
+	ret0, _ := ret[0].([]*store.TagEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List
+func (mr *MockTagEventStoreMockRecorder) List(o interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockTagEventStore)(nil).List), o)
+}
+
 // MockTagStore is a mock of TagStore interface
 type MockTagStore struct {
 	ctrl     *gomock.Controller
@@ -31,9 +31,61 @@ func (Image) TableName() string {
 	return "imagespy_image"
 }
 
+// Repository summarizes the images that share a name.
+type Repository struct {
+	// ImageCount is the number of digests of the repository.
+	ImageCount int
+	Name       string
+	// ScrapedAt is the most recent time an image of the repository has been scraped.
+	ScrapedAt time.Time
+	// TagCount is the number of distinct names of the tags that currently point to an image of the repository.
+	TagCount int
+}
+
+const (
+	// JobActionDeleteImage removes an image that has been deleted from the registry. The reference of the job is a digest.
+	JobActionDeleteImage = "delete_image"
+	// JobActionDeleteTag untags a tag that has been deleted from the registry. The reference of the job is a tag.
+	JobActionDeleteTag = "delete_tag"
+	// JobActionScrape scrapes an image and the latest version of the image. The reference of the job is a tag.
+	JobActionScrape = "scrape"
+
+	// JobStatusDead marks a job that failed too often and will not be retried.
+	JobStatusDead = "dead"
+	// JobStatusDone marks a job that has been processed successfully.
+	JobStatusDone = "done"
+	// JobStatusPending marks a job that waits to be processed.
+	JobStatusPending = "pending"
+	// JobStatusRunning marks a job that is being processed by a worker.
+	JobStatusRunning = "running"
+)
+
+// Job is a unit of work in the queue of the server, e.g. created by an event of a registry.
+type Job struct {
+	Model
+	Action    string
+	Attempts  int
+	CreatedAt time.Time
+	LastError string
+	// Name is the name of the repository, including the host of the registry.
+	Name string
+	// NextRunAt is the earliest time the job gets processed.
+	// It is also used as the lease of a running job. The job is processed again if the lease expires.
+	NextRunAt time.Time
+	Reference string
+	Status    string
+	UpdatedAt time.Time
+}
+
+func (Job) TableName() string {
+	return "imagespy_job"
+}
+
 type Layer struct {
 	Model
 	Digest         string
+	MediaType      string
+	Size           int64
 	SourceImageIDs []int `gorm:"-"`
 }
 
@@ -70,16 +122,30 @@ type Platform struct {
 	Features       []*Feature `gorm:"many2many:imagespy_platform_features;"`
 	ImageID        int
 	ManifestDigest string
+	MediaType      string
 	OS             string
-	OSFeatures     []*OSFeature `gorm:"many2many:imagespy_platform_os_features;"`
+	OSFeatures     []*OSFeature `gorm:"many2many:imagespy_platform_os_features;association_jointable_foreignkey:osfeature_id;"`
 	OSVersion      string
-	Variant        string
+	// Size is the sum of the compressed sizes of all layers of the platform.
+	Size    int64
+	Variant string
 }
 
 func (Platform) TableName() string {
 	return "imagespy_platform"
 }
 
+// PlatformConfig holds the metadata read from the config of a platform.
+type PlatformConfig struct {
+	Model
+	Entrypoint   []string
+	Env          []string
+	ExposedPorts []string
+	Labels       map[string]string
+	PlatformID   int
+	User         string
+}
+
 type Tag struct {
 	Model
 	Distinction string
@@ -92,3 +158,30 @@ type Tag struct {
 func (Tag) TableName() string {
 	return "imagespy_tag"
 }
+
+const (
+	// TagEventActionSetLatest records that a tag has become the latest tag of its distinction.
+	TagEventActionSetLatest = "set_latest"
+	// TagEventActionTag records that a tag has been set on an image.
+	TagEventActionTag = "tag"
+	// TagEventActionUnsetLatest records that a tag is no longer the latest tag of its distinction.
+	TagEventActionUnsetLatest = "unset_latest"
+	// TagEventActionUntag records that a tag has been removed from an image, e.g. because it has been moved to another image.
+	TagEventActionUntag = "untag"
+)
+
+// TagEvent is an entry in the append-only log of changes of tags.
+// It copies the name and the digest of the image because the image can be deleted later on.
+type TagEvent struct {
+	Model
+	Action      string
+	CreatedAt   time.Time
+	Digest      string
+	Distinction string
+	ImageName   string
+	TagName     string
+}
+
+func (TagEvent) TableName() string {
+	return "imagespy_tag_event"
+}
@@ -2,6 +2,7 @@ package store
 
 import (
 	"errors"
+	"time"
 )
 
 var (
@@ -13,9 +14,11 @@ var (
 type Store interface {
 	Close() error
 	Images() ImageStore
+	Jobs() JobStore
 	Layers() LayerStore
 	LayerPositions() LayerPositionStore
 	Platforms() PlatformStore
+	TagEvents() TagEventStore
 	Tags() TagStore
 	Transaction() (StoreTransaction, error)
 }
@@ -29,9 +32,14 @@ type StoreTransaction interface {
 // ImageStore allows creating, manipulating and reading images.
 type ImageStore interface {
 	Create(i *Image) error
+	// Delete removes the image, its tags and its platforms including all data attached to them.
+	// Layers are kept because other images might share them.
+	Delete(i *Image) error
 	FindByLayerIDHavingLayerCountGreaterThan(layerID, count int) ([]*Image, error)
 	Get(o ImageGetOptions) (*Image, error)
 	List(o ImageListOptions) ([]*Image, error)
+	// ListRepositories groups the images by their name.
+	ListRepositories(o RepositoryListOptions) ([]*Repository, error)
 	Update(i *Image) error
 }
 
@@ -46,8 +54,70 @@ type ImageGetOptions struct {
 }
 
 type ImageListOptions struct {
-	Digest string
-	Name   string
+	// CreatedSince selects images created at or after this time. It is ignored if it is the zero time.
+	CreatedSince time.Time
+	// CreatedUntil selects images created before this time. It is ignored if it is the zero time.
+	CreatedUntil time.Time
+	Digest       string
+	// Digests selects images which digest is one of the digests. It is ignored if it is empty.
+	Digests []string
+	// IDs selects images which ID is one of the IDs. It is ignored if it is empty.
+	IDs []int
+	// Limit restricts the number of images returned. All images are returned if it is 0.
+	Limit int
+	Name  string
+	// Offset skips the given number of images. It is ignored if Limit is 0.
+	Offset int
+	// TagDistinction selects images that have at least one tag with this distinction.
+	TagDistinction string
+}
+
+const (
+	// RepositoryOrderName orders repositories by name in ascending order.
+	RepositoryOrderName = "name"
+	// RepositoryOrderScrapedAt orders repositories by the time of their last scrape, most recent first.
+	RepositoryOrderScrapedAt = "scraped_at"
+)
+
+type RepositoryListOptions struct {
+	// Limit restricts the number of repositories returned. All repositories are returned if it is 0.
+	Limit int
+	// NameContains selects repositories which name contains this string, ignoring case.
+	NameContains string
+	// NamePrefix selects repositories which name starts with this string, ignoring case.
+	NamePrefix string
+	// Offset skips the given number of repositories. It is ignored if Limit is 0.
+	Offset int
+	// OrderBy is either RepositoryOrderName or RepositoryOrderScrapedAt. Defaults to RepositoryOrderName.
+	OrderBy string
+}
+
+// JobStore persists the jobs of the queue.
+type JobStore interface {
+	// Claim marks the next job which NextRunAt has passed as running and sets its lease to leaseUntil.
+	// It returns ErrDoesNotExist if no job is due.
+	Claim(now time.Time, leaseUntil time.Time) (*Job, error)
+	// CountByStatus returns the number of jobs per status.
+	CountByStatus() (map[string]int, error)
+	// Create stores a new pending job.
+	// It does nothing if a pending job with the same action, name and reference exists.
+	Create(j *Job) error
+	// DeleteDone deletes all jobs with status done that have been updated before updatedBefore.
+	// It returns the number of deleted jobs.
+	DeleteDone(updatedBefore time.Time) (int, error)
+	Get(o JobGetOptions) (*Job, error)
+	List(o JobListOptions) ([]*Job, error)
+	Update(j *Job) error
+}
+
+type JobGetOptions struct {
+	ID int
+}
+
+type JobListOptions struct {
+	// Limit restricts the number of jobs returned. All jobs are returned if it is 0.
+	Limit  int
+	Status string
 }
 
 type LayerStore interface {
@@ -63,6 +133,8 @@ type LayerGetOptions struct {
 }
 
 type LayerListOptions struct {
+	// IDs selects layers which ID is one of the IDs. It is ignored if it is empty.
+	IDs        []int
 	PlatformID int
 }
 
@@ -78,10 +150,16 @@ type LayerPositionListOptions struct {
 
 type PlatformStore interface {
 	Create(*Platform) error
+	CreateConfig(*PlatformConfig) error
 	Get(o PlatformGetOptions) (*Platform, error)
+	GetConfig(o PlatformConfigGetOptions) (*PlatformConfig, error)
 	List(o PlatformListOptions) ([]*Platform, error)
 }
 
+type PlatformConfigGetOptions struct {
+	PlatformID int
+}
+
 type PlatformGetOptions struct {
 	Architecture string
 	ImageID      int
@@ -91,9 +169,32 @@ type PlatformGetOptions struct {
 }
 
 type PlatformListOptions struct {
+	ImageID int
+	// ImageIDs selects platforms of images which ID is one of the IDs. It is ignored if it is empty.
+	ImageIDs []int
+	// LabelName selects platforms which config has a label with this name.
+	LabelName string
+	// LabelValue selects platforms which config has a label with this value. Requires LabelName.
+	LabelValue  string
 	LayerDigest string
 }
 
+// TagEventStore appends to and reads the log of changes of tags. Events cannot be changed once they have been created.
+type TagEventStore interface {
+	Create(e *TagEvent) error
+	// List returns events ordered from newest to oldest.
+	List(o TagEventListOptions) ([]*TagEvent, error)
+}
+
+type TagEventListOptions struct {
+	ImageName string
+	// Limit restricts the number of events returned. All events are returned if it is 0.
+	Limit int
+	// Offset skips the given number of events. It is ignored if Limit is 0.
+	Offset  int
+	TagName string
+}
+
 type TagStore interface {
 	Create(*Tag) error
 	Get(o TagGetOptions) (*Tag, error)
@@ -110,7 +211,16 @@ type TagGetOptions struct {
 }
 
 type TagListOptions struct {
-	ImageID  int
-	IsLatest *bool
-	IsTagged *bool
+	Distinction string
+	ImageID     int
+	// ImageIDs selects tags of images which ID is one of the IDs. It is ignored if it is empty.
+	ImageIDs []int
+	// ImageName selects tags of all images with this name.
+	ImageName string
+	// ImageNames selects tags of all images which name is one of the names. It is ignored if it is empty.
+	ImageNames []string
+	IsLatest   *bool
+	IsTagged   *bool
+	// Names selects tags which name is one of the names. It is ignored if it is empty.
+	Names []string
 }
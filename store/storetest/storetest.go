@@ -0,0 +1,1011 @@
+// Package storetest contains a test suite that every implementation of store.Store has to pass.
+package storetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/imagespy/api/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Run executes the suite. newStore is called once per test and has to return an empty store.
+// The suite closes the store at the end of each test.
+func Run(t *testing.T, newStore func(t *testing.T) store.Store) {
+	tests := []struct {
+		name string
+		f    func(t *testing.T, s store.Store)
+	}{
+		{name: "ImageCreate", f: testImageCreate},
+		{name: "ImageDelete", f: testImageDelete},
+		{name: "ImageFindByLayerIDHavingLayerCountGreaterThan", f: testImageFindByLayerIDHavingLayerCountGreaterThan},
+		{name: "ImageGet", f: testImageGet},
+		{name: "ImageList", f: testImageList},
+		{name: "ImageListHistory", f: testImageListHistory},
+		{name: "ImageListRepositories", f: testImageListRepositories},
+		{name: "ImageUpdate", f: testImageUpdate},
+		{name: "JobClaim", f: testJobClaim},
+		{name: "JobCreate", f: testJobCreate},
+		{name: "JobDeleteDone", f: testJobDeleteDone},
+		{name: "LayerCreate", f: testLayerCreate},
+		{name: "LayerGet", f: testLayerGet},
+		{name: "LayerList", f: testLayerList},
+		{name: "LayerUpdate", f: testLayerUpdate},
+		{name: "LayerPositionCreate", f: testLayerPositionCreate},
+		{name: "LayerPositionList", f: testLayerPositionList},
+		{name: "PlatformConfig", f: testPlatformConfig},
+		{name: "PlatformCreate", f: testPlatformCreate},
+		{name: "PlatformGet", f: testPlatformGet},
+		{name: "PlatformList", f: testPlatformList},
+		{name: "TagEvent", f: testTagEvent},
+		{name: "TagCreate", f: testTagCreate},
+		{name: "TagGet", f: testTagGet},
+		{name: "TagList", f: testTagList},
+		{name: "TagUpdate", f: testTagUpdate},
+		{name: "TransactionCommit", f: testTransactionCommit},
+		{name: "TransactionRollback", f: testTransactionRollback},
+	}
+
+	for _, tc := range tests {
+		f := tc.f
+		t.Run(tc.name, func(t *testing.T) {
+			s := newStore(t)
+			defer s.Close()
+			f(t, s)
+		})
+	}
+}
+
+var baseTime = time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func createImage(t *testing.T, s store.Store, name, digest string, createdAt time.Time) *store.Image {
+	i := &store.Image{
+		CreatedAt:     createdAt,
+		Digest:        digest,
+		Name:          name,
+		SchemaVersion: 2,
+		ScrapedAt:     createdAt,
+	}
+	require.NoError(t, s.Images().Create(i))
+	return i
+}
+
+func createLayer(t *testing.T, s store.Store, digest string, sourceImageIDs ...int) *store.Layer {
+	l := &store.Layer{
+		Digest:         digest,
+		MediaType:      "application/vnd.docker.image.rootfs.diff.tar.gzip",
+		Size:           1024,
+		SourceImageIDs: sourceImageIDs,
+	}
+	require.NoError(t, s.Layers().Create(l))
+	return l
+}
+
+func createPlatform(t *testing.T, s store.Store, imageID int, arch, variant string, layers ...*store.Layer) *store.Platform {
+	p := &store.Platform{
+		Architecture:   arch,
+		Created:        baseTime,
+		CreatedAt:      baseTime,
+		ImageID:        imageID,
+		ManifestDigest: "sha256:manifest-" + arch + variant,
+		OS:             "linux",
+		Variant:        variant,
+	}
+	require.NoError(t, s.Platforms().Create(p))
+	for position, l := range layers {
+		require.NoError(t, s.LayerPositions().Create(&store.LayerPosition{LayerID: l.ID, PlatformID: p.ID, Position: position}))
+	}
+
+	return p
+}
+
+func createTag(t *testing.T, s store.Store, imageID int, name, distinction string, isLatest bool) *store.Tag {
+	tag := &store.Tag{
+		Distinction: distinction,
+		ImageID:     imageID,
+		IsLatest:    isLatest,
+		IsTagged:    true,
+		Name:        name,
+	}
+	require.NoError(t, s.Tags().Create(tag))
+	return tag
+}
+
+func imageIDs(images []*store.Image) []int {
+	ids := []int{}
+	for _, i := range images {
+		ids = append(ids, i.ID)
+	}
+
+	return ids
+}
+
+func layerIDs(layers []*store.Layer) []int {
+	ids := []int{}
+	for _, l := range layers {
+		ids = append(ids, l.ID)
+	}
+
+	return ids
+}
+
+func platformIDs(platforms []*store.Platform) []int {
+	ids := []int{}
+	for _, p := range platforms {
+		ids = append(ids, p.ID)
+	}
+
+	return ids
+}
+
+func tagIDs(tags []*store.Tag) []int {
+	ids := []int{}
+	for _, t := range tags {
+		ids = append(ids, t.ID)
+	}
+
+	return ids
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func testImageCreate(t *testing.T, s store.Store) {
+	i := createImage(t, s, "unit.test/create", "sha256:a", baseTime)
+	assert.NotZero(t, i.ID)
+
+	again := &store.Image{Digest: "sha256:a", Name: "unit.test/create", SchemaVersion: 2}
+	require.NoError(t, s.Images().Create(again))
+	assert.Equal(t, i.ID, again.ID, "creating an existing image returns the existing image")
+
+	assert.Error(t, s.Images().Create(i), "creating an image that has an ID fails")
+}
+
+func testImageDelete(t *testing.T, s store.Store) {
+	deleted := createImage(t, s, "unit.test/delete", "sha256:a", baseTime)
+	kept := createImage(t, s, "unit.test/delete", "sha256:b", baseTime.Add(time.Hour))
+	shared := createLayer(t, s, "sha256:shared", deleted.ID, kept.ID)
+	p := createPlatform(t, s, deleted.ID, "amd64", "", shared)
+	createPlatform(t, s, kept.ID, "amd64", "", shared)
+	require.NoError(t, s.Platforms().CreateConfig(&store.PlatformConfig{Labels: map[string]string{"a": "b"}, PlatformID: p.ID}))
+	createTag(t, s, deleted.ID, "1.0", "major", false)
+	keptTag := createTag(t, s, kept.ID, "2.0", "major", true)
+
+	require.NoError(t, s.Images().Delete(deleted))
+
+	_, err := s.Images().Get(store.ImageGetOptions{ID: deleted.ID})
+	assert.Equal(t, store.ErrDoesNotExist, err)
+	platforms, err := s.Platforms().List(store.PlatformListOptions{ImageID: deleted.ID})
+	require.NoError(t, err)
+	assert.Empty(t, platforms)
+	_, err = s.Platforms().GetConfig(store.PlatformConfigGetOptions{PlatformID: p.ID})
+	assert.Equal(t, store.ErrDoesNotExist, err)
+	positions, err := s.LayerPositions().List(store.LayerPositionListOptions{PlatformID: p.ID})
+	require.NoError(t, err)
+	assert.Empty(t, positions)
+	tags, err := s.Tags().List(store.TagListOptions{ImageName: "unit.test/delete"})
+	require.NoError(t, err)
+	assert.Equal(t, []int{keptTag.ID}, tagIDs(tags))
+
+	layer, err := s.Layers().Get(store.LayerGetOptions{ID: shared.ID})
+	require.NoError(t, err, "layers are kept")
+	assert.Equal(t, []int{kept.ID}, layer.SourceImageIDs)
+	_, err = s.Images().Get(store.ImageGetOptions{ID: kept.ID})
+	assert.NoError(t, err)
+}
+
+func testImageFindByLayerIDHavingLayerCountGreaterThan(t *testing.T, s store.Store) {
+	base := createImage(t, s, "unit.test/b-base", "sha256:a", baseTime)
+	child := createImage(t, s, "unit.test/a-child", "sha256:b", baseTime)
+	first := createLayer(t, s, "sha256:first")
+	second := createLayer(t, s, "sha256:second")
+	third := createLayer(t, s, "sha256:third")
+	createPlatform(t, s, base.ID, "amd64", "", first)
+	createPlatform(t, s, child.ID, "amd64", "", first, second, third)
+
+	images, err := s.Images().FindByLayerIDHavingLayerCountGreaterThan(first.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []int{child.ID}, imageIDs(images))
+
+	images, err = s.Images().FindByLayerIDHavingLayerCountGreaterThan(first.ID, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []int{child.ID, base.ID}, imageIDs(images), "images are ordered by name")
+
+	images, err = s.Images().FindByLayerIDHavingLayerCountGreaterThan(second.ID, 3)
+	require.NoError(t, err)
+	assert.Empty(t, images)
+}
+
+func testImageGet(t *testing.T, s store.Store) {
+	_, err := s.Images().Get(store.ImageGetOptions{})
+	assert.Error(t, err, "Digest, ID or Name is required")
+
+	newest := createImage(t, s, "unit.test/get", "sha256:newest", baseTime.Add(time.Hour))
+	oldest := createImage(t, s, "unit.test/get", "sha256:oldest", baseTime)
+	other := createImage(t, s, "unit.test/other", "sha256:other", baseTime)
+	createTag(t, s, oldest.ID, "1.0", "major", false)
+	createTag(t, s, oldest.ID, "1", "minor", true)
+	createTag(t, s, newest.ID, "2.0", "major", true)
+
+	testCases := []struct {
+		name       string
+		opts       store.ImageGetOptions
+		expectedID int
+	}{
+		{name: "Digest", opts: store.ImageGetOptions{Digest: "sha256:oldest"}, expectedID: oldest.ID},
+		{name: "ID", opts: store.ImageGetOptions{ID: other.ID}, expectedID: other.ID},
+		{name: "Name returns the newest image", opts: store.ImageGetOptions{Name: "unit.test/get"}, expectedID: newest.ID},
+		{name: "TagDistinction", opts: store.ImageGetOptions{Name: "unit.test/get", TagDistinction: "minor"}, expectedID: oldest.ID},
+		{name: "TagIsLatest true", opts: store.ImageGetOptions{Name: "unit.test/get", TagDistinction: "major", TagIsLatest: boolPtr(true)}, expectedID: newest.ID},
+		{name: "TagIsLatest false", opts: store.ImageGetOptions{Name: "unit.test/get", TagIsLatest: boolPtr(false)}, expectedID: oldest.ID},
+		{name: "TagName", opts: store.ImageGetOptions{Name: "unit.test/get", TagName: "1.0"}, expectedID: oldest.ID},
+	}
+	for _, tc := range testCases {
+		i, err := s.Images().Get(tc.opts)
+		if assert.NoError(t, err, tc.name) {
+			assert.Equal(t, tc.expectedID, i.ID, tc.name)
+		}
+	}
+
+	i, err := s.Images().Get(store.ImageGetOptions{ID: oldest.ID})
+	require.NoError(t, err)
+	assert.Equal(t, "sha256:oldest", i.Digest)
+	assert.Equal(t, "unit.test/get", i.Name)
+	assert.Equal(t, 2, i.SchemaVersion)
+	assert.True(t, baseTime.Equal(i.CreatedAt))
+	assert.True(t, baseTime.Equal(i.ScrapedAt))
+
+	_, err = s.Images().Get(store.ImageGetOptions{Name: "unit.test/get", TagName: "3.0"})
+	assert.Equal(t, store.ErrDoesNotExist, err)
+	_, err = s.Images().Get(store.ImageGetOptions{Name: "unit.test/unknown"})
+	assert.Equal(t, store.ErrDoesNotExist, err)
+}
+
+func testImageList(t *testing.T, s store.Store) {
+	first := createImage(t, s, "unit.test/list", "sha256:a", baseTime)
+	second := createImage(t, s, "unit.test/list", "sha256:b", baseTime)
+	other := createImage(t, s, "unit.test/other", "sha256:a", baseTime)
+
+	images, err := s.Images().List(store.ImageListOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []int{other.ID, second.ID, first.ID}, imageIDs(images), "images are ordered by ID descending")
+
+	images, err = s.Images().List(store.ImageListOptions{Name: "unit.test/list"})
+	require.NoError(t, err)
+	assert.Equal(t, []int{second.ID, first.ID}, imageIDs(images))
+
+	images, err = s.Images().List(store.ImageListOptions{Digest: "sha256:a"})
+	require.NoError(t, err)
+	assert.Equal(t, []int{other.ID, first.ID}, imageIDs(images))
+
+	images, err = s.Images().List(store.ImageListOptions{Digest: "sha256:a", Name: "unit.test/list"})
+	require.NoError(t, err)
+	assert.Equal(t, []int{first.ID}, imageIDs(images))
+
+	images, err = s.Images().List(store.ImageListOptions{Digests: []string{"sha256:b", "sha256:unknown"}})
+	require.NoError(t, err)
+	assert.Equal(t, []int{second.ID}, imageIDs(images))
+
+	images, err = s.Images().List(store.ImageListOptions{Digests: []string{"sha256:a", "sha256:b"}, Name: "unit.test/other"})
+	require.NoError(t, err)
+	assert.Equal(t, []int{other.ID}, imageIDs(images))
+
+	images, err = s.Images().List(store.ImageListOptions{IDs: []int{first.ID, other.ID}})
+	require.NoError(t, err)
+	assert.Equal(t, []int{other.ID, first.ID}, imageIDs(images))
+
+	images, err = s.Images().List(store.ImageListOptions{IDs: []int{first.ID, other.ID}, Name: "unit.test/list"})
+	require.NoError(t, err)
+	assert.Equal(t, []int{first.ID}, imageIDs(images))
+
+	images, err = s.Images().List(store.ImageListOptions{Name: "unit.test/unknown"})
+	require.NoError(t, err)
+	assert.Empty(t, images)
+}
+
+func testImageListHistory(t *testing.T, s store.Store) {
+	first := createImage(t, s, "unit.test/history", "sha256:a", baseTime)
+	second := createImage(t, s, "unit.test/history", "sha256:b", baseTime.Add(time.Hour))
+	third := createImage(t, s, "unit.test/history", "sha256:c", baseTime.Add(2*time.Hour))
+	createImage(t, s, "unit.test/other", "sha256:d", baseTime.Add(time.Hour))
+	createTag(t, s, first.ID, "1.0", "major", false)
+	createTag(t, s, first.ID, "1.0.0", "minor", false)
+	createTag(t, s, third.ID, "1.1", "major", true)
+
+	testCases := []struct {
+		name        string
+		opts        store.ImageListOptions
+		expectedIDs []int
+	}{
+		{name: "CreatedSince", opts: store.ImageListOptions{CreatedSince: baseTime.Add(time.Hour), Name: "unit.test/history"}, expectedIDs: []int{third.ID, second.ID}},
+		{name: "CreatedUntil", opts: store.ImageListOptions{CreatedUntil: baseTime.Add(time.Hour), Name: "unit.test/history"}, expectedIDs: []int{first.ID}},
+		{name: "CreatedSince and CreatedUntil", opts: store.ImageListOptions{CreatedSince: baseTime, CreatedUntil: baseTime.Add(2 * time.Hour), Name: "unit.test/history"}, expectedIDs: []int{second.ID, first.ID}},
+		{name: "TagDistinction", opts: store.ImageListOptions{Name: "unit.test/history", TagDistinction: "major"}, expectedIDs: []int{third.ID, first.ID}},
+		{name: "Limit", opts: store.ImageListOptions{Limit: 2, Name: "unit.test/history"}, expectedIDs: []int{third.ID, second.ID}},
+		{name: "Limit and Offset", opts: store.ImageListOptions{Limit: 2, Name: "unit.test/history", Offset: 2}, expectedIDs: []int{first.ID}},
+		{name: "Offset beyond the end", opts: store.ImageListOptions{Limit: 2, Name: "unit.test/history", Offset: 3}, expectedIDs: []int{}},
+		{name: "Offset without Limit", opts: store.ImageListOptions{Name: "unit.test/history", Offset: 2}, expectedIDs: []int{third.ID, second.ID, first.ID}},
+	}
+	for _, tc := range testCases {
+		images, err := s.Images().List(tc.opts)
+		if assert.NoError(t, err, tc.name) {
+			assert.Equal(t, tc.expectedIDs, imageIDs(images), tc.name)
+		}
+	}
+}
+
+func testImageListRepositories(t *testing.T, s store.Store) {
+	app := createImage(t, s, "unit.test/team/app", "sha256:a", baseTime)
+	app.ScrapedAt = baseTime.Add(3 * time.Hour)
+	require.NoError(t, s.Images().Update(app))
+	appOld := createImage(t, s, "unit.test/team/app", "sha256:b", baseTime)
+	createTag(t, s, app.ID, "1.1", "minor", true)
+	createTag(t, s, app.ID, "latest", "", true)
+	createTag(t, s, appOld.ID, "1.0", "minor", false)
+	createTag(t, s, appOld.ID, "latest", "", false)
+	require.NoError(t, s.Tags().Create(&store.Tag{Distinction: "minor", ImageID: appOld.ID, Name: "0.9"}))
+	db := createImage(t, s, "unit.test/team/db", "sha256:c", baseTime.Add(time.Hour))
+	createImage(t, s, "unit.test/tools_x", "sha256:d", baseTime.Add(2*time.Hour))
+	createImage(t, s, "other.test/app", "sha256:e", baseTime)
+
+	repositories, err := s.Images().ListRepositories(store.RepositoryListOptions{})
+	require.NoError(t, err)
+	names := []string{}
+	for _, r := range repositories {
+		names = append(names, r.Name)
+	}
+
+	assert.Equal(t, []string{"other.test/app", "unit.test/team/app", "unit.test/team/db", "unit.test/tools_x"}, names, "repositories are ordered by name")
+	app2 := repositories[1]
+	assert.Equal(t, 2, app2.ImageCount)
+	assert.Equal(t, 3, app2.TagCount, "untagged tags are not counted and tags with the same name are counted once")
+	assert.True(t, baseTime.Add(3*time.Hour).Equal(app2.ScrapedAt), "ScrapedAt is the time of the most recent scrape")
+	assert.Equal(t, 1, repositories[2].ImageCount)
+	assert.Equal(t, 0, repositories[2].TagCount)
+	assert.True(t, db.ScrapedAt.Equal(repositories[2].ScrapedAt))
+
+	testCases := []struct {
+		name     string
+		opts     store.RepositoryListOptions
+		expected []string
+	}{
+		{name: "NamePrefix", opts: store.RepositoryListOptions{NamePrefix: "unit.test/team/"}, expected: []string{"unit.test/team/app", "unit.test/team/db"}},
+		{name: "NameContains", opts: store.RepositoryListOptions{NameContains: "app"}, expected: []string{"other.test/app", "unit.test/team/app"}},
+		{name: "NamePrefix ignores case", opts: store.RepositoryListOptions{NamePrefix: "Unit.Test/TEAM/"}, expected: []string{"unit.test/team/app", "unit.test/team/db"}},
+		{name: "NameContains ignores case", opts: store.RepositoryListOptions{NameContains: "APP"}, expected: []string{"other.test/app", "unit.test/team/app"}},
+		{name: "NameContains escapes wildcards", opts: store.RepositoryListOptions{NameContains: "s_x"}, expected: []string{"unit.test/tools_x"}},
+		{name: "NameContains matches wildcards literally", opts: store.RepositoryListOptions{NameContains: "m%"}, expected: []string{}},
+		{name: "NamePrefix and NameContains", opts: store.RepositoryListOptions{NameContains: "app", NamePrefix: "unit.test/"}, expected: []string{"unit.test/team/app"}},
+		{name: "OrderBy ScrapedAt", opts: store.RepositoryListOptions{OrderBy: store.RepositoryOrderScrapedAt}, expected: []string{"unit.test/team/app", "unit.test/tools_x", "unit.test/team/db", "other.test/app"}},
+		{name: "Limit", opts: store.RepositoryListOptions{Limit: 2}, expected: []string{"other.test/app", "unit.test/team/app"}},
+		{name: "Limit and Offset", opts: store.RepositoryListOptions{Limit: 2, Offset: 3}, expected: []string{"unit.test/tools_x"}},
+	}
+	for _, tc := range testCases {
+		repositories, err := s.Images().ListRepositories(tc.opts)
+		if assert.NoError(t, err, tc.name) {
+			names := []string{}
+			for _, r := range repositories {
+				names = append(names, r.Name)
+			}
+
+			assert.Equal(t, tc.expected, names, tc.name)
+		}
+	}
+}
+
+func testImageUpdate(t *testing.T, s store.Store) {
+	i := createImage(t, s, "unit.test/update", "sha256:a", baseTime)
+	i.ScrapedAt = baseTime.Add(time.Hour)
+	require.NoError(t, s.Images().Update(i))
+
+	updated, err := s.Images().Get(store.ImageGetOptions{ID: i.ID})
+	require.NoError(t, err)
+	assert.True(t, baseTime.Add(time.Hour).Equal(updated.ScrapedAt))
+}
+
+func testJobClaim(t *testing.T, s store.Store) {
+	_, err := s.Jobs().Claim(baseTime, baseTime.Add(time.Minute))
+	assert.Equal(t, store.ErrDoesNotExist, err)
+
+	later := &store.Job{Action: store.JobActionScrape, Name: "unit.test/claim", NextRunAt: baseTime.Add(-time.Second), Reference: "2.0"}
+	require.NoError(t, s.Jobs().Create(later))
+	sooner := &store.Job{Action: store.JobActionScrape, Name: "unit.test/claim", NextRunAt: baseTime.Add(-time.Minute), Reference: "1.0"}
+	require.NoError(t, s.Jobs().Create(sooner))
+	future := &store.Job{Action: store.JobActionScrape, Name: "unit.test/claim", NextRunAt: baseTime.Add(time.Hour), Reference: "3.0"}
+	require.NoError(t, s.Jobs().Create(future))
+
+	j, err := s.Jobs().Claim(baseTime, baseTime.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, sooner.ID, j.ID, "the job that is due first is claimed first")
+	assert.Equal(t, 1, j.Attempts)
+	assert.Equal(t, store.JobStatusRunning, j.Status)
+	assert.True(t, baseTime.Add(time.Minute).Equal(j.NextRunAt))
+
+	j, err = s.Jobs().Claim(baseTime, baseTime.Add(2*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, later.ID, j.ID)
+
+	_, err = s.Jobs().Claim(baseTime, baseTime.Add(time.Minute))
+	assert.Equal(t, store.ErrDoesNotExist, err, "running jobs are not claimed before their lease expired")
+
+	j, err = s.Jobs().Claim(baseTime.Add(90*time.Second), baseTime.Add(3*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, sooner.ID, j.ID, "running jobs are claimed again after their lease expired")
+	assert.Equal(t, 2, j.Attempts)
+}
+
+func testJobCreate(t *testing.T, s store.Store) {
+	j := &store.Job{Action: store.JobActionScrape, Name: "unit.test/create", NextRunAt: baseTime, Reference: "1.0"}
+	require.NoError(t, s.Jobs().Create(j))
+	assert.NotZero(t, j.ID)
+	assert.Equal(t, store.JobStatusPending, j.Status)
+
+	duplicate := &store.Job{Action: store.JobActionScrape, Name: "unit.test/create", NextRunAt: baseTime, Reference: "1.0"}
+	require.NoError(t, s.Jobs().Create(duplicate))
+	assert.Equal(t, j.ID, duplicate.ID, "a pending job with the same action, name and reference is not created twice")
+
+	j.Status = store.JobStatusDone
+	require.NoError(t, s.Jobs().Update(j))
+	next := &store.Job{Action: store.JobActionScrape, Name: "unit.test/create", NextRunAt: baseTime, Reference: "1.0"}
+	require.NoError(t, s.Jobs().Create(next))
+	assert.NotEqual(t, j.ID, next.ID)
+
+	stored, err := s.Jobs().Get(store.JobGetOptions{ID: j.ID})
+	require.NoError(t, err)
+	assert.Equal(t, store.JobStatusDone, stored.Status)
+	_, err = s.Jobs().Get(store.JobGetOptions{ID: next.ID + 1})
+	assert.Equal(t, store.ErrDoesNotExist, err)
+
+	jobs, err := s.Jobs().List(store.JobListOptions{Status: store.JobStatusPending})
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, next.ID, jobs[0].ID)
+	jobs, err = s.Jobs().List(store.JobListOptions{Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, next.ID, jobs[0].ID, "jobs are ordered by ID descending")
+
+	counts, err := s.Jobs().CountByStatus()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{store.JobStatusDone: 1, store.JobStatusPending: 1}, counts)
+}
+
+func testJobDeleteDone(t *testing.T, s store.Store) {
+	done := &store.Job{Action: store.JobActionScrape, Name: "unit.test/delete", NextRunAt: baseTime, Reference: "1.0"}
+	require.NoError(t, s.Jobs().Create(done))
+	done.Status = store.JobStatusDone
+	require.NoError(t, s.Jobs().Update(done))
+	dead := &store.Job{Action: store.JobActionScrape, Name: "unit.test/delete", NextRunAt: baseTime, Reference: "2.0"}
+	require.NoError(t, s.Jobs().Create(dead))
+	dead.Status = store.JobStatusDead
+	require.NoError(t, s.Jobs().Update(dead))
+	pending := &store.Job{Action: store.JobActionScrape, Name: "unit.test/delete", NextRunAt: baseTime, Reference: "3.0"}
+	require.NoError(t, s.Jobs().Create(pending))
+
+	deleted, err := s.Jobs().DeleteDone(time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 0, deleted, "jobs updated after the cutoff are kept")
+
+	deleted, err = s.Jobs().DeleteDone(time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	_, err = s.Jobs().Get(store.JobGetOptions{ID: done.ID})
+	assert.Equal(t, store.ErrDoesNotExist, err)
+	_, err = s.Jobs().Get(store.JobGetOptions{ID: dead.ID})
+	assert.NoError(t, err, "dead jobs are kept")
+	_, err = s.Jobs().Get(store.JobGetOptions{ID: pending.ID})
+	assert.NoError(t, err, "pending jobs are kept")
+}
+
+func testLayerCreate(t *testing.T, s store.Store) {
+	i := createImage(t, s, "unit.test/layer", "sha256:a", baseTime)
+	l := createLayer(t, s, "sha256:layer", i.ID)
+	assert.NotZero(t, l.ID)
+
+	again := &store.Layer{Digest: "sha256:layer", MediaType: "application/vnd.oci.image.layer.v1.tar+gzip", Size: 2048, SourceImageIDs: []int{i.ID}}
+	require.NoError(t, s.Layers().Create(again))
+	assert.Equal(t, l.ID, again.ID, "creating an existing layer returns the existing layer")
+
+	stored, err := s.Layers().Get(store.LayerGetOptions{ID: l.ID})
+	require.NoError(t, err)
+	assert.Equal(t, "application/vnd.oci.image.layer.v1.tar+gzip", stored.MediaType, "creating an existing layer updates the media type")
+	assert.Equal(t, int64(2048), stored.Size, "creating an existing layer updates the size")
+	assert.Equal(t, []int{i.ID}, stored.SourceImageIDs, "source images are not added twice")
+}
+
+func testLayerGet(t *testing.T, s store.Store) {
+	first := createImage(t, s, "unit.test/layer", "sha256:a", baseTime)
+	second := createImage(t, s, "unit.test/layer", "sha256:b", baseTime)
+	l := createLayer(t, s, "sha256:layer", first.ID, second.ID)
+	createLayer(t, s, "sha256:other")
+
+	byDigest, err := s.Layers().Get(store.LayerGetOptions{Digest: "sha256:layer"})
+	require.NoError(t, err)
+	assert.Equal(t, l.ID, byDigest.ID)
+	assert.Equal(t, "application/vnd.docker.image.rootfs.diff.tar.gzip", byDigest.MediaType)
+	assert.Equal(t, int64(1024), byDigest.Size)
+	assert.ElementsMatch(t, []int{first.ID, second.ID}, byDigest.SourceImageIDs)
+
+	byID, err := s.Layers().Get(store.LayerGetOptions{ID: l.ID})
+	require.NoError(t, err)
+	assert.Equal(t, "sha256:layer", byID.Digest)
+
+	_, err = s.Layers().Get(store.LayerGetOptions{Digest: "sha256:unknown"})
+	assert.Equal(t, store.ErrDoesNotExist, err)
+}
+
+func testLayerList(t *testing.T, s store.Store) {
+	i := createImage(t, s, "unit.test/layer", "sha256:a", baseTime)
+	first := createLayer(t, s, "sha256:first", i.ID)
+	second := createLayer(t, s, "sha256:second")
+	third := createLayer(t, s, "sha256:third")
+	p := createPlatform(t, s, i.ID, "amd64", "", first, second)
+
+	layers, err := s.Layers().List(store.LayerListOptions{})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []int{first.ID, second.ID, third.ID}, layerIDs(layers))
+
+	layers, err = s.Layers().List(store.LayerListOptions{PlatformID: p.ID})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []int{first.ID, second.ID}, layerIDs(layers))
+	for _, l := range layers {
+		if l.ID == first.ID {
+			assert.Equal(t, []int{i.ID}, l.SourceImageIDs)
+		}
+	}
+
+	other := createImage(t, s, "unit.test/layer", "sha256:b", baseTime)
+	third.SourceImageIDs = []int{other.ID, i.ID}
+	require.NoError(t, s.Layers().Update(third))
+	layers, err = s.Layers().List(store.LayerListOptions{IDs: []int{first.ID, third.ID}})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []int{first.ID, third.ID}, layerIDs(layers))
+	for _, l := range layers {
+		switch l.ID {
+		case first.ID:
+			assert.Equal(t, []int{i.ID}, l.SourceImageIDs)
+		case third.ID:
+			assert.Equal(t, []int{other.ID, i.ID}, l.SourceImageIDs, "source images keep the order in which they have been added")
+		}
+	}
+
+	layers, err = s.Layers().List(store.LayerListOptions{IDs: []int{first.ID, third.ID}, PlatformID: p.ID})
+	require.NoError(t, err)
+	assert.Equal(t, []int{first.ID}, layerIDs(layers))
+}
+
+func testLayerUpdate(t *testing.T, s store.Store) {
+	first := createImage(t, s, "unit.test/layer", "sha256:a", baseTime)
+	second := createImage(t, s, "unit.test/layer", "sha256:b", baseTime)
+	l := createLayer(t, s, "sha256:layer", first.ID)
+
+	l.SourceImageIDs = []int{second.ID}
+	require.NoError(t, s.Layers().Update(l))
+	stored, err := s.Layers().Get(store.LayerGetOptions{ID: l.ID})
+	require.NoError(t, err)
+	assert.Equal(t, []int{second.ID}, stored.SourceImageIDs, "Update replaces the source images")
+
+	l.SourceImageIDs = nil
+	require.NoError(t, s.Layers().Update(l))
+	stored, err = s.Layers().Get(store.LayerGetOptions{ID: l.ID})
+	require.NoError(t, err)
+	assert.Empty(t, stored.SourceImageIDs)
+}
+
+func testLayerPositionCreate(t *testing.T, s store.Store) {
+	i := createImage(t, s, "unit.test/position", "sha256:a", baseTime)
+	l := createLayer(t, s, "sha256:layer")
+	p := createPlatform(t, s, i.ID, "amd64", "")
+
+	lp := &store.LayerPosition{LayerID: l.ID, PlatformID: p.ID, Position: 0}
+	require.NoError(t, s.LayerPositions().Create(lp))
+	assert.NotZero(t, lp.ID)
+
+	again := &store.LayerPosition{LayerID: l.ID, PlatformID: p.ID, Position: 0}
+	require.NoError(t, s.LayerPositions().Create(again))
+	assert.Equal(t, lp.ID, again.ID, "creating an existing layer position returns the existing layer position")
+}
+
+func testLayerPositionList(t *testing.T, s store.Store) {
+	i := createImage(t, s, "unit.test/position", "sha256:a", baseTime)
+	first := createLayer(t, s, "sha256:first")
+	second := createLayer(t, s, "sha256:second")
+	p := &store.Platform{Architecture: "amd64", CreatedAt: baseTime, ImageID: i.ID, ManifestDigest: "sha256:p", OS: "linux"}
+	require.NoError(t, s.Platforms().Create(p))
+	other := createPlatform(t, s, i.ID, "arm", "v7", first)
+	// Created in reverse order to verify the ordering by position.
+	require.NoError(t, s.LayerPositions().Create(&store.LayerPosition{LayerID: second.ID, PlatformID: p.ID, Position: 1}))
+	require.NoError(t, s.LayerPositions().Create(&store.LayerPosition{LayerID: first.ID, PlatformID: p.ID, Position: 0}))
+
+	positions, err := s.LayerPositions().List(store.LayerPositionListOptions{PlatformID: p.ID})
+	require.NoError(t, err)
+	require.Len(t, positions, 2)
+	assert.Equal(t, first.ID, positions[0].LayerID)
+	assert.Equal(t, 0, positions[0].Position)
+	assert.Equal(t, second.ID, positions[1].LayerID)
+	assert.Equal(t, 1, positions[1].Position)
+
+	positions, err = s.LayerPositions().List(store.LayerPositionListOptions{LayerID: first.ID})
+	require.NoError(t, err)
+	assert.Len(t, positions, 2)
+
+	positions, err = s.LayerPositions().List(store.LayerPositionListOptions{LayerID: first.ID, PlatformID: other.ID})
+	require.NoError(t, err)
+	require.Len(t, positions, 1)
+	assert.Equal(t, other.ID, positions[0].PlatformID)
+}
+
+func testPlatformConfig(t *testing.T, s store.Store) {
+	i := createImage(t, s, "unit.test/config", "sha256:a", baseTime)
+	p := createPlatform(t, s, i.ID, "amd64", "")
+	withoutLabels := createPlatform(t, s, i.ID, "arm", "v7")
+
+	_, err := s.Platforms().GetConfig(store.PlatformConfigGetOptions{})
+	assert.Error(t, err, "PlatformID is required")
+	_, err = s.Platforms().GetConfig(store.PlatformConfigGetOptions{PlatformID: p.ID})
+	assert.Equal(t, store.ErrDoesNotExist, err)
+
+	c := &store.PlatformConfig{
+		Entrypoint:   []string{"/docker-entrypoint.sh"},
+		Env:          []string{"PATH=/usr/bin"},
+		ExposedPorts: []string{"80/tcp"},
+		Labels:       map[string]string{"maintainer": "unit test"},
+		PlatformID:   p.ID,
+		User:         "nobody",
+	}
+	require.NoError(t, s.Platforms().CreateConfig(c))
+	assert.NotZero(t, c.ID)
+	assert.Error(t, s.Platforms().CreateConfig(c), "creating a config that has an ID fails")
+
+	stored, err := s.Platforms().GetConfig(store.PlatformConfigGetOptions{PlatformID: p.ID})
+	require.NoError(t, err)
+	assert.Equal(t, c, stored)
+
+	require.NoError(t, s.Platforms().CreateConfig(&store.PlatformConfig{PlatformID: withoutLabels.ID}))
+	stored, err = s.Platforms().GetConfig(store.PlatformConfigGetOptions{PlatformID: withoutLabels.ID})
+	require.NoError(t, err)
+	assert.Nil(t, stored.Labels)
+	assert.Empty(t, stored.Entrypoint)
+}
+
+func testPlatformCreate(t *testing.T, s store.Store) {
+	i := createImage(t, s, "unit.test/platform", "sha256:a", baseTime)
+	p := &store.Platform{
+		Architecture:   "amd64",
+		Created:        baseTime,
+		CreatedAt:      baseTime,
+		Features:       []*store.Feature{{CreatedAt: baseTime, Name: "sse4"}},
+		ImageID:        i.ID,
+		ManifestDigest: "sha256:p",
+		MediaType:      "application/vnd.docker.distribution.manifest.v2+json",
+		OS:             "windows",
+		OSFeatures:     []*store.OSFeature{{CreatedAt: baseTime, Name: "win32k"}},
+		OSVersion:      "10.0.14393.1066",
+		Size:           4096,
+	}
+	require.NoError(t, s.Platforms().Create(p))
+	assert.NotZero(t, p.ID)
+
+	platforms, err := s.Platforms().List(store.PlatformListOptions{ImageID: i.ID})
+	require.NoError(t, err)
+	require.Len(t, platforms, 1)
+	stored := platforms[0]
+	assert.Equal(t, "amd64", stored.Architecture)
+	assert.True(t, baseTime.Equal(stored.Created))
+	assert.Equal(t, "sha256:p", stored.ManifestDigest)
+	assert.Equal(t, "application/vnd.docker.distribution.manifest.v2+json", stored.MediaType)
+	assert.Equal(t, "windows", stored.OS)
+	assert.Equal(t, "10.0.14393.1066", stored.OSVersion)
+	assert.Equal(t, int64(4096), stored.Size)
+	require.Len(t, stored.Features, 1)
+	assert.Equal(t, "sse4", stored.Features[0].Name)
+	require.Len(t, stored.OSFeatures, 1)
+	assert.Equal(t, "win32k", stored.OSFeatures[0].Name)
+
+	second := &store.Platform{
+		Architecture:   "arm64",
+		CreatedAt:      baseTime,
+		Features:       []*store.Feature{{CreatedAt: baseTime, Name: "aes"}, {CreatedAt: baseTime, Name: "sha2"}},
+		ImageID:        i.ID,
+		ManifestDigest: "sha256:second",
+		OS:             "linux",
+	}
+	require.NoError(t, s.Platforms().Create(second))
+	platforms, err = s.Platforms().List(store.PlatformListOptions{ImageID: i.ID})
+	require.NoError(t, err)
+	require.Len(t, platforms, 2)
+	for _, p := range platforms {
+		features := []string{}
+		for _, f := range p.Features {
+			features = append(features, f.Name)
+		}
+
+		osFeatures := []string{}
+		for _, f := range p.OSFeatures {
+			osFeatures = append(osFeatures, f.Name)
+		}
+
+		switch p.ID {
+		case stored.ID:
+			assert.Equal(t, []string{"sse4"}, features)
+			assert.Equal(t, []string{"win32k"}, osFeatures)
+		case second.ID:
+			assert.Equal(t, []string{"aes", "sha2"}, features, "features are assigned to the platform that has them")
+			assert.Empty(t, osFeatures)
+		}
+	}
+}
+
+func testPlatformGet(t *testing.T, s store.Store) {
+	i := createImage(t, s, "unit.test/platform", "sha256:a", baseTime)
+	other := createImage(t, s, "unit.test/platform", "sha256:b", baseTime)
+	amd64 := createPlatform(t, s, i.ID, "amd64", "")
+	armv7 := createPlatform(t, s, i.ID, "arm", "v7")
+	armv6 := createPlatform(t, s, i.ID, "arm", "v6")
+	windows := &store.Platform{Architecture: "amd64", CreatedAt: baseTime, ImageID: other.ID, ManifestDigest: "sha256:windows", OS: "windows", OSVersion: "10.0"}
+	require.NoError(t, s.Platforms().Create(windows))
+
+	testCases := []struct {
+		name       string
+		opts       store.PlatformGetOptions
+		expectedID int
+	}{
+		{name: "Architecture", opts: store.PlatformGetOptions{Architecture: "amd64", ImageID: i.ID}, expectedID: amd64.ID},
+		{name: "ImageID", opts: store.PlatformGetOptions{ImageID: other.ID}, expectedID: windows.ID},
+		{name: "OS", opts: store.PlatformGetOptions{OS: "windows"}, expectedID: windows.ID},
+		{name: "OSVersion", opts: store.PlatformGetOptions{Architecture: "amd64", OSVersion: stringPtr("10.0")}, expectedID: windows.ID},
+		{name: "empty OSVersion", opts: store.PlatformGetOptions{Architecture: "amd64", OSVersion: stringPtr("")}, expectedID: amd64.ID},
+		{name: "Variant", opts: store.PlatformGetOptions{Architecture: "arm", Variant: stringPtr("v6")}, expectedID: armv6.ID},
+		{name: "all", opts: store.PlatformGetOptions{Architecture: "arm", ImageID: i.ID, OS: "linux", OSVersion: stringPtr(""), Variant: stringPtr("v7")}, expectedID: armv7.ID},
+	}
+	for _, tc := range testCases {
+		p, err := s.Platforms().Get(tc.opts)
+		if assert.NoError(t, err, tc.name) {
+			assert.Equal(t, tc.expectedID, p.ID, tc.name)
+		}
+	}
+
+	_, err := s.Platforms().Get(store.PlatformGetOptions{Architecture: "arm", Variant: stringPtr("v8")})
+	assert.Equal(t, store.ErrDoesNotExist, err)
+}
+
+func testPlatformList(t *testing.T, s store.Store) {
+	i := createImage(t, s, "unit.test/platform", "sha256:a", baseTime)
+	other := createImage(t, s, "unit.test/platform", "sha256:b", baseTime)
+	l := createLayer(t, s, "sha256:layer")
+	amd64 := createPlatform(t, s, i.ID, "amd64", "", l)
+	arm := createPlatform(t, s, i.ID, "arm", "v7")
+	otherAMD64 := createPlatform(t, s, other.ID, "amd64", "", l)
+	require.NoError(t, s.Platforms().CreateConfig(&store.PlatformConfig{Labels: map[string]string{"team": "a"}, PlatformID: amd64.ID}))
+	require.NoError(t, s.Platforms().CreateConfig(&store.PlatformConfig{Labels: map[string]string{"team": "b"}, PlatformID: arm.ID}))
+
+	testCases := []struct {
+		name        string
+		opts        store.PlatformListOptions
+		expectedIDs []int
+	}{
+		{name: "none", opts: store.PlatformListOptions{}, expectedIDs: []int{amd64.ID, arm.ID, otherAMD64.ID}},
+		{name: "ImageID", opts: store.PlatformListOptions{ImageID: i.ID}, expectedIDs: []int{amd64.ID, arm.ID}},
+		{name: "ImageIDs", opts: store.PlatformListOptions{ImageIDs: []int{i.ID, other.ID}}, expectedIDs: []int{amd64.ID, arm.ID, otherAMD64.ID}},
+		{name: "ImageIDs and LabelName", opts: store.PlatformListOptions{ImageIDs: []int{other.ID}, LabelName: "team"}, expectedIDs: []int{}},
+		{name: "LabelName", opts: store.PlatformListOptions{LabelName: "team"}, expectedIDs: []int{amd64.ID, arm.ID}},
+		{name: "LabelValue", opts: store.PlatformListOptions{LabelName: "team", LabelValue: "b"}, expectedIDs: []int{arm.ID}},
+		{name: "LayerDigest", opts: store.PlatformListOptions{LayerDigest: "sha256:layer"}, expectedIDs: []int{amd64.ID, otherAMD64.ID}},
+		{name: "ImageID and LayerDigest", opts: store.PlatformListOptions{ImageID: other.ID, LayerDigest: "sha256:layer"}, expectedIDs: []int{otherAMD64.ID}},
+		{name: "unknown label", opts: store.PlatformListOptions{LabelName: "unknown"}, expectedIDs: []int{}},
+	}
+	for _, tc := range testCases {
+		platforms, err := s.Platforms().List(tc.opts)
+		if assert.NoError(t, err, tc.name) {
+			assert.ElementsMatch(t, tc.expectedIDs, platformIDs(platforms), tc.name)
+		}
+	}
+}
+
+func testTagEvent(t *testing.T, s store.Store) {
+	tagged := &store.TagEvent{Action: store.TagEventActionTag, CreatedAt: baseTime, Digest: "sha256:a", Distinction: "major", ImageName: "unit.test/event", TagName: "1"}
+	require.NoError(t, s.TagEvents().Create(tagged))
+	assert.NotZero(t, tagged.ID)
+	assert.Error(t, s.TagEvents().Create(tagged), "events cannot be created twice")
+	untagged := &store.TagEvent{Action: store.TagEventActionUntag, CreatedAt: baseTime.Add(time.Hour), Digest: "sha256:a", Distinction: "major", ImageName: "unit.test/event", TagName: "1"}
+	require.NoError(t, s.TagEvents().Create(untagged))
+	moved := &store.TagEvent{Action: store.TagEventActionTag, CreatedAt: baseTime.Add(time.Hour), Digest: "sha256:b", Distinction: "major", ImageName: "unit.test/event", TagName: "1"}
+	require.NoError(t, s.TagEvents().Create(moved))
+	otherTag := &store.TagEvent{Action: store.TagEventActionTag, CreatedAt: baseTime, Digest: "sha256:b", Distinction: "major", ImageName: "unit.test/event", TagName: "2"}
+	require.NoError(t, s.TagEvents().Create(otherTag))
+	otherImage := &store.TagEvent{Action: store.TagEventActionTag, CreatedAt: baseTime, Digest: "sha256:c", Distinction: "major", ImageName: "unit.test/other", TagName: "1"}
+	require.NoError(t, s.TagEvents().Create(otherImage))
+
+	events, err := s.TagEvents().List(store.TagEventListOptions{ImageName: "unit.test/event", TagName: "1"})
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, []int{moved.ID, untagged.ID, tagged.ID}, []int{events[0].ID, events[1].ID, events[2].ID}, "events are ordered from newest to oldest")
+	assert.Equal(t, store.TagEventActionUntag, events[1].Action)
+	assert.True(t, baseTime.Add(time.Hour).Equal(events[1].CreatedAt))
+	assert.Equal(t, "sha256:a", events[1].Digest)
+	assert.Equal(t, "major", events[1].Distinction)
+	assert.Equal(t, "unit.test/event", events[1].ImageName)
+	assert.Equal(t, "1", events[1].TagName)
+
+	events, err = s.TagEvents().List(store.TagEventListOptions{ImageName: "unit.test/event"})
+	require.NoError(t, err)
+	assert.Len(t, events, 4)
+
+	events, err = s.TagEvents().List(store.TagEventListOptions{TagName: "1"})
+	require.NoError(t, err)
+	assert.Len(t, events, 4)
+
+	events, err = s.TagEvents().List(store.TagEventListOptions{ImageName: "unit.test/event", Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, otherTag.ID, events[0].ID)
+	assert.Equal(t, moved.ID, events[1].ID)
+
+	events, err = s.TagEvents().List(store.TagEventListOptions{ImageName: "unit.test/event", Limit: 2, Offset: 2})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, untagged.ID, events[0].ID)
+	assert.Equal(t, tagged.ID, events[1].ID)
+
+	events, err = s.TagEvents().List(store.TagEventListOptions{ImageName: "unit.test/event", Limit: 2, Offset: 4})
+	require.NoError(t, err)
+	assert.Empty(t, events, "offset beyond the end")
+}
+
+func testTagCreate(t *testing.T, s store.Store) {
+	i := createImage(t, s, "unit.test/tag", "sha256:a", baseTime)
+	tag := createTag(t, s, i.ID, "1.0", "major", true)
+	assert.NotZero(t, tag.ID)
+
+	assert.Error(t, s.Tags().Create(&store.Tag{Distinction: "major", ImageID: i.ID, Name: "1.0"}), "a tag is unique per distinction and image")
+	assert.NoError(t, s.Tags().Create(&store.Tag{Distinction: "minor", ImageID: i.ID, Name: "1.0"}))
+}
+
+func testTagGet(t *testing.T, s store.Store) {
+	i := createImage(t, s, "unit.test/tag", "sha256:a", baseTime)
+	other := createImage(t, s, "unit.test/tag", "sha256:b", baseTime)
+	otherName := createImage(t, s, "unit.test/other", "sha256:a", baseTime)
+	major := createTag(t, s, i.ID, "1.0", "major", false)
+	minor := createTag(t, s, i.ID, "1", "minor", true)
+	otherMajor := createTag(t, s, other.ID, "2.0", "major", true)
+	createTag(t, s, otherName.ID, "3.0", "major", true)
+
+	_, err := s.Tags().Get(store.TagGetOptions{Name: "1.0"})
+	assert.Error(t, err, "ImageName is required")
+
+	testCases := []struct {
+		name       string
+		opts       store.TagGetOptions
+		expectedID int
+	}{
+		{name: "Distinction", opts: store.TagGetOptions{Distinction: "minor", ImageName: "unit.test/tag"}, expectedID: minor.ID},
+		{name: "ImageID", opts: store.TagGetOptions{ImageID: other.ID, ImageName: "unit.test/tag"}, expectedID: otherMajor.ID},
+		{name: "IsLatest true", opts: store.TagGetOptions{Distinction: "major", ImageName: "unit.test/tag", IsLatest: boolPtr(true)}, expectedID: otherMajor.ID},
+		{name: "IsLatest false", opts: store.TagGetOptions{ImageName: "unit.test/tag", IsLatest: boolPtr(false)}, expectedID: major.ID},
+		{name: "Name", opts: store.TagGetOptions{ImageName: "unit.test/tag", Name: "1.0"}, expectedID: major.ID},
+	}
+	for _, tc := range testCases {
+		tag, err := s.Tags().Get(tc.opts)
+		if assert.NoError(t, err, tc.name) {
+			assert.Equal(t, tc.expectedID, tag.ID, tc.name)
+		}
+	}
+
+	_, err = s.Tags().Get(store.TagGetOptions{ImageName: "unit.test/tag", Name: "3.0"})
+	assert.Equal(t, store.ErrDoesNotExist, err, "tags of images with other names are not returned")
+}
+
+func testTagList(t *testing.T, s store.Store) {
+	i := createImage(t, s, "unit.test/tag", "sha256:a", baseTime)
+	other := createImage(t, s, "unit.test/tag", "sha256:b", baseTime)
+	otherName := createImage(t, s, "unit.test/other", "sha256:a", baseTime)
+	major := createTag(t, s, i.ID, "1.0", "major", false)
+	minor := createTag(t, s, i.ID, "1", "minor", true)
+	otherMajor := createTag(t, s, other.ID, "2.0", "major", true)
+	untagged := &store.Tag{Distinction: "major", ImageID: other.ID, Name: "0.9"}
+	require.NoError(t, s.Tags().Create(untagged))
+	otherNameMajor := createTag(t, s, otherName.ID, "3.0", "major", true)
+
+	testCases := []struct {
+		name        string
+		opts        store.TagListOptions
+		expectedIDs []int
+	}{
+		{name: "none", opts: store.TagListOptions{}, expectedIDs: []int{major.ID, minor.ID, otherMajor.ID, untagged.ID, otherNameMajor.ID}},
+		{name: "Distinction", opts: store.TagListOptions{Distinction: "minor"}, expectedIDs: []int{minor.ID}},
+		{name: "ImageID", opts: store.TagListOptions{ImageID: other.ID}, expectedIDs: []int{otherMajor.ID, untagged.ID}},
+		{name: "ImageIDs", opts: store.TagListOptions{ImageIDs: []int{i.ID, otherName.ID}}, expectedIDs: []int{major.ID, minor.ID, otherNameMajor.ID}},
+		{name: "ImageName", opts: store.TagListOptions{ImageName: "unit.test/tag"}, expectedIDs: []int{major.ID, minor.ID, otherMajor.ID, untagged.ID}},
+		{name: "ImageNames", opts: store.TagListOptions{ImageNames: []string{"unit.test/other", "unit.test/unknown"}}, expectedIDs: []int{otherNameMajor.ID}},
+		{name: "ImageNames and IsLatest", opts: store.TagListOptions{ImageNames: []string{"unit.test/tag", "unit.test/other"}, IsLatest: boolPtr(true)}, expectedIDs: []int{minor.ID, otherMajor.ID, otherNameMajor.ID}},
+		{name: "Names", opts: store.TagListOptions{Names: []string{"1.0", "3.0"}}, expectedIDs: []int{major.ID, otherNameMajor.ID}},
+		{name: "Names and ImageName", opts: store.TagListOptions{ImageName: "unit.test/tag", Names: []string{"1.0", "3.0", "0.9"}}, expectedIDs: []int{major.ID, untagged.ID}},
+		{name: "ImageIDs and IsTagged", opts: store.TagListOptions{ImageIDs: []int{other.ID}, IsTagged: boolPtr(true)}, expectedIDs: []int{otherMajor.ID}},
+		{name: "IsLatest true", opts: store.TagListOptions{ImageName: "unit.test/tag", IsLatest: boolPtr(true)}, expectedIDs: []int{minor.ID, otherMajor.ID}},
+		{name: "IsLatest false", opts: store.TagListOptions{ImageName: "unit.test/tag", IsLatest: boolPtr(false)}, expectedIDs: []int{major.ID, untagged.ID}},
+		{name: "IsTagged true", opts: store.TagListOptions{ImageID: other.ID, IsTagged: boolPtr(true)}, expectedIDs: []int{otherMajor.ID}},
+		{name: "IsTagged false", opts: store.TagListOptions{IsTagged: boolPtr(false)}, expectedIDs: []int{untagged.ID}},
+		{name: "Distinction and ImageName", opts: store.TagListOptions{Distinction: "major", ImageName: "unit.test/other"}, expectedIDs: []int{otherNameMajor.ID}},
+	}
+	for _, tc := range testCases {
+		tags, err := s.Tags().List(tc.opts)
+		if assert.NoError(t, err, tc.name) {
+			assert.ElementsMatch(t, tc.expectedIDs, tagIDs(tags), tc.name)
+		}
+	}
+}
+
+func testTagUpdate(t *testing.T, s store.Store) {
+	i := createImage(t, s, "unit.test/tag", "sha256:a", baseTime)
+	tag := createTag(t, s, i.ID, "1.0", "major", true)
+	tag.IsLatest = false
+	tag.IsTagged = false
+	require.NoError(t, s.Tags().Update(tag))
+
+	stored, err := s.Tags().Get(store.TagGetOptions{ImageName: "unit.test/tag", Name: "1.0"})
+	require.NoError(t, err)
+	assert.Equal(t, tag, stored)
+}
+
+func testTransactionCommit(t *testing.T, s store.Store) {
+	tx, err := s.Transaction()
+	require.NoError(t, err)
+	i := createImage(t, tx, "unit.test/tx", "sha256:a", baseTime)
+	createTag(t, tx, i.ID, "1.0", "major", true)
+	createPlatform(t, tx, i.ID, "amd64", "", createLayer(t, tx, "sha256:layer", i.ID))
+	require.NoError(t, tx.Commit())
+
+	stored, err := s.Images().Get(store.ImageGetOptions{Name: "unit.test/tx", TagName: "1.0"})
+	require.NoError(t, err)
+	assert.Equal(t, i.ID, stored.ID)
+	platforms, err := s.Platforms().List(store.PlatformListOptions{LayerDigest: "sha256:layer"})
+	require.NoError(t, err)
+	assert.Len(t, platforms, 1)
+}
+
+func testTransactionRollback(t *testing.T, s store.Store) {
+	existing := createImage(t, s, "unit.test/tx", "sha256:a", baseTime)
+	existingTag := createTag(t, s, existing.ID, "1.0", "major", true)
+
+	tx, err := s.Transaction()
+	require.NoError(t, err)
+	i := createImage(t, tx, "unit.test/tx", "sha256:b", baseTime.Add(time.Hour))
+	createTag(t, tx, i.ID, "2.0", "major", true)
+	createPlatform(t, tx, i.ID, "amd64", "", createLayer(t, tx, "sha256:layer", i.ID))
+	existingTag.IsLatest = false
+	require.NoError(t, tx.Tags().Update(existingTag))
+	require.NoError(t, tx.TagEvents().Create(&store.TagEvent{Action: store.TagEventActionUnsetLatest, Digest: existing.Digest, ImageName: existing.Name, TagName: existingTag.Name}))
+	require.NoError(t, tx.Rollback())
+
+	images, err := s.Images().List(store.ImageListOptions{Name: "unit.test/tx"})
+	require.NoError(t, err)
+	assert.Equal(t, []int{existing.ID}, imageIDs(images))
+	tags, err := s.Tags().List(store.TagListOptions{ImageName: "unit.test/tx"})
+	require.NoError(t, err)
+	require.Len(t, tags, 1)
+	assert.True(t, tags[0].IsLatest, "updates are rolled back")
+	_, err = s.Layers().Get(store.LayerGetOptions{Digest: "sha256:layer"})
+	assert.Equal(t, store.ErrDoesNotExist, err)
+	platforms, err := s.Platforms().List(store.PlatformListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, platforms)
+	events, err := s.TagEvents().List(store.TagEventListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	tx, err = s.Transaction()
+	require.NoError(t, err)
+	require.NoError(t, tx.Images().Delete(existing))
+	require.NoError(t, tx.Rollback())
+	_, err = s.Images().Get(store.ImageGetOptions{ID: existing.ID})
+	assert.NoError(t, err, "deletes are rolled back")
+	tags, err = s.Tags().List(store.TagListOptions{ImageName: "unit.test/tx"})
+	require.NoError(t, err)
+	assert.Len(t, tags, 1)
+}
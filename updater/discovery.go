@@ -0,0 +1,217 @@
+package updater
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/tunny"
+	"github.com/imagespy/api/registry"
+	"github.com/imagespy/api/scrape"
+	"github.com/imagespy/api/store"
+	"github.com/imagespy/api/versionparser"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	log "github.com/sirupsen/logrus"
+)
+
+type discoveryUpdater struct {
+	dispatchFunc func(repositories []registry.Repository)
+	exclude      []string
+	include      []string
+	promPusher   *push.Pusher
+	registry     registry.Registry
+	rules        *versionparser.Rules
+	scraper      scrape.Scraper
+	store        store.Store
+}
+
+// Run scrapes all tags of every repository in the catalog of the registry that passes the filters.
+func (d *discoveryUpdater) Run() error {
+	failCount.Set(0)
+	start := time.Now()
+	repositories, err := d.registry.Repositories()
+	if err != nil {
+		return fmt.Errorf("discoveryUpdater.Run - listing repositories of registry %s: %s", d.registry.Address(), err)
+	}
+
+	matching := []registry.Repository{}
+	for _, repository := range repositories {
+		if d.matches(repositoryPath(repository)) {
+			matching = append(matching, repository)
+		}
+	}
+
+	log.Infof("Discovered %d repositories of which %d match the filters", len(repositories), len(matching))
+	d.dispatchFunc(matching)
+	duration.Set(time.Since(start).Seconds())
+	completionTime.SetToCurrentTime()
+	if d.promPusher != nil {
+		err = d.promPusher.Add()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// matches returns true if name matches any include pattern and no exclude pattern.
+// All names match if no include pattern has been set.
+func (d *discoveryUpdater) matches(name string) bool {
+	for _, pattern := range d.exclude {
+		ok, _ := path.Match(pattern, name)
+		if ok {
+			return false
+		}
+	}
+
+	if len(d.include) == 0 {
+		return true
+	}
+
+	for _, pattern := range d.include {
+		ok, _ := path.Match(pattern, name)
+		if ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// processRepository scrapes all tags of a repository first and the latest image of each distinction afterwards.
+// Scraping the latest image lists all tags of the repository, so it happens once per distinction instead of once per tag.
+// The scraper replaces the latest tag of a distinction only if it receives that tag, so the current latest tag is preferred.
+func (d *discoveryUpdater) processRepository(repository registry.Repository) {
+	log.Debugf("Discovering images of repository %s", repository.FullName())
+	images, err := repository.Images()
+	if err != nil {
+		log.Errorf("unable to list images of repository %s: %s", repository.FullName(), err)
+		failCount.Inc()
+		return
+	}
+
+	b := true
+	latestTags, err := d.store.Tags().List(store.TagListOptions{ImageName: repository.FullName(), IsLatest: &b})
+	if err != nil {
+		log.Errorf("unable to read latest tags of repository %s: %s", repository.FullName(), err)
+		failCount.Inc()
+		return
+	}
+
+	isLatest := map[string]bool{}
+	for _, t := range latestTags {
+		isLatest[t.Name] = true
+	}
+
+	vps := d.rules.ForRepository(repository.FullName())
+	distinctions := []string{}
+	imagesByDistinction := map[string]registry.Image{}
+	latestByDistinction := map[string]bool{}
+	for _, image := range images {
+		err := d.scraper.ScrapeImage(image)
+		if err != nil {
+			log.Error(err)
+			failCount.Inc()
+			continue
+		}
+
+		tag, err := image.Tag()
+		if err != nil {
+			log.Errorf("unable to read tag of image of repository %s: %s", repository.FullName(), err)
+			failCount.Inc()
+			continue
+		}
+
+		if vps.IsIgnored(tag) {
+			continue
+		}
+
+		distinction := vps.FindForVersion(tag).Distinction()
+		if _, ok := imagesByDistinction[distinction]; !ok {
+			distinctions = append(distinctions, distinction)
+			imagesByDistinction[distinction] = image
+		}
+
+		if isLatest[tag] && !latestByDistinction[distinction] {
+			imagesByDistinction[distinction] = image
+			latestByDistinction[distinction] = true
+		}
+	}
+
+	for _, distinction := range distinctions {
+		err := d.scraper.ScrapeLatestImage(imagesByDistinction[distinction])
+		if err != nil {
+			log.Error(err)
+			failCount.Inc()
+		}
+	}
+}
+
+// repositoryPath removes the domain of the registry from the full name of a repository.
+func repositoryPath(r registry.Repository) string {
+	parts := strings.SplitN(r.FullName(), "/", 2)
+	if len(parts) == 1 {
+		return parts[0]
+	}
+
+	return parts[1]
+}
+
+// NewDiscoveryUpdater returns an Updater that scrapes every repository listed in the catalog of the registry r.
+// include and exclude are glob patterns in the format of path.Match that are matched against the path of a repository,
+// e.g. "library/*". Exclude patterns take precedence over include patterns.
+// rules group the tags of a repository by their distinction to scrape the latest image of each distinction once.
+func NewDiscoveryUpdater(pushgatewayURL string, r registry.Registry, s scrape.Scraper, st store.Store, rules *versionparser.Rules, include []string, exclude []string, wc int) (Updater, error) {
+	for _, pattern := range append(append([]string{}, include...), exclude...) {
+		_, err := path.Match(pattern, "")
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %s: %s", pattern, err)
+		}
+	}
+
+	du := &discoveryUpdater{
+		exclude:  exclude,
+		include:  include,
+		registry: r,
+		rules:    rules,
+		scraper:  s,
+		store:    st,
+	}
+
+	if pushgatewayURL != "" {
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(completionTime, duration, failCount)
+		du.promPusher = push.New(pushgatewayURL, "imagespy_updater_discovery").Gatherer(registry)
+	}
+
+	pool := tunny.NewFunc(wc, func(payload interface{}) interface{} {
+		repository, ok := payload.(registry.Repository)
+		if !ok {
+			log.Error("unable to cast payload to registry.Repository")
+			return nil
+		}
+
+		du.processRepository(repository)
+		return nil
+	})
+
+	du.dispatchFunc = func(repositories []registry.Repository) {
+		wg := &sync.WaitGroup{}
+		wg.Add(len(repositories))
+		for _, repository := range repositories {
+			payload := repository
+			go func() {
+				pool.Process(payload)
+				wg.Done()
+			}()
+		}
+
+		wg.Wait()
+	}
+
+	return du, nil
+}
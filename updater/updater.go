@@ -1,7 +1,6 @@
 package updater
 
 import (
-	"database/sql"
 	"fmt"
 	"sync"
 	"time"
@@ -169,27 +168,22 @@ func (ap *asyncProcessor) dispatch(groups map[string][]string) {
 }
 
 type allImagesUpdater struct {
-	db         *sql.DB
 	promPusher *push.Pusher
 	registry   registry.Registry
 	scraper    scrape.Scraper
+	store      store.Store
 }
 
 func (a *allImagesUpdater) Run() error {
 	failCount.Set(0)
 	start := time.Now()
-	rows, err := a.db.Query("select name from imagespy_image group by name")
+	repositories, err := a.store.Images().ListRepositories(store.RepositoryListOptions{})
 	if err != nil {
 		return err
 	}
-	defer rows.Close()
-	for rows.Next() {
-		var name string
-		err := rows.Scan(&name)
-		if err != nil {
-			return err
-		}
 
+	for _, r := range repositories {
+		name := r.Name
 		log.Debugf("Updating image %s...", name)
 		repository, err := a.registry.Repository(name)
 		if err != nil {
@@ -230,7 +224,7 @@ func (a *allImagesUpdater) Run() error {
 	return nil
 }
 
-func NewAllImagesUpdater(pushgatewayURL string, db *sql.DB, r registry.Registry, s scrape.Scraper) Updater {
+func NewAllImagesUpdater(pushgatewayURL string, r registry.Registry, s scrape.Scraper, st store.Store) Updater {
 	var promPusher *push.Pusher
 	if pushgatewayURL != "" {
 		registry := prometheus.NewRegistry()
@@ -239,9 +233,9 @@ func NewAllImagesUpdater(pushgatewayURL string, db *sql.DB, r registry.Registry,
 	}
 
 	return &allImagesUpdater{
-		db:         db,
 		promPusher: promPusher,
 		registry:   r,
 		scraper:    s,
+		store:      st,
 	}
 }
@@ -8,6 +8,7 @@ import (
 	"github.com/imagespy/api/scrape"
 	"github.com/imagespy/api/store"
 	"github.com/imagespy/api/store/mock"
+	"github.com/imagespy/api/versionparser"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -85,3 +86,91 @@ func TestLatestImageUpdater_Run(t *testing.T) {
 	}
 	assert.Equal(t, expectedGroups, actualGroups)
 }
+
+func TestAllImagesUpdater_Run(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	imageStore := mock.NewMockImageStore(ctrl)
+	imageStore.EXPECT().
+		ListRepositories(gomock.Eq(store.RepositoryListOptions{})).
+		Return([]*store.Repository{{Name: "unit.test/first"}, {Name: "unit.test/second"}}, nil)
+
+	store := mock.NewMockStore(ctrl)
+	store.EXPECT().
+		Images().
+		Return(imageStore).
+		AnyTimes()
+
+	rmi1 := registryMock.NewImage("", "unit.test/first", nil, 2, "1")
+	rmi2 := registryMock.NewImage("", "unit.test/first", nil, 2, "latest")
+	rmi3 := registryMock.NewImage("", "unit.test/second", nil, 2, "v3")
+	rm := registryMock.NewRegistry()
+	rm.AddImage(rmi1)
+	rm.AddImage(rmi2)
+	rm.AddImage(rmi3)
+
+	scraper := scrape.NewMockScraper(ctrl)
+	for _, rmi := range []*registryMock.Image{rmi1, rmi2, rmi3} {
+		scraper.EXPECT().
+			ScrapeImage(rmi).
+			Return(nil)
+		scraper.EXPECT().
+			ScrapeLatestImage(rmi).
+			Return(nil)
+	}
+
+	err := NewAllImagesUpdater("", rm, scraper, store).Run()
+	assert.NoError(t, err)
+}
+
+func TestDiscoveryUpdater_Run(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tagStore := mock.NewMockTagStore(ctrl)
+	b := true
+	tagStore.EXPECT().
+		List(gomock.Eq(store.TagListOptions{ImageName: "unit.test/library/first", IsLatest: &b})).
+		Return([]*store.Tag{{Distinction: "major", IsLatest: true, IsTagged: true, Name: "2"}}, nil)
+	st := mock.NewMockStore(ctrl)
+	st.EXPECT().
+		Tags().
+		Return(tagStore).
+		AnyTimes()
+
+	rmi1 := registryMock.NewImage("", "unit.test/library/first", nil, 2, "1")
+	rmi2 := registryMock.NewImage("", "unit.test/library/first", nil, 2, "2")
+	rmi3 := registryMock.NewImage("", "unit.test/library/first", nil, 2, "3")
+	rmi4 := registryMock.NewImage("", "unit.test/library/first", nil, 2, "latest")
+	rmi5 := registryMock.NewImage("", "unit.test/library/second", nil, 2, "v3")
+	rmi6 := registryMock.NewImage("", "unit.test/other/third", nil, 2, "1.0")
+	rm := registryMock.NewRegistry()
+	for _, rmi := range []*registryMock.Image{rmi1, rmi2, rmi3, rmi4, rmi5, rmi6} {
+		rm.AddImage(rmi)
+	}
+
+	scraper := scrape.NewMockScraper(ctrl)
+	for _, rmi := range []*registryMock.Image{rmi1, rmi2, rmi3, rmi4} {
+		scraper.EXPECT().
+			ScrapeImage(rmi).
+			Return(nil)
+	}
+
+	// The latest image is scraped once per distinction, starting from the current latest tag.
+	scraper.EXPECT().
+		ScrapeLatestImage(rmi2).
+		Return(nil)
+	scraper.EXPECT().
+		ScrapeLatestImage(rmi4).
+		Return(nil)
+
+	rules := versionparser.NewRules(versionparser.Opts{})
+	u, err := NewDiscoveryUpdater("", rm, scraper, st, rules, []string{"library/*"}, []string{"*/second"}, 2)
+	assert.NoError(t, err)
+	err = u.Run()
+	assert.NoError(t, err)
+
+	_, err = NewDiscoveryUpdater("", rm, scraper, st, rules, []string{"library/["}, nil, 1)
+	assert.Error(t, err)
+}
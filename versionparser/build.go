@@ -0,0 +1,130 @@
+package versionparser
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrCreatedUnknown = errors.New("Version Parser needs the time the image has been created")
+
+	// buildNumberRegexp matches a name followed by a number, e.g. "build-1234", "r42" or "ci_7".
+	buildNumberRegexp = regexp.MustCompile("^([A-Za-z][A-Za-z_]*?)[-_.]?(\\d+)$")
+	// gitSHARegexp matches an abbreviated or full commit SHA, optionally prefixed by a name, e.g. "a1b2c3d" or "sha-a1b2c3d".
+	gitSHARegexp = regexp.MustCompile("^(?:([A-Za-z]\\w*)-)?([0-9a-f]{7,40})$")
+	// hexLetterRegexp tells a commit SHA apart from a number.
+	hexLetterRegexp = regexp.MustCompile("[a-f]")
+)
+
+// CreatedOrdered is implemented by version parsers that cannot derive the order of versions from the tag.
+// Versions are ordered by the time the image of the tag has been created instead.
+type CreatedOrdered interface {
+	VersionParser
+	// WithCreated returns a copy of the version parser that knows the time the image of the tag has been created.
+	WithCreated(created time.Time) VersionParser
+}
+
+// BuildNumber is a version that is a monotonically increasing number of a build, e.g. "build-1234".
+type BuildNumber struct {
+	name   string
+	number int
+	raw    string
+}
+
+func (p *BuildNumber) Distinction() string {
+	return fmt.Sprintf("buildNumber-%s", p.name)
+}
+
+func (p *BuildNumber) IsGreaterThan(other VersionParser) (bool, error) {
+	o, ok := other.(*BuildNumber)
+	if !ok {
+		return false, ErrWrongVPType
+	}
+
+	return p.number > o.number, nil
+}
+
+func (p *BuildNumber) String() string {
+	return p.raw
+}
+
+func (p *BuildNumber) Weight() int {
+	return 65
+}
+
+func buildNumberFactory(version string) (VersionParser, error) {
+	matches := buildNumberRegexp.FindStringSubmatch(version)
+	if len(matches) == 0 {
+		return nil, ErrVersionNotSupported
+	}
+
+	number, err := strconv.Atoi(matches[2])
+	if err != nil {
+		return nil, ErrVersionNotSupported
+	}
+
+	return &BuildNumber{
+		name:   matches[1],
+		number: number,
+		raw:    version,
+	}, nil
+}
+
+// GitSHA is a version that is the SHA of a commit, e.g. "a1b2c3d".
+// A SHA does not tell which commit is newer, so versions are ordered by the time their image has been created.
+type GitSHA struct {
+	created time.Time
+	name    string
+	raw     string
+}
+
+func (p *GitSHA) Distinction() string {
+	if p.name == "" {
+		return "gitSHA"
+	}
+
+	return fmt.Sprintf("gitSHA-%s", p.name)
+}
+
+// IsGreaterThan returns ErrCreatedUnknown if the time of one of the images is not known.
+func (p *GitSHA) IsGreaterThan(other VersionParser) (bool, error) {
+	o, ok := other.(*GitSHA)
+	if !ok {
+		return false, ErrWrongVPType
+	}
+
+	if p.created.IsZero() || o.created.IsZero() {
+		return false, ErrCreatedUnknown
+	}
+
+	return p.created.After(o.created), nil
+}
+
+func (p *GitSHA) String() string {
+	return p.raw
+}
+
+func (p *GitSHA) Weight() int {
+	return 55
+}
+
+func (p *GitSHA) WithCreated(created time.Time) VersionParser {
+	vp := *p
+	vp.created = created
+	return &vp
+}
+
+func gitSHAFactory(version string) (VersionParser, error) {
+	matches := gitSHARegexp.FindStringSubmatch(version)
+	if len(matches) == 0 || !hexLetterRegexp.MatchString(matches[2]) {
+		return nil, ErrVersionNotSupported
+	}
+
+	return &GitSHA{
+		name: matches[1],
+		raw:  version,
+	}, nil
+}
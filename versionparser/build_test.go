@@ -0,0 +1,95 @@
+package versionparser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildNumber(t *testing.T) {
+	testcases := []struct {
+		version         string
+		other           string
+		expectedGreater bool
+	}{
+		{"build-1234", "build-1233", true},
+		{"build-999", "build-1000", false},
+		{"r42", "r9", true},
+	}
+
+	for _, tc := range testcases {
+		vp, err := buildNumberFactory(tc.version)
+		require.NoError(t, err)
+		other, err := buildNumberFactory(tc.other)
+		require.NoError(t, err)
+		assert.Equal(t, vp.Distinction(), other.Distinction())
+
+		result, err := vp.IsGreaterThan(other)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expectedGreater, result, "%s > %s", tc.version, tc.other)
+	}
+}
+
+func TestGitSHA(t *testing.T) {
+	created := time.Date(2019, 3, 26, 12, 0, 0, 0, time.UTC)
+	testcases := []struct {
+		version         string
+		versionCreated  time.Time
+		other           string
+		otherCreated    time.Time
+		expectedGreater bool
+		expectedErr     error
+		testName        string
+	}{
+		{"a1b2c3d", created.Add(time.Hour), "d4e5f60", created, true, nil, "Created later"},
+		{"a1b2c3d", created, "d4e5f60", created.Add(time.Hour), false, nil, "Created earlier"},
+		{"a1b2c3d", created, "d4e5f60", created, false, nil, "Created at the same time"},
+		{"a1b2c3d", created, "d4e5f60", time.Time{}, false, ErrCreatedUnknown, "Created of other unknown"},
+		{"a1b2c3d", time.Time{}, "d4e5f60", created, false, ErrCreatedUnknown, "Created unknown"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.testName, func(t *testing.T) {
+			vp, err := gitSHAFactory(tc.version)
+			require.NoError(t, err)
+			other, err := gitSHAFactory(tc.other)
+			require.NoError(t, err)
+			if !tc.versionCreated.IsZero() {
+				vp = vp.(CreatedOrdered).WithCreated(tc.versionCreated)
+			}
+
+			if !tc.otherCreated.IsZero() {
+				other = other.(CreatedOrdered).WithCreated(tc.otherCreated)
+			}
+
+			result, err := vp.IsGreaterThan(other)
+			assert.Equal(t, tc.expectedErr, err)
+			assert.Equal(t, tc.expectedGreater, result)
+		})
+	}
+}
+
+func TestBuildRegistry(t *testing.T) {
+	testcases := []struct {
+		version             string
+		expectedDistinction string
+		testName            string
+	}{
+		{"a1b2c3d", "gitSHA", "Abbreviated SHA"},
+		{"a1b2c3d4e5f60718293a4b5c6d7e8f9012345678", "gitSHA", "Full SHA"},
+		{"sha-a1b2c3d", "gitSHA-sha", "SHA with name"},
+		{"build-1234", "buildNumber-build", "Build number"},
+		{"r42", "buildNumber-r", "Build number without separator"},
+		{"1234567", "major", "Number is not a SHA"},
+		{"ubuntu-20180913", "nameDate-ubuntu", "Date is not a build number"},
+		{"build-1553601600", "timestamp-build", "Timestamp is not a build number"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.testName, func(t *testing.T) {
+			assert.Equal(t, tc.expectedDistinction, Registry.FindForVersion(tc.version).Distinction())
+		})
+	}
+}
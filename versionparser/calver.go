@@ -0,0 +1,85 @@
+package versionparser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+var (
+	// calVerLongRegexp matches versions with a four-digit year, e.g. "2019.04", "2019.4" or "2019.04.1".
+	calVerLongRegexp = regexp.MustCompile("^v?((?:19|20)\\d{2})\\.(0?[1-9]|1[0-2])(?:\\.(\\d+))?(-.*)?$")
+	// calVerShortRegexp matches versions with a two-digit year and a month with a leading zero, e.g. "19.04", "19.10" or "19.04.1".
+	// Versions like "19.10.1" are also semantic versions, so the parser of this format is tried after the semantic version parsers.
+	calVerShortRegexp = regexp.MustCompile("^v?(\\d{2})\\.(0[1-9]|1[0-2])(?:\\.(\\d+))?(-.*)?$")
+)
+
+// CalVer is a version in the format of Calendar Versioning, e.g. "2019.04", "2019.04.1" or "19.04.1".
+// A version without a micro, e.g. "2019.04", shares the distinction with the versions of the same year format that have one.
+type CalVer struct {
+	build string
+	micro int
+	month int
+	raw   string
+	year  int
+}
+
+func (p *CalVer) Distinction() string {
+	return fmt.Sprintf("calVer%s", p.build)
+}
+
+func (p *CalVer) IsGreaterThan(other VersionParser) (bool, error) {
+	o, ok := other.(*CalVer)
+	if !ok {
+		return false, ErrWrongVPType
+	}
+
+	if p.year != o.year {
+		return p.year > o.year, nil
+	}
+
+	if p.month != o.month {
+		return p.month > o.month, nil
+	}
+
+	return p.micro > o.micro, nil
+}
+
+func (p *CalVer) String() string {
+	return p.raw
+}
+
+func (p *CalVer) Weight() int {
+	return 95
+}
+
+// calVerFactory parses versions with a four-digit year, e.g. "2019.04".
+func calVerFactory(version string) (VersionParser, error) {
+	return newCalVer(version, calVerLongRegexp.FindStringSubmatch(version))
+}
+
+// calVerShortYearFactory parses versions with a two-digit year, e.g. "19.04.1".
+func calVerShortYearFactory(version string) (VersionParser, error) {
+	return newCalVer(version, calVerShortRegexp.FindStringSubmatch(version))
+}
+
+func newCalVer(version string, matches []string) (VersionParser, error) {
+	if len(matches) == 0 {
+		return nil, ErrVersionNotSupported
+	}
+
+	year, _ := strconv.Atoi(matches[1])
+	if len(matches[1]) == 2 {
+		year = 2000 + year
+	}
+
+	month, _ := strconv.Atoi(matches[2])
+	micro, _ := strconv.Atoi(matches[3])
+	return &CalVer{
+		build: matches[4],
+		micro: micro,
+		month: month,
+		raw:   version,
+		year:  year,
+	}, nil
+}
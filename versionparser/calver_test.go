@@ -0,0 +1,69 @@
+package versionparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalVer_Parse(t *testing.T) {
+	testcases := []struct {
+		version             string
+		expectedDistinction string
+		testName            string
+	}{
+		{"2019.04", "calVer", "Year and month"},
+		{"2019.4", "calVer", "Year and month without leading zero"},
+		{"2019.04.1", "calVer", "Year, month and micro"},
+		{"v2019.12.3", "calVer", "With v prefix"},
+		{"2019.04-alpine", "calVer-alpine", "With build suffix"},
+		{"2019.13", "majorMinor", "Invalid month"},
+		{"19.04", "calVer", "Short year and month"},
+		{"19.04.1", "calVer", "Short year, month and micro"},
+		{"19.10.1", "majorMinorPatch", "Short year and two-digit month"},
+		{"19.12", "majorMinor", "Short year and last month"},
+		{"19.13", "majorMinor", "Short year and invalid month"},
+		{"19.4", "majorMinor", "Short year and month without leading zero"},
+		{"19.04-minimal", "calVer-minimal", "Short year with build suffix"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.testName, func(t *testing.T) {
+			vp := Registry.FindForVersion(tc.version)
+			assert.Equal(t, tc.expectedDistinction, vp.Distinction())
+			assert.Equal(t, tc.version, vp.String())
+		})
+	}
+}
+
+func TestCalVer_IsGreaterThan(t *testing.T) {
+	testcases := []struct {
+		version         string
+		other           string
+		expectedGreater bool
+	}{
+		{"2019.05", "2019.04", true},
+		{"2019.04", "2019.05", false},
+		{"2020.01", "2019.12", true},
+		{"2019.04.1", "2019.04", true},
+		{"2019.04.10", "2019.04.9", true},
+		{"2019.4", "2019.04", false},
+		{"19.04.1", "2019.04", true},
+		{"19.04", "2018.12.5", true},
+		{"19.10", "19.09.3", true},
+	}
+
+	// "19.10" is a majorMinor in the default registry, so the versions are parsed by the CalVer parsers only.
+	r := &DefaultRegistry{factories: []func(string) (VersionParser, error){calVerFactory, calVerShortYearFactory}}
+	for _, tc := range testcases {
+		vp := r.FindForVersion(tc.version)
+		require.IsType(t, &CalVer{}, vp, tc.version)
+		other := r.FindForVersion(tc.other)
+		require.IsType(t, &CalVer{}, other, tc.other)
+
+		result, err := vp.IsGreaterThan(other)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expectedGreater, result, "%s > %s", tc.version, tc.other)
+	}
+}
@@ -0,0 +1,103 @@
+package versionparser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// customDistinctionGroup is the name of the capture group of a custom parser that becomes part of the distinction instead of being compared.
+const customDistinctionGroup = "distinction"
+
+// Custom is a version parser that is defined by a regular expression.
+// Named capture groups are compared in the order in which they appear in the expression.
+// Values that are numbers are compared numerically, all other values in ASCII sort order.
+// The capture group "distinction" is not compared. Its value is appended to the distinction, e.g. "-alpine".
+type Custom struct {
+	distinction string
+	name        string
+	raw         string
+	values      []string
+	weight      int
+}
+
+func (p *Custom) Distinction() string {
+	return fmt.Sprintf("%s%s", p.name, p.distinction)
+}
+
+func (p *Custom) IsGreaterThan(other VersionParser) (bool, error) {
+	o, ok := other.(*Custom)
+	if !ok || o.name != p.name {
+		return false, ErrWrongVPType
+	}
+
+	for idx := 0; idx < len(p.values) && idx < len(o.values); idx++ {
+		if p.values[idx] == o.values[idx] {
+			continue
+		}
+
+		pInt, pErr := strconv.Atoi(p.values[idx])
+		oInt, oErr := strconv.Atoi(o.values[idx])
+		if pErr == nil && oErr == nil {
+			return pInt > oInt, nil
+		}
+
+		return strings.Compare(p.values[idx], o.values[idx]) > 0, nil
+	}
+
+	return false, nil
+}
+
+func (p *Custom) String() string {
+	return p.raw
+}
+
+func (p *Custom) Weight() int {
+	return p.weight
+}
+
+// newCustomFactory compiles the expression of a custom parser.
+func newCustomFactory(name string, expr string, weight int) (func(string) (VersionParser, error), error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("compiling regexp of custom parser '%s': %s", name, err)
+	}
+
+	hasGroup := false
+	for _, groupName := range re.SubexpNames() {
+		if groupName != "" && groupName != customDistinctionGroup {
+			hasGroup = true
+		}
+	}
+
+	if !hasGroup {
+		return nil, fmt.Errorf("regexp of custom parser '%s' does not contain a named capture group to compare", name)
+	}
+
+	return func(version string) (VersionParser, error) {
+		matches := re.FindStringSubmatch(version)
+		if len(matches) == 0 {
+			return nil, ErrVersionNotSupported
+		}
+
+		vp := &Custom{
+			name:   name,
+			raw:    version,
+			values: []string{},
+			weight: weight,
+		}
+		for idx, groupName := range re.SubexpNames() {
+			switch groupName {
+			case "":
+				continue
+			case customDistinctionGroup:
+				vp.distinction = matches[idx]
+			default:
+				vp.values = append(vp.values, matches[idx])
+			}
+		}
+
+		return vp, nil
+	}, nil
+}
@@ -4,10 +4,11 @@ import (
 	"fmt"
 	"regexp"
 	"strconv"
+	"strings"
 )
 
 var (
-	nameDateRegexp  = regexp.MustCompile("^(\\w*)-(\\d{8})$")
+	nameDateRegexp  = regexp.MustCompile("^(\\w*)-(\\d{8}|\\d{4}-\\d{2}-\\d{2})$")
 	staticKnownTags = map[string]struct{}{"latest": struct{}{}, "mainline": struct{}{}, "master": struct{}{}, "stable": struct{}{}}
 )
 
@@ -45,7 +46,7 @@ func nameDateFactory(version string) (VersionParser, error) {
 		return nil, ErrVersionNotSupported
 	}
 
-	date, _ := strconv.Atoi(matches[2])
+	date, _ := strconv.Atoi(strings.Replace(matches[2], "-", "", -1))
 	return &NameDate{
 		date: date,
 		name: matches[1],
@@ -17,3 +17,13 @@ func TestNameDate(t *testing.T) {
 	assert.NoError(t, err)
 	assert.True(t, result)
 }
+
+func TestNameDate_WithDashes(t *testing.T) {
+	first, _ := nameDateFactory("ubuntu-2018-09-13")
+	second, _ := nameDateFactory("ubuntu-20180914")
+	assert.Equal(t, first.Distinction(), second.Distinction())
+
+	result, err := second.IsGreaterThan(first)
+	assert.NoError(t, err)
+	assert.True(t, result)
+}
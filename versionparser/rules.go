@@ -0,0 +1,182 @@
+package versionparser
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// defaultCustomWeight is the weight of a custom parser that does not set one. It equals the weight of SemVer.
+const defaultCustomWeight = 100
+
+// CustomParserConfig defines a version parser by a regular expression with named capture groups.
+type CustomParserConfig struct {
+	Name   string `json:"name" yaml:"name"`
+	Regexp string `json:"regexp" yaml:"regexp"`
+	Weight int    `json:"weight" yaml:"weight"`
+}
+
+// RepositoryRulesConfig configures the version parsers of the repositories that match Pattern.
+type RepositoryRulesConfig struct {
+	// Custom defines version parsers in addition to the built-in ones.
+	Custom []CustomParserConfig `json:"custom" yaml:"custom"`
+	// Ignore contains glob patterns of tags that are not scraped, e.g. "tmp-*".
+	Ignore []string `json:"ignore" yaml:"ignore"`
+	// Parsers lists the names of the built-in and custom parsers in the order in which they are tried.
+	// If empty, the custom parsers are tried first, followed by all built-in parsers.
+	Parsers []string `json:"parsers" yaml:"parsers"`
+	// Pattern is a glob pattern that matches the name of a repository, e.g. "registry.example.com/team/*".
+	Pattern string `json:"pattern" yaml:"pattern"`
+	// PreReleases overrides the pre-release policy, "exclude" or "include".
+	PreReleases string `json:"pre_releases" yaml:"pre_releases"`
+}
+
+// RulesConfig is the content of a file of versioning rules.
+type RulesConfig struct {
+	Repositories []RepositoryRulesConfig `json:"repositories" yaml:"repositories"`
+}
+
+type repositoryRules struct {
+	pattern  string
+	registry *DefaultRegistry
+}
+
+// Rules selects the version parsers of a repository.
+// The rules of the first pattern that matches the name of a repository apply.
+// Repositories that match no pattern use the built-in parsers.
+type Rules struct {
+	fallback     *DefaultRegistry
+	repositories []repositoryRules
+}
+
+// ForRepository returns the registry of version parsers of the repository name, e.g. "registry.example.com/team/app".
+func (r *Rules) ForRepository(name string) *DefaultRegistry {
+	for _, rr := range r.repositories {
+		ok, _ := path.Match(rr.pattern, name)
+		if ok {
+			return rr.registry
+		}
+	}
+
+	return r.fallback
+}
+
+// NewRules returns rules that apply the built-in parsers to every repository.
+func NewRules(o Opts) *Rules {
+	return &Rules{fallback: NewRegistry(o)}
+}
+
+// NewRulesFromConfig validates the config and creates the registries of its repositories.
+func NewRulesFromConfig(c RulesConfig, o Opts) (*Rules, error) {
+	rules := NewRules(o)
+	for idx, rc := range c.Repositories {
+		if rc.Pattern == "" {
+			return nil, fmt.Errorf("repository %d: pattern is empty", idx)
+		}
+
+		_, err := path.Match(rc.Pattern, "")
+		if err != nil {
+			return nil, fmt.Errorf("repository %s: invalid pattern: %s", rc.Pattern, err)
+		}
+
+		reg, err := newRegistryFromConfig(rc, o)
+		if err != nil {
+			return nil, fmt.Errorf("repository %s: %s", rc.Pattern, err)
+		}
+
+		rules.repositories = append(rules.repositories, repositoryRules{pattern: rc.Pattern, registry: reg})
+	}
+
+	return rules, nil
+}
+
+// LoadRules reads the rules from a YAML or JSON file.
+func LoadRules(file string, o Opts) (*Rules, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading rules file %s: %s", file, err)
+	}
+
+	c := RulesConfig{}
+	err = yaml.UnmarshalStrict(b, &c)
+	if err != nil {
+		return nil, fmt.Errorf("parsing rules file %s: %s", file, err)
+	}
+
+	rules, err := NewRulesFromConfig(c, o)
+	if err != nil {
+		return nil, fmt.Errorf("rules file %s: %s", file, err)
+	}
+
+	return rules, nil
+}
+
+func newRegistryFromConfig(rc RepositoryRulesConfig, o Opts) (*DefaultRegistry, error) {
+	if rc.PreReleases != "" {
+		policy, err := ParsePreReleasePolicy(rc.PreReleases)
+		if err != nil {
+			return nil, err
+		}
+
+		o.PreReleasePolicy = policy
+	}
+
+	for _, pattern := range rc.Ignore {
+		_, err := path.Match(pattern, "")
+		if err != nil {
+			return nil, fmt.Errorf("invalid ignore pattern %s: %s", pattern, err)
+		}
+	}
+
+	builtIn := builtInFactories(o)
+	factoriesByName := map[string]func(string) (VersionParser, error){}
+	for _, f := range builtIn {
+		factoriesByName[f.name] = f.factory
+	}
+
+	customNames := []string{}
+	for _, cc := range rc.Custom {
+		if cc.Name == "" {
+			return nil, fmt.Errorf("custom parser without a name")
+		}
+
+		if _, exists := factoriesByName[cc.Name]; exists {
+			return nil, fmt.Errorf("name of custom parser '%s' is already in use", cc.Name)
+		}
+
+		weight := cc.Weight
+		if weight == 0 {
+			weight = defaultCustomWeight
+		}
+
+		factory, err := newCustomFactory(cc.Name, cc.Regexp, weight)
+		if err != nil {
+			return nil, err
+		}
+
+		factoriesByName[cc.Name] = factory
+		customNames = append(customNames, cc.Name)
+	}
+
+	names := rc.Parsers
+	if len(names) == 0 {
+		names = customNames
+		for _, f := range builtIn {
+			names = append(names, f.name)
+		}
+	}
+
+	reg := &DefaultRegistry{ignore: rc.Ignore}
+	for _, name := range names {
+		factory, ok := factoriesByName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown parser '%s'", name)
+		}
+
+		reg.factories = append(reg.factories, factory)
+	}
+
+	return reg, nil
+}
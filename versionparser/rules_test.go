@@ -0,0 +1,145 @@
+package versionparser
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRules = `
+repositories:
+  - pattern: registry.example.com/team/*
+    custom:
+      - name: dateSha
+        regexp: '^(?P<date>\d{8})-[0-9a-f]+$'
+      - name: release
+        regexp: '^release-(?P<number>\d+)$'
+        weight: 50
+    ignore:
+      - "tmp-*"
+  - pattern: index.docker.io/library/ubuntu
+    parsers: [calVerShortYear, majorMinor, static]
+  - pattern: index.docker.io/library/*
+    parsers: [alpine, majorMinorPatch, static]
+    pre_releases: include
+    custom:
+      - name: alpine
+        regexp: '^v(?P<major>\d+)\.(?P<minor>\d+)\.(?P<patch>\d+)(?P<distinction>-alpine)[\d.]+$'
+`
+
+func TestLoadRules(t *testing.T) {
+	dir, err := ioutil.TempDir("", "versionparser")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	p := filepath.Join(dir, "rules.yaml")
+	require.NoError(t, ioutil.WriteFile(p, []byte(testRules), 0600))
+	rules, err := LoadRules(p, Opts{})
+	require.NoError(t, err)
+
+	testcases := []struct {
+		repository          string
+		version             string
+		expectedDistinction string
+		expectedIgnored     bool
+		testName            string
+	}{
+		{"registry.example.com/team/app", "20190326-abc123", "dateSha", false, "Custom parser with date"},
+		{"registry.example.com/team/app", "release-42", "release", false, "Custom parser with number"},
+		{"registry.example.com/team/app", "1.2.3", "majorMinorPatch", false, "Built-in parsers after custom parsers"},
+		{"registry.example.com/team/app", "tmp-feature", "unknown-tmp-feature", true, "Ignored tag"},
+		{"index.docker.io/library/nginx", "v1.2.3-alpine3.10", "alpine-alpine", false, "Custom parser with distinction"},
+		{"index.docker.io/library/nginx", "1.2", "unknown-1.2", false, "Parser not in list"},
+		{"index.docker.io/library/nginx", "stable", "static-stable", false, "Built-in parser in list"},
+		{"index.docker.io/library/ubuntu", "18.10", "calVer", false, "CalVer with short year before majorMinor"},
+		{"index.docker.io/library/ubuntu", "18.04-minimal", "calVer-minimal", false, "CalVer with short year and build suffix"},
+		{"registry.example.com/other/app", "18.10", "majorMinor", false, "CalVer with short year after majorMinor by default"},
+		{"registry.example.com/other/app", "20190326-abc123", "major-abc123", false, "Repository without rules"},
+		{"registry.example.com/other/app", "tmp-feature", "unknown-tmp-feature", false, "Repository without ignored tags"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.testName, func(t *testing.T) {
+			vps := rules.ForRepository(tc.repository)
+			assert.Equal(t, tc.expectedDistinction, vps.FindForVersion(tc.version).Distinction())
+			assert.Equal(t, tc.expectedIgnored, vps.IsIgnored(tc.version))
+		})
+	}
+
+	vps := rules.ForRepository("registry.example.com/team/app")
+	assert.Equal(t, 50, vps.FindForVersion("release-42").Weight())
+	assert.Equal(t, 100, vps.FindForVersion("20190326-abc123").Weight())
+
+	rc, _ := rules.ForRepository("index.docker.io/library/nginx").FindForVersion("2.1.0-rc.1").(*SemVer)
+	require.NotNil(t, rc)
+	release, _ := rules.ForRepository("index.docker.io/library/nginx").FindForVersion("2.0.0").(*SemVer)
+	result, err := rc.IsGreaterThan(release)
+	assert.NoError(t, err)
+	assert.True(t, result, "pre-release policy of the repository applies")
+}
+
+func TestNewRulesFromConfig_Errors(t *testing.T) {
+	testcases := []struct {
+		config   RepositoryRulesConfig
+		testName string
+	}{
+		{RepositoryRulesConfig{}, "Empty pattern"},
+		{RepositoryRulesConfig{Pattern: "["}, "Invalid pattern"},
+		{RepositoryRulesConfig{Pattern: "*", Parsers: []string{"semver"}}, "Unknown parser"},
+		{RepositoryRulesConfig{Pattern: "*", PreReleases: "sometimes"}, "Unknown pre-release policy"},
+		{RepositoryRulesConfig{Pattern: "*", Ignore: []string{"["}}, "Invalid ignore pattern"},
+		{RepositoryRulesConfig{Pattern: "*", Custom: []CustomParserConfig{{Regexp: "^(?P<n>\\d+)$"}}}, "Custom parser without name"},
+		{RepositoryRulesConfig{Pattern: "*", Custom: []CustomParserConfig{{Name: "major", Regexp: "^(?P<n>\\d+)$"}}}, "Custom parser with name of built-in parser"},
+		{RepositoryRulesConfig{Pattern: "*", Custom: []CustomParserConfig{{Name: "build", Regexp: "^(\\d+)$"}}}, "Custom parser without named group"},
+		{RepositoryRulesConfig{Pattern: "*", Custom: []CustomParserConfig{{Name: "build", Regexp: "^(?P<n>\\d+$"}}}, "Custom parser with invalid regexp"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.testName, func(t *testing.T) {
+			_, err := NewRulesFromConfig(RulesConfig{Repositories: []RepositoryRulesConfig{tc.config}}, Opts{})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestCustom(t *testing.T) {
+	factory, err := newCustomFactory("dateBuild", "^(?P<date>\\d{8})-(?P<build>\\w+)$", 100)
+	require.NoError(t, err)
+
+	testcases := []struct {
+		version         string
+		other           string
+		expectedGreater bool
+	}{
+		{"20190327-1", "20190326-2", true},
+		{"20190326-2", "20190327-1", false},
+		{"20190326-10", "20190326-9", true},
+		{"20190326-b", "20190326-a", true},
+		{"20190326-a", "20190326-a", false},
+	}
+
+	for _, tc := range testcases {
+		vp, err := factory(tc.version)
+		require.NoError(t, err)
+		other, err := factory(tc.other)
+		require.NoError(t, err)
+
+		result, err := vp.IsGreaterThan(other)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expectedGreater, result, "%s > %s", tc.version, tc.other)
+	}
+
+	_, err = factory("latest")
+	assert.Equal(t, ErrVersionNotSupported, err)
+
+	otherFactory, err := newCustomFactory("other", "^(?P<date>\\d{8})-(?P<build>\\w+)$", 100)
+	require.NoError(t, err)
+	vp, _ := factory("20190326-1")
+	other, _ := otherFactory("20190326-1")
+	_, err = vp.IsGreaterThan(other)
+	assert.Equal(t, ErrWrongVPType, err)
+}
@@ -4,12 +4,19 @@ import (
 	"fmt"
 	"regexp"
 	"strconv"
+	"strings"
 )
 
 var (
-	majorRegexp           = regexp.MustCompile("^v?(\\d+)(-.*)?$")
-	majorMinorRegexp      = regexp.MustCompile("^v?(\\d+)\\.(\\d+)(-.*)?$")
-	majorMinorPatchRegexp = regexp.MustCompile("^^v?(\\d+)\\.(\\d+)\\.(\\d+)(-.*)?$")
+	majorRegexp = regexp.MustCompile("^v?(\\d+)(-.*)?$")
+	// majorMinorRegexp and semVerRegexp do not match numbers with a leading zero, e.g. "19.04.1", which is a CalVer.
+	majorMinorRegexp = regexp.MustCompile("^v?(0|[1-9]\\d*)\\.(0|[1-9]\\d*)(-.*)?$")
+	// preReleaseIdentifierRegexp matches an identifier of a pre-release, e.g. "rc" or "1" of "1.2.3-rc.1".
+	preReleaseIdentifierRegexp = regexp.MustCompile("^(?:0|[1-9]\\d*|\\d*[A-Za-z-][0-9A-Za-z-]*)$")
+	// preReleaseRegexp matches the first identifier of a pre-release in front of a variant.
+	// Other suffixes in front of a variant, e.g. "-slim" of "1.2.3-slim-debian10", are part of the distinction.
+	preReleaseRegexp = regexp.MustCompile("(?i)^(alpha|beta|dev|pre|preview|rc|snapshot)\\d*$")
+	semVerRegexp     = regexp.MustCompile("^v?(0|[1-9]\\d*)\\.(0|[1-9]\\d*)\\.(0|[1-9]\\d*)(?:-([^+]+))?(?:\\+(.+))?$")
 )
 
 type Major struct {
@@ -117,66 +124,185 @@ func majorMinorFactory(version string) (VersionParser, error) {
 	}, nil
 }
 
-type MajorMinorPatch struct {
-	build string
-	major int
-	minor int
-	patch int
-	raw   string
+// SemVer is a version in the format of Semantic Versioning 2.0, e.g. "1.2.3", "1.2.3-rc.1" or "1.2.3+build.5".
+// Every suffix after "-" is a pre-release, e.g. "-m1" of "2.0.0-m1".
+// Only the version in front of a variant can have a suffix that is not a pre-release, e.g. "-slim" of "1.2.3-slim-debian10".
+type SemVer struct {
+	major      int
+	metadata   string
+	minor      int
+	patch      int
+	policy     PreReleasePolicy
+	preRelease []string
+	raw        string
+	suffix     string
 }
 
-func (p *MajorMinorPatch) Distinction() string {
-	return fmt.Sprintf("majorMinorPatch%s", p.build)
+// MajorMinorPatch is the former name of SemVer.
+type MajorMinorPatch = SemVer
+
+func (p *SemVer) Distinction() string {
+	return fmt.Sprintf("majorMinorPatch%s", p.suffix)
 }
 
-func (p *MajorMinorPatch) IsGreaterThan(other VersionParser) (bool, error) {
-	o, ok := other.(*MajorMinorPatch)
+// IsGreaterThan compares two versions by the precedence of Semantic Versioning 2.0.
+// Build metadata does not affect the precedence.
+// If pre-releases are excluded, every release is greater than every pre-release.
+func (p *SemVer) IsGreaterThan(other VersionParser) (bool, error) {
+	o, ok := other.(*SemVer)
 	if !ok {
 		return false, ErrWrongVPType
 	}
 
-	if p.major > o.major {
-		return true, nil
+	if p.policy == PreReleaseExclude && p.IsPreRelease() != o.IsPreRelease() {
+		return !p.IsPreRelease(), nil
 	}
 
-	if p.major == o.major {
-		if p.minor > o.minor {
-			return true, nil
-		}
+	return p.compare(o) > 0, nil
+}
+
+// IsPreRelease returns true if the version has a pre-release, e.g. "1.2.3-beta".
+func (p *SemVer) IsPreRelease() bool {
+	return len(p.preRelease) > 0
+}
+
+func (p *SemVer) String() string {
+	return p.raw
+}
 
-		if p.minor == o.minor {
-			if p.patch > o.patch {
-				return true, nil
-			}
+func (p *SemVer) Weight() int {
+	return 100
+}
+
+// compare returns a negative number if p has a lower precedence than o, zero if both are equal and a positive number otherwise.
+func (p *SemVer) compare(o *SemVer) int {
+	if p.major != o.major {
+		return p.major - o.major
+	}
+
+	if p.minor != o.minor {
+		return p.minor - o.minor
+	}
+
+	if p.patch != o.patch {
+		return p.patch - o.patch
+	}
+
+	// A release has a higher precedence than its pre-releases.
+	if len(p.preRelease) == 0 || len(o.preRelease) == 0 {
+		return len(o.preRelease) - len(p.preRelease)
+	}
+
+	for idx := 0; idx < len(p.preRelease) && idx < len(o.preRelease); idx++ {
+		result := comparePreReleaseIdentifiers(p.preRelease[idx], o.preRelease[idx])
+		if result != 0 {
+			return result
 		}
 	}
 
-	return false, nil
+	return len(p.preRelease) - len(o.preRelease)
 }
 
-func (p *MajorMinorPatch) String() string {
-	return p.raw
+// comparePreReleaseIdentifiers compares numeric identifiers numerically and other identifiers in ASCII sort order.
+// Numeric identifiers have a lower precedence than other identifiers.
+func comparePreReleaseIdentifiers(a, b string) int {
+	aInt, aErr := strconv.Atoi(a)
+	bInt, bErr := strconv.Atoi(b)
+	switch {
+	case aErr == nil && bErr == nil:
+		return aInt - bInt
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+
+	return strings.Compare(a, b)
 }
 
-func (p *MajorMinorPatch) Weight() int {
-	return 100
+// parsePreRelease splits a pre-release into its identifiers, e.g. "rc" and "1" of "rc.1".
+func parsePreRelease(preRelease string) ([]string, error) {
+	identifiers := strings.Split(preRelease, ".")
+	for _, identifier := range identifiers {
+		if !preReleaseIdentifierRegexp.MatchString(identifier) {
+			return nil, ErrVersionNotSupported
+		}
+	}
+
+	return identifiers, nil
 }
 
-func majorMinorPatchFactory(version string) (VersionParser, error) {
-	matches := majorMinorPatchRegexp.FindStringSubmatch(version)
-	matchCount := len(matches)
-	if matchCount == 0 {
-		return nil, ErrVersionNotSupported
+// parseSemVer returns the version without its pre-release and the pre-release, e.g. "rc.1" of "1.2.3-rc.1+build.5".
+func parseSemVer(version string, policy PreReleasePolicy) (*SemVer, string, error) {
+	matches := semVerRegexp.FindStringSubmatch(version)
+	if len(matches) == 0 {
+		return nil, "", ErrVersionNotSupported
 	}
 
 	majorVersionInt, _ := strconv.Atoi(matches[1])
 	minorVersionInt, _ := strconv.Atoi(matches[2])
 	patchVersionInt, _ := strconv.Atoi(matches[3])
-	return &MajorMinorPatch{
-		build: matches[4],
-		major: majorVersionInt,
-		minor: minorVersionInt,
-		patch: patchVersionInt,
-		raw:   version,
-	}, nil
+	return &SemVer{
+		major:    majorVersionInt,
+		metadata: matches[5],
+		minor:    minorVersionInt,
+		patch:    patchVersionInt,
+		policy:   policy,
+		raw:      version,
+	}, matches[4], nil
+}
+
+// newSemVerFactory returns a factory of SemVer version parsers that apply the pre-release policy.
+func newSemVerFactory(policy PreReleasePolicy) func(string) (VersionParser, error) {
+	return func(version string) (VersionParser, error) {
+		vp, preRelease, err := parseSemVer(version, policy)
+		if err != nil {
+			return nil, err
+		}
+
+		if preRelease == "" {
+			return vp, nil
+		}
+
+		vp.preRelease, err = parsePreRelease(preRelease)
+		if err != nil {
+			return nil, err
+		}
+
+		return vp, nil
+	}
+}
+
+// newSemVerSuffixFactory returns a factory of SemVer version parsers for the version in front of a variant.
+// Only suffixes that start with a well-known identifier, e.g. "alpha" or "rc", are pre-releases.
+// Other suffixes, e.g. "-slim", become part of the distinction as with the other version parsers.
+func newSemVerSuffixFactory(policy PreReleasePolicy) func(string) (VersionParser, error) {
+	return func(version string) (VersionParser, error) {
+		vp, preRelease, err := parseSemVer(version, policy)
+		if err != nil {
+			return nil, err
+		}
+
+		if preRelease == "" {
+			return vp, nil
+		}
+
+		// The pre-release ends at the next "-". The remainder is a suffix, e.g. "-slim" of "1.2.3-rc.1-slim".
+		parts := strings.SplitN(preRelease, "-", 2)
+		if !preReleaseRegexp.MatchString(strings.Split(parts[0], ".")[0]) {
+			vp.suffix = "-" + preRelease
+			return vp, nil
+		}
+
+		vp.preRelease, err = parsePreRelease(parts[0])
+		if err != nil {
+			return nil, err
+		}
+
+		if len(parts) == 2 {
+			vp.suffix = "-" + parts[1]
+		}
+
+		return vp, nil
+	}
 }
@@ -31,8 +31,10 @@ func TestMajorMinor(t *testing.T) {
 }
 
 func TestMajorMinorPatch(t *testing.T) {
-	first, _ := majorMinorPatchFactory("1.2.3")
-	second, _ := majorMinorPatchFactory("1.2.4")
+	factory := newSemVerFactory(PreReleaseExclude)
+	first, _ := factory("1.2.3")
+	second, _ := factory("1.2.4")
+	assert.IsType(t, &MajorMinorPatch{}, first)
 	result, err := first.IsGreaterThan(second)
 	assert.NoError(t, err)
 	assert.False(t, result)
@@ -41,3 +43,174 @@ func TestMajorMinorPatch(t *testing.T) {
 	assert.NoError(t, err)
 	assert.True(t, result)
 }
+
+func TestSemVer_Precedence(t *testing.T) {
+	// Ordered from the lowest to the highest precedence, as in the specification of Semantic Versioning 2.0.
+	versions := []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1-0.3.7",
+		"1.0.1-foo.1",
+		"1.0.1-foo.2",
+		"1.0.1-foo.10",
+		"1.0.1-x.7.z.92",
+		"1.0.1",
+		"2.0.0-beta",
+		"2.0.0-m1",
+		"2.0.0-m2",
+		"2.0.0",
+	}
+
+	factory := newSemVerFactory(PreReleaseInclude)
+	for idx := 1; idx < len(versions); idx++ {
+		lower, err := factory(versions[idx-1])
+		assert.NoError(t, err)
+		higher, err := factory(versions[idx])
+		assert.NoError(t, err)
+
+		result, err := higher.IsGreaterThan(lower)
+		assert.NoError(t, err)
+		assert.True(t, result, "%s > %s", versions[idx], versions[idx-1])
+
+		result, err = lower.IsGreaterThan(higher)
+		assert.NoError(t, err)
+		assert.False(t, result, "%s > %s", versions[idx-1], versions[idx])
+	}
+}
+
+func TestSemVer_PreReleasePolicy(t *testing.T) {
+	testcases := []struct {
+		policy          PreReleasePolicy
+		version         string
+		other           string
+		expectedGreater bool
+		testName        string
+	}{
+		{PreReleaseExclude, "2.0.0", "2.1.0-rc.1", true, "Exclude orders releases before newer pre-releases"},
+		{PreReleaseExclude, "2.1.0-rc.1", "2.0.0", false, "Exclude never orders a pre-release before a release"},
+		{PreReleaseExclude, "2.1.0-rc.2", "2.1.0-rc.1", true, "Exclude orders pre-releases by precedence"},
+		{PreReleaseInclude, "2.0.0", "2.1.0-rc.1", false, "Include orders releases by precedence"},
+		{PreReleaseInclude, "2.1.0-rc.1", "2.0.0", true, "Include orders a newer pre-release before a release"},
+		{PreReleaseInclude, "2.1.0", "2.1.0-rc.1", true, "Include orders a release before its pre-releases"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.testName, func(t *testing.T) {
+			factory := newSemVerFactory(tc.policy)
+			vp, err := factory(tc.version)
+			assert.NoError(t, err)
+			other, err := factory(tc.other)
+			assert.NoError(t, err)
+
+			result, err := vp.IsGreaterThan(other)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedGreater, result)
+		})
+	}
+}
+
+func TestSemVer_BuildMetadata(t *testing.T) {
+	factory := newSemVerFactory(PreReleaseInclude)
+	first, err := factory("1.2.3+build.1")
+	assert.NoError(t, err)
+	second, err := factory("1.2.3+build.2")
+	assert.NoError(t, err)
+
+	result, err := second.IsGreaterThan(first)
+	assert.NoError(t, err)
+	assert.False(t, result)
+
+	result, err = first.IsGreaterThan(second)
+	assert.NoError(t, err)
+	assert.False(t, result)
+	assert.Equal(t, first.Distinction(), second.Distinction())
+}
+
+func TestSemVer_Parse(t *testing.T) {
+	testcases := []struct {
+		version             string
+		expectedDistinction string
+		expectedPreRelease  bool
+		testName            string
+	}{
+		{"1.2.3-rc1", "majorMinorPatch", true, "Pre-release"},
+		{"1.2.3-RC.1", "majorMinorPatch", true, "Upper case pre-release"},
+		{"1.2.3-foo.1", "majorMinorPatch", true, "Pre-release with other identifier"},
+		{"2.0.0-m1", "majorMinorPatch", true, "Milestone"},
+		{"1.2.3-rc.1-alpine", "majorMinorPatch", true, "Identifier with hyphen"},
+		{"v1.2.3-beta+exp.sha.5114f85", "majorMinorPatch", true, "Pre-release with build metadata"},
+		{"1.2.3+20190101", "majorMinorPatch", false, "Build metadata"},
+		{"1.0.0-0.3.7", "majorMinorPatch", true, "Numeric identifiers"},
+		{"1.0.0-x.7.z.92", "majorMinorPatch", true, "Mixed identifiers"},
+		{"1.2.3-1", "majorMinorPatch", true, "Numeric identifier"},
+	}
+
+	factory := newSemVerFactory(PreReleaseExclude)
+	for _, tc := range testcases {
+		t.Run(tc.testName, func(t *testing.T) {
+			vp, err := factory(tc.version)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedDistinction, vp.Distinction())
+			assert.Equal(t, tc.expectedPreRelease, vp.(*SemVer).IsPreRelease())
+			assert.Equal(t, tc.version, vp.String())
+		})
+	}
+
+	for _, version := range []string{"1.2.3-rc..1", "1.2.3-rc.01", "1.2.3-rc_1", "19.04.1", "01.2.3", "1.2.03"} {
+		_, err := factory(version)
+		assert.Equal(t, ErrVersionNotSupported, err, version)
+	}
+
+	_, err := majorMinorFactory("19.04")
+	assert.Equal(t, ErrVersionNotSupported, err, "Leading zero")
+}
+
+func TestSemVerSuffix_Parse(t *testing.T) {
+	testcases := []struct {
+		version             string
+		expectedDistinction string
+		expectedPreRelease  bool
+		testName            string
+	}{
+		{"1.2.3-rc1", "majorMinorPatch", true, "Pre-release"},
+		{"1.2.3-slim", "majorMinorPatch-slim", false, "Suffix"},
+		{"1.2.3-rc.1-slim", "majorMinorPatch-slim", true, "Pre-release with suffix"},
+		{"1.2.3-slim+20190101", "majorMinorPatch-slim", false, "Suffix with build metadata"},
+		{"1.0.0-0.3.7", "majorMinorPatch-0.3.7", false, "Numeric identifiers are a suffix"},
+		{"1.0.0-x.7.z.92", "majorMinorPatch-x.7.z.92", false, "Unknown identifiers are a suffix"},
+		{"1.2.3-1", "majorMinorPatch-1", false, "Revision"},
+	}
+
+	factory := newSemVerSuffixFactory(PreReleaseExclude)
+	for _, tc := range testcases {
+		t.Run(tc.testName, func(t *testing.T) {
+			vp, err := factory(tc.version)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedDistinction, vp.Distinction())
+			assert.Equal(t, tc.expectedPreRelease, vp.(*SemVer).IsPreRelease())
+			assert.Equal(t, tc.version, vp.String())
+		})
+	}
+
+	_, err := factory("1.2.3-rc..1")
+	assert.Equal(t, ErrVersionNotSupported, err)
+}
+
+func TestParsePreReleasePolicy(t *testing.T) {
+	policy, err := ParsePreReleasePolicy("include")
+	assert.NoError(t, err)
+	assert.Equal(t, PreReleaseInclude, policy)
+
+	policy, err = ParsePreReleasePolicy("exclude")
+	assert.NoError(t, err)
+	assert.Equal(t, PreReleaseExclude, policy)
+
+	_, err = ParsePreReleasePolicy("sometimes")
+	assert.Error(t, err)
+}
@@ -0,0 +1,71 @@
+package versionparser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+const buildTimeLayout = "20060102150405"
+
+// timestampRegexp matches a Unix timestamp in seconds or a build ID in the format YYYYMMDDHHMMSS, optionally prefixed by a name, e.g. "1553601600" or "nightly-20190326120000".
+var timestampRegexp = regexp.MustCompile("^(?:(\\w+)-)?(\\d{10}|\\d{14})$")
+
+// Timestamp is a version that is the time of a build.
+// Unix timestamps and build IDs share a distinction because both are compared by the time they represent.
+type Timestamp struct {
+	name string
+	raw  string
+	time time.Time
+}
+
+func (p *Timestamp) Distinction() string {
+	if p.name == "" {
+		return "timestamp"
+	}
+
+	return fmt.Sprintf("timestamp-%s", p.name)
+}
+
+func (p *Timestamp) IsGreaterThan(other VersionParser) (bool, error) {
+	o, ok := other.(*Timestamp)
+	if !ok {
+		return false, ErrWrongVPType
+	}
+
+	return p.time.After(o.time), nil
+}
+
+func (p *Timestamp) String() string {
+	return p.raw
+}
+
+func (p *Timestamp) Weight() int {
+	return 75
+}
+
+func timestampFactory(version string) (VersionParser, error) {
+	matches := timestampRegexp.FindStringSubmatch(version)
+	if len(matches) == 0 {
+		return nil, ErrVersionNotSupported
+	}
+
+	var t time.Time
+	if len(matches[2]) == len(buildTimeLayout) {
+		var err error
+		t, err = time.Parse(buildTimeLayout, matches[2])
+		if err != nil {
+			return nil, ErrVersionNotSupported
+		}
+	} else {
+		seconds, _ := strconv.ParseInt(matches[2], 10, 64)
+		t = time.Unix(seconds, 0).UTC()
+	}
+
+	return &Timestamp{
+		name: matches[1],
+		raw:  version,
+		time: t,
+	}, nil
+}
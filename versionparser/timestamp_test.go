@@ -0,0 +1,65 @@
+package versionparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimestamp_Parse(t *testing.T) {
+	testcases := []struct {
+		version             string
+		expectedDistinction string
+		expectedSupported   bool
+		testName            string
+	}{
+		{"1553601600", "timestamp", true, "Unix timestamp"},
+		{"20190326120000", "timestamp", true, "Build ID"},
+		{"nightly-20190326120000", "timestamp-nightly", true, "Build ID with name"},
+		{"nightly-1553601600", "timestamp-nightly", true, "Unix timestamp with name"},
+		{"20191326120000", "", false, "Build ID with invalid month"},
+		{"20190326", "", false, "Date"},
+		{"155360160", "", false, "Too short"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.testName, func(t *testing.T) {
+			vp, err := timestampFactory(tc.version)
+			if !tc.expectedSupported {
+				assert.Equal(t, ErrVersionNotSupported, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedDistinction, vp.Distinction())
+			assert.Equal(t, tc.version, vp.String())
+		})
+	}
+}
+
+func TestTimestamp_IsGreaterThan(t *testing.T) {
+	testcases := []struct {
+		version         string
+		other           string
+		expectedGreater bool
+	}{
+		{"1553601601", "1553601600", true},
+		{"1553601600", "1553601601", false},
+		{"20190326120001", "20190326120000", true},
+		{"20190327000000", "20190326235959", true},
+		{"20190326120001", "1553601600", true},
+		{"1553601600", "20190326120000", false},
+	}
+
+	for _, tc := range testcases {
+		vp, err := timestampFactory(tc.version)
+		require.NoError(t, err)
+		other, err := timestampFactory(tc.other)
+		require.NoError(t, err)
+
+		result, err := vp.IsGreaterThan(other)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expectedGreater, result, "%s > %s", tc.version, tc.other)
+	}
+}
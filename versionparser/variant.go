@@ -0,0 +1,128 @@
+package versionparser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// variantRegexp splits a tag into the version, the family of the variant, the version of the variant and a suffix, e.g. "1.2.3", "alpine", "3.10" and "-slim".
+// Only families of operating systems are variants. Other suffixes, e.g. "-jdk11", stay part of the version.
+var variantRegexp = regexp.MustCompile("^(.+?)-(alpine|amazonlinux|centos|debian|fedora|opensuse|rockylinux|ubi|ubuntu)(\\d+(?:\\.\\d+)*)?(-.*)?$")
+
+// Variant is a version that is built on a versioned base image, e.g. "1.2.3-alpine3.10".
+// All versions of the same family of a variant share a distinction, e.g. "majorMinorPatch-alpine".
+// They are ordered by their version first and by the version of the variant second.
+type Variant struct {
+	family         string
+	raw            string
+	suffix         string
+	variantRaw     string
+	variantVersion []int
+	version        VersionParser
+}
+
+func (p *Variant) Distinction() string {
+	return fmt.Sprintf("%s-%s%s", p.version.Distinction(), p.family, p.suffix)
+}
+
+func (p *Variant) IsGreaterThan(other VersionParser) (bool, error) {
+	o, ok := other.(*Variant)
+	if !ok || o.family != p.family {
+		return false, ErrWrongVPType
+	}
+
+	isGreater, err := p.IsVersionGreaterThan(o)
+	if err != nil || isGreater {
+		return isGreater, err
+	}
+
+	isLower, err := o.IsVersionGreaterThan(p)
+	if err != nil || isLower {
+		return false, err
+	}
+
+	return p.IsVariantGreaterThan(o)
+}
+
+// IsVariantGreaterThan compares the versions of the variants, e.g. "3.10" of "1.2.3-alpine3.10" and "3.9" of "1.2.4-alpine3.9".
+// A variant without a version, e.g. "1.2.3-alpine", is lower than every variant with a version.
+func (p *Variant) IsVariantGreaterThan(other VersionParser) (bool, error) {
+	o, ok := other.(*Variant)
+	if !ok || o.family != p.family {
+		return false, ErrWrongVPType
+	}
+
+	for idx := 0; idx < len(p.variantVersion) && idx < len(o.variantVersion); idx++ {
+		if p.variantVersion[idx] != o.variantVersion[idx] {
+			return p.variantVersion[idx] > o.variantVersion[idx], nil
+		}
+	}
+
+	return len(p.variantVersion) > len(o.variantVersion), nil
+}
+
+// IsVersionGreaterThan compares the versions without their variants, e.g. "1.2.3" of "1.2.3-alpine3.10" and "1.2.4" of "1.2.4-alpine3.9".
+func (p *Variant) IsVersionGreaterThan(other VersionParser) (bool, error) {
+	o, ok := other.(*Variant)
+	if !ok {
+		return false, ErrWrongVPType
+	}
+
+	return p.version.IsGreaterThan(o.version)
+}
+
+func (p *Variant) String() string {
+	return p.raw
+}
+
+// Variant returns the family of the variant and its version, e.g. "alpine" and "3.10".
+func (p *Variant) Variant() (string, string) {
+	return p.family, p.variantRaw
+}
+
+func (p *Variant) Weight() int {
+	return p.version.Weight()
+}
+
+// newVariantFactory returns a factory of Variant version parsers.
+// The version in front of the variant is parsed by the first of the factories that supports it.
+func newVariantFactory(factories []func(string) (VersionParser, error)) func(string) (VersionParser, error) {
+	return func(version string) (VersionParser, error) {
+		matches := variantRegexp.FindStringSubmatch(version)
+		if len(matches) == 0 {
+			return nil, ErrVersionNotSupported
+		}
+
+		var base VersionParser
+		for _, fac := range factories {
+			vp, err := fac(matches[1])
+			if err == nil {
+				base = vp
+				break
+			}
+		}
+
+		if base == nil {
+			return nil, ErrVersionNotSupported
+		}
+
+		vp := &Variant{
+			family:         matches[2],
+			raw:            version,
+			suffix:         matches[4],
+			variantRaw:     matches[3],
+			variantVersion: []int{},
+			version:        base,
+		}
+		if matches[3] != "" {
+			for _, part := range strings.Split(matches[3], ".") {
+				v, _ := strconv.Atoi(part)
+				vp.variantVersion = append(vp.variantVersion, v)
+			}
+		}
+
+		return vp, nil
+	}
+}
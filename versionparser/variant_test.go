@@ -0,0 +1,77 @@
+package versionparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVariant_Parse(t *testing.T) {
+	testcases := []struct {
+		version                string
+		expectedDistinction    string
+		expectedFamily         string
+		expectedVariantVersion string
+		testName               string
+	}{
+		{"1.2.3-alpine3.10", "majorMinorPatch-alpine", "alpine", "3.10", "MajorMinorPatch with variant"},
+		{"1.2-alpine3.9", "majorMinor-alpine", "alpine", "3.9", "MajorMinor with variant"},
+		{"v1-ubuntu18.04", "major-ubuntu", "ubuntu", "18.04", "Major with v prefix and variant"},
+		{"1.2.3-alpine", "majorMinorPatch-alpine", "alpine", "", "Variant without version"},
+		{"1.2.3-alpine3.10-slim", "majorMinorPatch-alpine-slim", "alpine", "3.10", "Variant with suffix"},
+		{"1.2.3-rc.1-alpine3.10", "majorMinorPatch-alpine", "alpine", "3.10", "Pre-release with variant"},
+		{"1.2.3-slim-debian10", "majorMinorPatch-slim-debian", "debian", "10", "Suffix in front of variant"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.testName, func(t *testing.T) {
+			vp := Registry.FindForVersion(tc.version)
+			require.IsType(t, &Variant{}, vp)
+			assert.Equal(t, tc.expectedDistinction, vp.Distinction())
+			assert.Equal(t, tc.version, vp.String())
+			family, variantVersion := vp.(*Variant).Variant()
+			assert.Equal(t, tc.expectedFamily, family)
+			assert.Equal(t, tc.expectedVariantVersion, variantVersion)
+		})
+	}
+
+	assert.IsType(t, &SemVer{}, Registry.FindForVersion("1.2.3-jdk11"))
+	assert.IsType(t, &NameDate{}, Registry.FindForVersion("ubuntu-20180913"))
+}
+
+func TestVariant_IsGreaterThan(t *testing.T) {
+	testcases := []struct {
+		version         string
+		other           string
+		expectedGreater bool
+		expectedVersion bool
+		expectedVariant bool
+	}{
+		{"1.2.3-alpine3.10", "1.2.3-alpine3.9", true, false, true},
+		{"1.2.3-alpine3.9", "1.2.3-alpine3.10", false, false, false},
+		{"1.2.4-alpine3.9", "1.2.3-alpine3.10", true, true, false},
+		{"1.2.4-alpine3.10", "1.2.3-alpine3.9", true, true, true},
+		{"1.2.3-alpine3.10", "1.2.3-alpine", true, false, true},
+		{"1.2.3-alpine3.10", "1.2.3-alpine3.10", false, false, false},
+	}
+
+	for _, tc := range testcases {
+		vp := Registry.FindForVersion(tc.version).(*Variant)
+		other := Registry.FindForVersion(tc.other).(*Variant)
+		result, err := vp.IsGreaterThan(other)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expectedGreater, result, "%s > %s", tc.version, tc.other)
+
+		result, err = vp.IsVersionGreaterThan(other)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expectedVersion, result, "version of %s > %s", tc.version, tc.other)
+
+		result, err = vp.IsVariantGreaterThan(other)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expectedVariant, result, "variant of %s > %s", tc.version, tc.other)
+	}
+
+	_, err := Registry.FindForVersion("1.2.3-alpine3.10").IsGreaterThan(Registry.FindForVersion("1.2.3-debian10"))
+	assert.Equal(t, ErrWrongVPType, err)
+}
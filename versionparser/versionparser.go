@@ -2,6 +2,8 @@ package versionparser
 
 import (
 	"errors"
+	"fmt"
+	"path"
 )
 
 var (
@@ -10,6 +12,29 @@ var (
 	ErrVersionNotSupported = errors.New("Version Parser does not support this version string")
 )
 
+// PreReleasePolicy controls if a pre-release of a semantic version can become the latest version of its distinction.
+type PreReleasePolicy int
+
+const (
+	// PreReleaseExclude orders every release before every pre-release.
+	// A pre-release becomes the latest version only if its distinction contains no release.
+	PreReleaseExclude PreReleasePolicy = iota
+	// PreReleaseInclude orders pre-releases by their precedence, e.g. "2.1.0-rc.1" is greater than "2.0.0".
+	PreReleaseInclude
+)
+
+// ParsePreReleasePolicy parses "exclude" or "include".
+func ParsePreReleasePolicy(policy string) (PreReleasePolicy, error) {
+	switch policy {
+	case "exclude":
+		return PreReleaseExclude, nil
+	case "include":
+		return PreReleaseInclude, nil
+	default:
+		return PreReleaseExclude, fmt.Errorf("unknown pre-release policy '%s'", policy)
+	}
+}
+
 type VersionParser interface {
 	Distinction() string
 	IsGreaterThan(other VersionParser) (bool, error)
@@ -19,6 +44,7 @@ type VersionParser interface {
 
 type DefaultRegistry struct {
 	factories []func(string) (VersionParser, error)
+	ignore    []string
 }
 
 func (d *DefaultRegistry) FindForVersion(version string) VersionParser {
@@ -32,16 +58,59 @@ func (d *DefaultRegistry) FindForVersion(version string) VersionParser {
 	return &Unknown{raw: version}
 }
 
+// IsIgnored returns true if a tag matches one of the ignore patterns of the registry.
+func (d *DefaultRegistry) IsIgnored(tag string) bool {
+	for _, pattern := range d.ignore {
+		ok, _ := path.Match(pattern, tag)
+		if ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Opts configures the version parsers of a registry.
+type Opts struct {
+	PreReleasePolicy PreReleasePolicy
+}
+
+type namedFactory struct {
+	factory func(string) (VersionParser, error)
+	name    string
+}
+
+// builtInFactories returns the built-in parsers in the order in which they are tried by default.
+// The name of a parser equals the prefix of its distinctions, except for variants which are prefixed by the parser of their version
+// and calVerShortYear which shares the prefix "calVer".
+func builtInFactories(o Opts) []namedFactory {
+	semVerFactory := newSemVerFactory(o.PreReleasePolicy)
+	return []namedFactory{
+		{factory: newVariantFactory([]func(string) (VersionParser, error){calVerFactory, majorFactory, majorMinorFactory, newSemVerSuffixFactory(o.PreReleasePolicy), calVerShortYearFactory}), name: "variant"},
+		{factory: timestampFactory, name: "timestamp"},
+		{factory: calVerFactory, name: "calVer"},
+		{factory: majorFactory, name: "major"},
+		{factory: majorMinorFactory, name: "majorMinor"},
+		{factory: semVerFactory, name: "majorMinorPatch"},
+		{factory: calVerShortYearFactory, name: "calVerShortYear"},
+		{factory: nameDateFactory, name: "nameDate"},
+		{factory: staticFactory, name: "static"},
+		{factory: gitSHAFactory, name: "gitSHA"},
+		{factory: buildNumberFactory, name: "buildNumber"},
+	}
+}
+
 func NewDefaultRegistry() *DefaultRegistry {
-	return &DefaultRegistry{
-		factories: []func(string) (VersionParser, error){
-			majorFactory,
-			majorMinorFactory,
-			majorMinorPatchFactory,
-			nameDateFactory,
-			staticFactory,
-		},
+	return NewRegistry(Opts{})
+}
+
+func NewRegistry(o Opts) *DefaultRegistry {
+	reg := &DefaultRegistry{}
+	for _, f := range builtInFactories(o) {
+		reg.factories = append(reg.factories, f.factory)
 	}
+
+	return reg
 }
 
 var Registry *DefaultRegistry = NewDefaultRegistry()
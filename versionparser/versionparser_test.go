@@ -25,7 +25,15 @@ func TestRegistry(t *testing.T) {
 		{"1.2.3-alpine", "majorMinorPatch-alpine", "1.2.3-alpine", "MajorMinorPatch with build suffix"},
 		{"v1.2.3", "majorMinorPatch", "v1.2.3", "MajorMinorPatch with v prefix"},
 		{"v1.2.3-alpine", "majorMinorPatch-alpine", "v1.2.3-alpine", "MajorMinorPatch with v prefix and build suffix"},
+		{"1.2.3-rc.1", "majorMinorPatch", "1.2.3-rc.1", "MajorMinorPatch with pre-release"},
+		{"1.2.3-beta2-alpine", "majorMinorPatch-alpine", "1.2.3-beta2-alpine", "MajorMinorPatch with pre-release and build suffix"},
 		{"ubuntu-20180913", "nameDate-ubuntu", "ubuntu-20180913", "NameDate"},
+		{"ubuntu-2018-09-13", "nameDate-ubuntu", "ubuntu-2018-09-13", "NameDate with dashes"},
+		{"2019.04", "calVer", "2019.04", "CalVer"},
+		{"19.04.1", "calVer", "19.04.1", "CalVer with short year"},
+		{"19.10.1", "majorMinorPatch", "19.10.1", "CalVer with short year and a semantic version"},
+		{"1553601600", "timestamp", "1553601600", "Unix timestamp"},
+		{"20190326120000", "timestamp", "20190326120000", "Build ID"},
 		{"latest", "static-latest", "latest", "Static latest"},
 		{"mainline", "static-mainline", "mainline", "Static mainline"},
 		{"master", "static-master", "master", "Static master"},
@@ -3,7 +3,7 @@ package web
 import (
 	"encoding/json"
 	"net/http"
-	"sync"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
@@ -13,6 +13,7 @@ import (
 
 	"github.com/imagespy/api/versionparser"
 
+	"github.com/imagespy/api/queue"
 	"github.com/imagespy/api/registry"
 	"github.com/imagespy/api/scrape"
 	"github.com/imagespy/api/store"
@@ -42,7 +43,17 @@ type imageSerialize struct {
 	Digest      string                `json:"digest"`
 	LatestImage *latestImageSerialize `json:"latest_image"`
 	Name        string                `json:"name"`
+	Platforms   []*platformSerialize  `json:"platforms,omitempty"`
 	Tags        []string              `json:"tags"`
+	Updates     *updatesSerialize     `json:"updates,omitempty"`
+}
+
+type platformSerialize struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	OSVersion    string `json:"os_version"`
+	Size         int64  `json:"size"`
+	Variant      string `json:"variant"`
 }
 
 type latestImageSerialize struct {
@@ -51,8 +62,30 @@ type latestImageSerialize struct {
 	Tags   []string `json:"tags"`
 }
 
+// updatesSerialize tells what the latest image updates compared to the requested tag.
+type updatesSerialize struct {
+	// Variant is true if the latest image is built on a newer version of the base image, e.g. "alpine3.10" instead of "alpine3.9".
+	Variant bool `json:"variant"`
+	// Version is true if the latest image has a newer version, not considering its variant.
+	Version bool `json:"version"`
+}
+
+type platformConfigSerialize struct {
+	Architecture string            `json:"architecture"`
+	Created      time.Time         `json:"created"`
+	Entrypoint   []string          `json:"entrypoint"`
+	Env          []string          `json:"env"`
+	ExposedPorts []string          `json:"exposed_ports"`
+	Labels       map[string]string `json:"labels"`
+	OS           string            `json:"os"`
+	OSVersion    string            `json:"os_version"`
+	User         string            `json:"user"`
+	Variant      string            `json:"variant"`
+}
+
 type imageHandler struct {
 	registry   registry.Registry
+	rules      *versionparser.Rules
 	serializer func(interface{}) ([]byte, error)
 	scraper    scrape.Scraper
 	Store      store.Store
@@ -139,12 +172,32 @@ func (h *imageHandler) getImage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	vps := h.rules.ForRepository(address + "/" + path)
+	currentVP := vps.FindForVersion(tagInput)
+	// The requested tag keeps the distinction it has been stored with, even if the version parsers changed since.
+	distinction := currentVP.Distinction()
+	for _, t := range tags {
+		if t.Name == tagInput {
+			distinction = t.Distinction
+		}
+	}
+
 	isLatestTag := true
 	latestImage, err := h.Store.Images().Get(store.ImageGetOptions{
 		Name:           address + "/" + path,
-		TagDistinction: versionparser.FindForVersion(tagInput).Distinction(),
+		TagDistinction: distinction,
 		TagIsLatest:    &isLatestTag,
 	})
+	if err == store.ErrDoesNotExist && distinction != currentVP.Distinction() {
+		// The latest image of a former distinction has been scraped with the current version parsers.
+		distinction = currentVP.Distinction()
+		latestImage, err = h.Store.Images().Get(store.ImageGetOptions{
+			Name:           address + "/" + path,
+			TagDistinction: distinction,
+			TagIsLatest:    &isLatestTag,
+		})
+	}
+
 	if err != nil {
 		log.Errorf("reading latest image: %s", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -158,7 +211,25 @@ func (h *imageHandler) getImage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	platforms, err := h.Store.Platforms().List(store.PlatformListOptions{ImageID: image.ID})
+	if err != nil {
+		log.Errorf("reading platforms of current image: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
 	serialization := convertImageToResult(image, tags, latestImage, latestTags)
+	serialization.Updates = findUpdates(currentVP, distinction, latestTags, vps)
+	for _, p := range platforms {
+		serialization.Platforms = append(serialization.Platforms, &platformSerialize{
+			Architecture: p.Architecture,
+			OS:           p.OS,
+			OSVersion:    p.OSVersion,
+			Size:         p.Size,
+			Variant:      p.Variant,
+		})
+	}
+
 	b, err := h.serializer(serialization)
 	if err != nil {
 		log.Errorf("serializing image, latest image and tags: %s", err)
@@ -224,33 +295,41 @@ func (h *imageHandler) getImageLayers(w http.ResponseWriter, r *http.Request) {
 	}
 
 	result := []*layerSerialize{}
-	layersClient := h.Store.Layers()
-	for _, lp := range layerPositions {
-		layer, err := layersClient.Get(store.LayerGetOptions{ID: lp.LayerID})
+	if len(layerPositions) > 0 {
+		layerIDs := []int{}
+		for _, lp := range layerPositions {
+			layerIDs = append(layerIDs, lp.LayerID)
+		}
+
+		layers, err := h.Store.Layers().List(store.LayerListOptions{IDs: layerIDs})
 		if err != nil {
-			log.Errorf("layersHandler.layers: reading layer of position '%d': %s", lp.ID, err)
+			log.Errorf("layersHandler.layers: reading layers of platform '%d': %s", platform.ID, err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
-		serialization := &layerSerialize{Digest: layer.Digest}
-		for _, sourceImageID := range layer.SourceImageIDs {
-			sourceImage, sourceImageTags, latestImage, latestTags, err := findSourceImageOfLayer(sourceImageID, h.Store)
-			if err != nil {
-				log.Errorf("layersHandler.layers: reading source image '%d': %s", sourceImageID, err)
+		sourceImages, err := findSourceImagesOfLayers(layers, h.Store, h.rules)
+		if err != nil {
+			log.Errorf("layersHandler.layers: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		layersByID := map[int]*store.Layer{}
+		for _, l := range layers {
+			layersByID[l.ID] = l
+		}
+
+		for _, lp := range layerPositions {
+			layer, ok := layersByID[lp.LayerID]
+			if !ok {
+				log.Errorf("layersHandler.layers: layer of position '%d' does not exist", lp.ID)
 				w.WriteHeader(http.StatusInternalServerError)
 				return
 			}
 
-			if sourceImage == nil {
-				continue
-			}
-
-			imageSerialization := convertImageToResult(sourceImage, sourceImageTags, latestImage, latestTags)
-			serialization.SourceImages = append(serialization.SourceImages, imageSerialization)
+			result = append(result, convertLayerToResultWithSourceImages(layer, sourceImages))
 		}
-
-		result = append(result, serialization)
 	}
 
 	b, err := h.serializer(result)
@@ -266,6 +345,76 @@ func (h *imageHandler) getImageLayers(w http.ResponseWriter, r *http.Request) {
 	w.Write(b)
 }
 
+func (h *imageHandler) getImageConfig(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	imageID := vars["name"]
+	address, path, tagInput, _, err := registry.ParseImage(imageID)
+	if err != nil {
+		log.Errorf("imageHandler.getImageConfig: parsing image name: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	image, err := h.Store.Images().Get(store.ImageGetOptions{
+		Name:    address + "/" + path,
+		TagName: tagInput,
+	})
+	if err != nil {
+		if err == store.ErrDoesNotExist {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		log.Errorf("imageHandler.getImageConfig: reading image: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	platform, err := h.Store.Platforms().Get(store.PlatformGetOptions{
+		Architecture: getQueryParam(r, "arch", "amd64"),
+		ImageID:      image.ID,
+		OS:           getQueryParam(r, "os", "linux"),
+		OSVersion:    getQueryParamOrNil(r, "os_version"),
+		Variant:      getQueryParamOrNil(r, "variant"),
+	})
+	if err != nil {
+		if err == store.ErrDoesNotExist {
+			log.Info("imageHandler.getImageConfig: platform does not exist")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		log.Errorf("imageHandler.getImageConfig: reading platform of image '%d': %s", image.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	config, err := h.Store.Platforms().GetConfig(store.PlatformConfigGetOptions{PlatformID: platform.ID})
+	if err != nil {
+		if err == store.ErrDoesNotExist {
+			log.Infof("imageHandler.getImageConfig: config of platform '%d' does not exist", platform.ID)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		log.Errorf("imageHandler.getImageConfig: reading config of platform '%d': %s", platform.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	b, err := h.serializer(convertPlatformConfigToResult(platform, config))
+	if err != nil {
+		log.Errorf("imageHandler.getImageConfig: serializing result: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	addCacheHeaders(w)
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
 func (h *imageHandler) getChildren(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	imageID := vars["name"]
@@ -334,18 +483,29 @@ func (h *imageHandler) getChildren(w http.ResponseWriter, r *http.Request) {
 	}
 
 	apiResult := []*latestImageSerialize{}
-	for _, ci := range childImages {
+	tagsByImageID := map[int][]*store.Tag{}
+	if len(childImages) > 0 {
+		childImageIDs := []int{}
+		for _, ci := range childImages {
+			childImageIDs = append(childImageIDs, ci.ID)
+		}
+
 		tagged := true
 		tags, err := h.Store.Tags().List(store.TagListOptions{
-			ImageID:  ci.ID,
+			ImageIDs: childImageIDs,
 			IsTagged: &tagged,
 		})
 		if err != nil {
-			log.Errorf("imageHandler.getChildren: finding images tags for image '%d': %s", ci.ID, err)
+			log.Errorf("imageHandler.getChildren: finding tags of child images of image '%d': %s", image.ID, err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
+		tagsByImageID = groupTagsByImageID(tags)
+	}
+
+	for _, ci := range childImages {
+		tags := tagsByImageID[ci.ID]
 		if len(tags) == 0 {
 			continue
 		}
@@ -396,33 +556,103 @@ func convertImageToResult(image *store.Image, tags []*store.Tag, latestImage *st
 	return imageSerialized
 }
 
-func Init(registry registry.Registry, scraper scrape.Scraper, store store.Store) http.Handler {
+func convertLayerToResult(l *store.Layer) *layerSerialize {
+	return &layerSerialize{
+		Digest:    l.Digest,
+		MediaType: l.MediaType,
+		Size:      l.Size,
+	}
+}
+
+// convertLayerToResultWithSourceImages adds the source images of a layer, as returned by findSourceImagesOfLayers, to the serialization of the layer.
+func convertLayerToResultWithSourceImages(l *store.Layer, sourceImages map[int]*imageSerialize) *layerSerialize {
+	serialization := convertLayerToResult(l)
+	for _, sourceImageID := range l.SourceImageIDs {
+		if sourceImage, ok := sourceImages[sourceImageID]; ok {
+			serialization.SourceImages = append(serialization.SourceImages, sourceImage)
+		}
+	}
+
+	return serialization
+}
+
+func convertPlatformConfigToResult(p *store.Platform, c *store.PlatformConfig) *platformConfigSerialize {
+	result := &platformConfigSerialize{
+		Architecture: p.Architecture,
+		Created:      p.Created,
+		Entrypoint:   []string{},
+		Env:          []string{},
+		ExposedPorts: []string{},
+		Labels:       map[string]string{},
+		OS:           p.OS,
+		OSVersion:    p.OSVersion,
+		User:         c.User,
+		Variant:      p.Variant,
+	}
+	result.Entrypoint = append(result.Entrypoint, c.Entrypoint...)
+	result.Env = append(result.Env, c.Env...)
+	result.ExposedPorts = append(result.ExposedPorts, c.ExposedPorts...)
+	for k, v := range c.Labels {
+		result.Labels[k] = v
+	}
+
+	return result
+}
+
+func Init(registry registry.Registry, scraper scrape.Scraper, store store.Store, q queue.Queue, webhookOpts WebhookOpts, rules *versionparser.Rules) http.Handler {
 	h := &imageHandler{
 		registry:   registry,
+		rules:      rules,
 		serializer: json.Marshal,
 		scraper:    scraper,
 		Store:      store,
 	}
 
-	rh := &registryHandler{
-		eventDedup:      map[string]struct{}{},
-		eventDedupMutex: &sync.RWMutex{},
-		registry:        registry,
-		scraper:         scraper,
+	jh := &jobsHandler{
+		serializer:  json.Marshal,
+		store:       store,
+		webhookOpts: webhookOpts,
+	}
+
+	wh := &webhookHandler{
+		opts:  webhookOpts,
+		queue: q,
 	}
 
 	lh := &layersHandler{
+		rules:      rules,
 		serializer: json.Marshal,
 		store:      store,
 	}
 
+	rh := &repositoriesHandler{
+		serializer: json.Marshal,
+		store:      store,
+	}
+
+	reh := &reportsHandler{
+		queue:       q,
+		rules:       rules,
+		serializer:  json.Marshal,
+		store:       store,
+		webhookOpts: webhookOpts,
+	}
+
 	r := mux.NewRouter()
 	r.HandleFunc(`/v2/images/{name:[a-zA-Z0-9\/\.\-:_]+}/children`, wrapPrometheus("/v2/images/{name}/children", h.getChildren)).Methods("GET")
+	r.HandleFunc(`/v2/images/{name:[a-zA-Z0-9\/\.\-:_]+}/config`, wrapPrometheus("/v2/images/{name}/config", h.getImageConfig)).Methods("GET")
 	r.HandleFunc(`/v2/images/{name:[a-zA-Z0-9\/\.\-:_]+}/layers`, wrapPrometheus("/v2/images/{name}/layers", h.getImageLayers)).Methods("GET")
+	r.HandleFunc(`/v2/images/{name:[a-zA-Z0-9\/\.\-:_]+}/tag-history`, wrapPrometheus("/v2/images/{name}/tag-history", h.getTagHistory)).Methods("GET")
 	r.HandleFunc(`/v2/images/{name:[a-zA-Z0-9\/\.\-:_]+}`, wrapPrometheus("/v2/images/{name}", h.createImage)).Methods("POST")
 	r.HandleFunc(`/v2/images/{name:[a-zA-Z0-9\/\.\-:_]+}`, wrapPrometheus("/v2/images/{name}", h.getImage)).Methods("GET")
+	r.HandleFunc("/v2/jobs/{id:[0-9]+}", wrapPrometheus("/v2/jobs/{id}", jh.getJob)).Methods("GET")
+	r.HandleFunc("/v2/jobs", wrapPrometheus("/v2/jobs", jh.listJobs)).Methods("GET")
 	r.HandleFunc("/v2/layers/{digest}", wrapPrometheus("/v2/layers/{digest}", lh.layers)).Methods("GET")
-	r.HandleFunc("/dockerRegistry/event", wrapPrometheus("/dockerRegistry/event", rh.registryEvent)).Methods("POST")
+	r.HandleFunc("/v2/reports/outdated", wrapPrometheus("/v2/reports/outdated", reh.outdated)).Methods("POST")
+	r.HandleFunc("/v2/repositories", wrapPrometheus("/v2/repositories", rh.list)).Methods("GET")
+	r.HandleFunc(`/v2/repositories/{name:[a-zA-Z0-9\/\.\-_]+}/images`, wrapPrometheus("/v2/repositories/{name}/images", rh.images)).Methods("GET")
+	r.HandleFunc("/webhooks/{provider}", wrapPrometheus("/webhooks/{provider}", webhookOpts.authenticate(wh.handleProvider))).Methods("POST")
+	r.HandleFunc("/dockerRegistry/event", wrapPrometheus("/dockerRegistry/event", webhookOpts.authenticate(wh.handleRegistryEvent))).Methods("POST")
 	r.Handle("/metrics", promhttp.Handler()).Methods("GET")
 	return r
 }
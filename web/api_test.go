@@ -0,0 +1,56 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/imagespy/api/store/memory"
+	"github.com/imagespy/api/versionparser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageHandler_getImage(t *testing.T) {
+	s := memory.New()
+	createTestImage(t, s, "unit.test/app", "sha256:100", testCreated, testTag{distinction: "majorMinorPatch", isTagged: true, name: "1.0.0"})
+	createTestImage(t, s, "unit.test/app", "sha256:101", testCreated.Add(time.Hour), testTag{distinction: "majorMinorPatch", isLatest: true, isTagged: true, name: "1.0.1"})
+	createTestImage(t, s, "unit.test/app", "sha256:200", testCreated, testTag{distinction: "majorMinorPatch-alpine", isTagged: true, name: "1.2.3-alpine3.9"})
+	createTestImage(t, s, "unit.test/app", "sha256:201", testCreated.Add(time.Hour), testTag{distinction: "majorMinorPatch-alpine", isLatest: true, isTagged: true, name: "1.2.3-alpine3.10"})
+	// Tags scraped by earlier version parsers keep the distinction they have been stored with.
+	createTestImage(t, s, "unit.test/app", "sha256:300", testCreated, testTag{distinction: "majorMinorPatch-alpine3.8", isTagged: true, name: "1.2.2-alpine3.8"})
+	createTestImage(t, s, "unit.test/app", "sha256:400", testCreated, testTag{distinction: "majorMinorPatch-rc1", isLatest: true, isTagged: true, name: "1.3.0-rc1"})
+	h := &imageHandler{rules: versionparser.NewRules(versionparser.Opts{}), serializer: json.Marshal, Store: s}
+
+	testcases := []struct {
+		name                 string
+		tag                  string
+		expectedLatestDigest string
+		expectedUpdates      *updatesSerialize
+	}{
+		{"outdated version", "1.0.0", "sha256:101", &updatesSerialize{Version: true}},
+		{"latest version", "1.0.1", "sha256:101", nil},
+		{"outdated variant", "1.2.3-alpine3.9", "sha256:201", &updatesSerialize{Variant: true}},
+		{"latest variant", "1.2.3-alpine3.10", "sha256:201", &updatesSerialize{}},
+		{"former distinction without a latest tag", "1.2.2-alpine3.8", "sha256:201", &updatesSerialize{Variant: true, Version: true}},
+		{"former distinction with a latest tag", "1.3.0-rc1", "sha256:400", nil},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			name := "unit.test/app:" + tc.tag
+			req := mux.SetURLVars(httptest.NewRequest("GET", "/v2/images/"+name, nil), map[string]string{"name": name})
+			w := httptest.NewRecorder()
+			h.getImage(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			result := &imageSerialize{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), result))
+			assert.Equal(t, tc.expectedLatestDigest, result.LatestImage.Digest)
+			assert.Equal(t, tc.expectedUpdates, result.Updates)
+		})
+	}
+}
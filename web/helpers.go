@@ -1,60 +1,162 @@
 package web
 
 import (
+	"fmt"
+
 	"github.com/imagespy/api/store"
 	"github.com/imagespy/api/versionparser"
-	log "github.com/sirupsen/logrus"
 )
 
-func findSourceImageOfLayer(sourceImageID int, s store.Store) (*store.Image, []*store.Tag, *store.Image, []*store.Tag, error) {
-	imagesClient := s.Images()
-	tagsClient := s.Tags()
-	sourceImage, err := imagesClient.Get(store.ImageGetOptions{ID: sourceImageID})
+// imageDistinction identifies the latest image of a distinction of a name.
+type imageDistinction struct {
+	distinction string
+	name        string
+}
+
+// findSourceImagesOfLayers reads the source images of all layers together with their tags and their latest images.
+// It issues a constant number of queries, independent of the number of layers and source images.
+// The result is keyed by the ID of a source image. Source images without a tag are not part of the result.
+func findSourceImagesOfLayers(layers []*store.Layer, s store.Store, rules *versionparser.Rules) (map[int]*imageSerialize, error) {
+	result := map[int]*imageSerialize{}
+	sourceImageIDs := []int{}
+	seen := map[int]struct{}{}
+	for _, l := range layers {
+		for _, id := range l.SourceImageIDs {
+			if _, ok := seen[id]; ok {
+				continue
+			}
+
+			seen[id] = struct{}{}
+			sourceImageIDs = append(sourceImageIDs, id)
+		}
+	}
+
+	if len(sourceImageIDs) == 0 {
+		return result, nil
+	}
+
+	sourceImages, err := s.Images().List(store.ImageListOptions{IDs: sourceImageIDs})
 	if err != nil {
-		log.Errorf("getting source image '%d': %s", sourceImageID, err)
-		return nil, nil, nil, nil, err
+		return nil, fmt.Errorf("reading source images: %s", err)
 	}
 
 	isTagged := true
-	sourceImageTags, err := tagsClient.List(store.TagListOptions{ImageID: sourceImage.ID, IsTagged: &isTagged})
+	sourceImageTags, err := s.Tags().List(store.TagListOptions{ImageIDs: sourceImageIDs, IsTagged: &isTagged})
 	if err != nil {
-		log.Errorf("reading tags of source image '%d': %s", sourceImageID, err)
-		return nil, nil, nil, nil, err
+		return nil, fmt.Errorf("reading tags of source images: %s", err)
 	}
 
-	if len(sourceImageTags) == 0 {
-		return nil, nil, nil, nil, nil
+	tagsByImageID := groupTagsByImageID(sourceImageTags)
+	names := []string{}
+	seenNames := map[string]struct{}{}
+	for _, i := range sourceImages {
+		if _, ok := seenNames[i.Name]; ok {
+			continue
+		}
+
+		seenNames[i.Name] = struct{}{}
+		names = append(names, i.Name)
 	}
 
-	latestImage, latestTags, err := findLatestImageOfImage(sourceImage, sourceImageTags, s)
+	latestImages, err := findLatestImagesOfNames(names, s)
 	if err != nil {
-		return nil, nil, nil, nil, err
+		return nil, err
 	}
 
-	return sourceImage, sourceImageTags, latestImage, latestTags, nil
+	latestImageIDs := []int{}
+	for _, i := range latestImages {
+		latestImageIDs = append(latestImageIDs, i.ID)
+	}
+
+	latestTagsByImageID := map[int][]*store.Tag{}
+	if len(latestImageIDs) > 0 {
+		latestTags, err := s.Tags().List(store.TagListOptions{ImageIDs: latestImageIDs})
+		if err != nil {
+			return nil, fmt.Errorf("reading tags of latest images: %s", err)
+		}
+
+		latestTagsByImageID = groupTagsByImageID(latestTags)
+	}
+
+	for _, i := range sourceImages {
+		tags := tagsByImageID[i.ID]
+		if len(tags) == 0 {
+			continue
+		}
+
+		latestImage, err := selectLatestImage(i, tags, latestImages, rules)
+		if err != nil {
+			return nil, err
+		}
+
+		result[i.ID] = convertImageToResult(i, tags, latestImage, latestTagsByImageID[latestImage.ID])
+	}
+
+	return result, nil
 }
 
-func findLatestImageOfImage(i *store.Image, tags []*store.Tag, s store.Store) (*store.Image, []*store.Tag, error) {
-	imagesClient := s.Images()
+// findLatestImagesOfNames reads the latest image of every distinction of the names.
+// If more than one image of a distinction is marked as latest, the most recently created one wins.
+func findLatestImagesOfNames(names []string, s store.Store) (map[imageDistinction]*store.Image, error) {
+	result := map[imageDistinction]*store.Image{}
+	isLatest := true
+	latestTags, err := s.Tags().List(store.TagListOptions{ImageNames: names, IsLatest: &isLatest})
+	if err != nil {
+		return nil, fmt.Errorf("reading latest tags: %s", err)
+	}
+
+	if len(latestTags) == 0 {
+		return result, nil
+	}
+
+	imageIDs := []int{}
+	for _, t := range latestTags {
+		imageIDs = append(imageIDs, t.ImageID)
+	}
+
+	images, err := s.Images().List(store.ImageListOptions{IDs: imageIDs})
+	if err != nil {
+		return nil, fmt.Errorf("reading latest images: %s", err)
+	}
+
+	imagesByID := map[int]*store.Image{}
+	for _, i := range images {
+		imagesByID[i.ID] = i
+	}
+
+	for _, t := range latestTags {
+		i, ok := imagesByID[t.ImageID]
+		if !ok {
+			continue
+		}
+
+		key := imageDistinction{distinction: t.Distinction, name: i.Name}
+		current, ok := result[key]
+		if !ok || i.CreatedAt.After(current.CreatedAt) || (i.CreatedAt.Equal(current.CreatedAt) && i.ID > current.ID) {
+			result[key] = i
+		}
+	}
+
+	return result, nil
+}
+
+// selectLatestImage selects the latest image of an image out of the latest images of each distinction of its tags.
+// The latest image of a distinction replaces the one selected so far only if its version parser has a greater weight.
+func selectLatestImage(i *store.Image, tags []*store.Tag, latestImages map[imageDistinction]*store.Image, rules *versionparser.Rules) (*store.Image, error) {
+	vps := rules.ForRepository(i.Name)
 	var latestImage *store.Image
 	var latestVP versionparser.VersionParser
 	for _, tag := range tags {
-		isLatestTag := true
-		li, err := imagesClient.Get(store.ImageGetOptions{
-			Name:           i.Name,
-			TagDistinction: tag.Distinction,
-			TagIsLatest:    &isLatestTag,
-		})
-		if err != nil {
-			log.Errorf("reading latest image of source image '%d': %s", i.ID, err)
-			return nil, nil, err
+		li, ok := latestImages[imageDistinction{distinction: tag.Distinction, name: i.Name}]
+		if !ok {
+			return nil, fmt.Errorf("latest image of source image '%d' with distinction '%s' does not exist", i.ID, tag.Distinction)
 		}
 
 		if latestImage == nil {
 			latestImage = li
-			latestVP = versionparser.FindForVersion(tag.Name)
+			latestVP = vps.FindForVersion(tag.Name)
 		} else {
-			vp := versionparser.FindForVersion(tag.Name)
+			vp := vps.FindForVersion(tag.Name)
 			if li.Digest != latestImage.Digest && latestVP.Weight() < vp.Weight() {
 				latestImage = li
 				latestVP = vp
@@ -62,11 +164,49 @@ func findLatestImageOfImage(i *store.Image, tags []*store.Tag, s store.Store) (*
 		}
 	}
 
-	latestTags, err := s.Tags().List(store.TagListOptions{ImageID: latestImage.ID})
-	if err != nil {
-		log.Errorf("reading tags of latest image of source image '%d': %s", latestImage.ID, err)
-		return nil, nil, err
+	return latestImage, nil
+}
+
+// findUpdates compares the version of the requested tag to the latest tag of its distinction.
+// Versions with a variant report a newer version and a newer variant separately.
+// Other versions report an update only if the latest tag is newer.
+func findUpdates(current versionparser.VersionParser, distinction string, latestTags []*store.Tag, vps *versionparser.DefaultRegistry) *updatesSerialize {
+	for _, t := range latestTags {
+		if !t.IsLatest || t.Distinction != distinction {
+			continue
+		}
+
+		latest := vps.FindForVersion(t.Name)
+		latestVariant, latestIsVariant := latest.(*versionparser.Variant)
+		currentVariant, currentIsVariant := current.(*versionparser.Variant)
+		if latestIsVariant && currentIsVariant {
+			result := &updatesSerialize{}
+			result.Version, _ = latestVariant.IsVersionGreaterThan(currentVariant)
+			result.Variant, _ = latestVariant.IsVariantGreaterThan(currentVariant)
+			return result
+		}
+
+		isGreater, err := latest.IsGreaterThan(current)
+		if err == versionparser.ErrCreatedUnknown {
+			// The latest tag of a distinction that is ordered by the time of creation is newer than every other tag.
+			isGreater = t.Name != current.String()
+		}
+
+		if !isGreater {
+			return nil
+		}
+
+		return &updatesSerialize{Version: true}
+	}
+
+	return nil
+}
+
+func groupTagsByImageID(tags []*store.Tag) map[int][]*store.Tag {
+	result := map[int][]*store.Tag{}
+	for _, t := range tags {
+		result[t.ImageID] = append(result[t.ImageID], t)
 	}
 
-	return latestImage, latestTags, nil
+	return result
 }
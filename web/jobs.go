@@ -0,0 +1,129 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/imagespy/api/store"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultJobsLimit = 100
+
+type jobSerialize struct {
+	Action    string    `json:"action"`
+	Attempts  int       `json:"attempts"`
+	CreatedAt time.Time `json:"created_at"`
+	ID        int       `json:"id"`
+	LastError string    `json:"last_error,omitempty"`
+	Name      string    `json:"name"`
+	NextRunAt time.Time `json:"next_run_at"`
+	Reference string    `json:"reference"`
+	Status    string    `json:"status"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type jobsSerialize struct {
+	Counts map[string]int  `json:"counts"`
+	Jobs   []*jobSerialize `json:"jobs"`
+}
+
+type jobsHandler struct {
+	serializer  func(interface{}) ([]byte, error)
+	store       store.Store
+	webhookOpts WebhookOpts
+}
+
+func (h *jobsHandler) getJob(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	j, err := h.store.Jobs().Get(store.JobGetOptions{ID: id})
+	if err != nil {
+		if err == store.ErrDoesNotExist {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		log.Errorf("jobsHandler.getJob: reading job '%d': %s", id, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	b, err := h.serializer(convertJobToResult(j, h.webhookOpts.isAuthenticated(r)))
+	if err != nil {
+		log.Errorf("jobsHandler.getJob: serializing result: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+func (h *jobsHandler) listJobs(w http.ResponseWriter, r *http.Request) {
+	limit, err := strconv.Atoi(getQueryParam(r, "limit", strconv.Itoa(defaultJobsLimit)))
+	if err != nil || limit < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	counts, err := h.store.Jobs().CountByStatus()
+	if err != nil {
+		log.Errorf("jobsHandler.listJobs: counting jobs: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	jobs, err := h.store.Jobs().List(store.JobListOptions{Limit: limit, Status: getQueryParam(r, "status", "")})
+	if err != nil {
+		log.Errorf("jobsHandler.listJobs: listing jobs: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	withError := h.webhookOpts.isAuthenticated(r)
+	result := &jobsSerialize{Counts: counts, Jobs: []*jobSerialize{}}
+	for _, j := range jobs {
+		result.Jobs = append(result.Jobs, convertJobToResult(j, withError))
+	}
+
+	b, err := h.serializer(result)
+	if err != nil {
+		log.Errorf("jobsHandler.listJobs: serializing result: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// convertJobToResult omits the last error unless withError is true.
+// Errors can contain internal details, e.g. hostnames of registries or errors of the database.
+func convertJobToResult(j *store.Job, withError bool) *jobSerialize {
+	result := &jobSerialize{
+		Action:    j.Action,
+		Attempts:  j.Attempts,
+		CreatedAt: j.CreatedAt,
+		ID:        j.ID,
+		Name:      j.Name,
+		NextRunAt: j.NextRunAt,
+		Reference: j.Reference,
+		Status:    j.Status,
+		UpdatedAt: j.UpdatedAt,
+	}
+	if withError {
+		result.LastError = j.LastError
+	}
+
+	return result
+}
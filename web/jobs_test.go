@@ -0,0 +1,152 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/imagespy/api/store"
+	"github.com/imagespy/api/store/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestJobsHandler(t *testing.T, opts WebhookOpts) *jobsHandler {
+	s := memory.New()
+	for _, j := range []*store.Job{
+		{Action: store.JobActionScrape, Name: "unit.test/app", Reference: "1.0"},
+		{Action: store.JobActionScrape, Name: "unit.test/app", Reference: "1.1"},
+		{Action: store.JobActionDeleteTag, Name: "unit.test/app", Reference: "0.9"},
+	} {
+		require.NoError(t, s.Jobs().Create(j))
+	}
+
+	dead, err := s.Jobs().Get(store.JobGetOptions{ID: 3})
+	require.NoError(t, err)
+	dead.LastError = "unit test"
+	dead.Status = store.JobStatusDead
+	require.NoError(t, s.Jobs().Update(dead))
+	return &jobsHandler{serializer: json.Marshal, store: s, webhookOpts: opts}
+}
+
+func TestJobsHandler_getJob(t *testing.T) {
+	testcases := []struct {
+		name           string
+		id             string
+		expectedStatus int
+		expectedJob    *jobSerialize
+	}{
+		{
+			name:           "existing job",
+			id:             "3",
+			expectedStatus: http.StatusOK,
+			expectedJob:    &jobSerialize{Action: store.JobActionDeleteTag, ID: 3, LastError: "unit test", Name: "unit.test/app", Reference: "0.9", Status: store.JobStatusDead},
+		},
+		{name: "unknown job", id: "4", expectedStatus: http.StatusNotFound},
+		{name: "invalid id", id: "abc", expectedStatus: http.StatusBadRequest},
+	}
+
+	h := newTestJobsHandler(t, WebhookOpts{})
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := mux.SetURLVars(httptest.NewRequest("GET", "/v2/jobs/"+tc.id, nil), map[string]string{"id": tc.id})
+			w := httptest.NewRecorder()
+			h.getJob(w, req)
+			require.Equal(t, tc.expectedStatus, w.Code)
+			if tc.expectedJob == nil {
+				return
+			}
+
+			assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+			result := &jobSerialize{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), result))
+			tc.expectedJob.CreatedAt = result.CreatedAt
+			tc.expectedJob.UpdatedAt = result.UpdatedAt
+			assert.Equal(t, tc.expectedJob, result)
+		})
+	}
+}
+
+func TestJobsHandler_listJobs(t *testing.T) {
+	testcases := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedIDs    []int
+	}{
+		{name: "all", expectedStatus: http.StatusOK, expectedIDs: []int{3, 2, 1}},
+		{name: "limit", query: "?limit=2", expectedStatus: http.StatusOK, expectedIDs: []int{3, 2}},
+		{name: "status", query: "?status=pending", expectedStatus: http.StatusOK, expectedIDs: []int{2, 1}},
+		{name: "unknown status", query: "?status=unknown", expectedStatus: http.StatusOK, expectedIDs: []int{}},
+		{name: "limit below 1", query: "?limit=0", expectedStatus: http.StatusBadRequest},
+		{name: "invalid limit", query: "?limit=abc", expectedStatus: http.StatusBadRequest},
+	}
+
+	h := newTestJobsHandler(t, WebhookOpts{})
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.listJobs(w, httptest.NewRequest("GET", "/v2/jobs"+tc.query, nil))
+			require.Equal(t, tc.expectedStatus, w.Code)
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			result := &jobsSerialize{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), result))
+			ids := []int{}
+			for _, j := range result.Jobs {
+				ids = append(ids, j.ID)
+			}
+
+			assert.Equal(t, tc.expectedIDs, ids)
+			assert.Equal(t, map[string]int{store.JobStatusDead: 1, store.JobStatusPending: 2}, result.Counts, "counts are independent of the filters")
+		})
+	}
+}
+
+func TestJobsHandler_LastError(t *testing.T) {
+	testcases := []struct {
+		name              string
+		opts              WebhookOpts
+		authorization     string
+		expectedLastError string
+	}{
+		{name: "authentication disabled", expectedLastError: "unit test"},
+		{name: "authenticated", opts: WebhookOpts{Secret: "secret"}, authorization: "Bearer secret", expectedLastError: "unit test"},
+		{name: "unauthenticated", opts: WebhookOpts{Secret: "secret"}},
+		{name: "wrong secret", opts: WebhookOpts{Secret: "secret"}, authorization: "Bearer other"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestJobsHandler(t, tc.opts)
+			req := mux.SetURLVars(httptest.NewRequest("GET", "/v2/jobs/3", nil), map[string]string{"id": "3"})
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+
+			w := httptest.NewRecorder()
+			h.getJob(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+			job := &jobSerialize{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), job))
+			assert.Equal(t, tc.expectedLastError, job.LastError, "getJob")
+
+			req = httptest.NewRequest("GET", "/v2/jobs?status=dead", nil)
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+
+			w = httptest.NewRecorder()
+			h.listJobs(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+			result := &jobsSerialize{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), result))
+			require.Len(t, result.Jobs, 1)
+			assert.Equal(t, tc.expectedLastError, result.Jobs[0].LastError, "listJobs")
+		})
+	}
+}
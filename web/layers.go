@@ -5,15 +5,19 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/imagespy/api/store"
+	"github.com/imagespy/api/versionparser"
 	log "github.com/sirupsen/logrus"
 )
 
 type layerSerialize struct {
 	Digest       string            `json:"digest"`
+	MediaType    string            `json:"media_type"`
+	Size         int64             `json:"size"`
 	SourceImages []*imageSerialize `json:"source_images"`
 }
 
 type layersHandler struct {
+	rules      *versionparser.Rules
 	serializer func(interface{}) ([]byte, error)
 	store      store.Store
 }
@@ -34,19 +38,15 @@ func (h *layersHandler) layers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	serialization := &layerSerialize{Digest: layer.Digest}
-	for _, sourceImageID := range layer.SourceImageIDs {
-		sourceImage, sourceImageTags, latestImage, latestTags, err := findSourceImageOfLayer(sourceImageID, h.store)
-		if err != nil {
-			log.Errorf("layersHandler.layers: %s", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-
-		imageSerialization := convertImageToResult(sourceImage, sourceImageTags, latestImage, latestTags)
-		serialization.SourceImages = append(serialization.SourceImages, imageSerialization)
+	sourceImages, err := findSourceImagesOfLayers([]*store.Layer{layer}, h.store, h.rules)
+	if err != nil {
+		log.Errorf("layersHandler.layers: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
 	}
 
+	serialization := convertLayerToResultWithSourceImages(layer, sourceImages)
+
 	b, err := h.serializer(serialization)
 	if err != nil {
 		log.Errorf("serializing layer '%s': %s", digestInput, err)
@@ -0,0 +1,433 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/imagespy/api/queue"
+	"github.com/imagespy/api/registry"
+	"github.com/imagespy/api/store"
+	"github.com/imagespy/api/versionparser"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	maxReportReferences = 1000
+
+	reportStatusLatest   = "latest"
+	reportStatusOutdated = "outdated"
+	reportStatusUnknown  = "unknown"
+)
+
+type reportPlatformInput struct {
+	Architecture string  `json:"architecture"`
+	OS           string  `json:"os"`
+	OSVersion    *string `json:"os_version"`
+	Variant      *string `json:"variant"`
+}
+
+type reportReferenceInput struct {
+	Platform  *reportPlatformInput `json:"platform"`
+	Reference string               `json:"reference"`
+}
+
+type outdatedReportInput struct {
+	// ScrapeUnknown queues a scrape of every reference that is unknown.
+	// It is ignored if the request does not contain the secret of webhooks.
+	ScrapeUnknown bool                    `json:"scrape_unknown"`
+	References    []*reportReferenceInput `json:"references"`
+}
+
+type reportImageSerialize struct {
+	Digest   string             `json:"digest"`
+	Platform *platformSerialize `json:"platform,omitempty"`
+	Tags     []string           `json:"tags"`
+}
+
+type reportEntrySerialize struct {
+	Current   *reportImageSerialize `json:"current,omitempty"`
+	Error     string                `json:"error,omitempty"`
+	Latest    *reportImageSerialize `json:"latest,omitempty"`
+	Name      string                `json:"name"`
+	Queued    bool                  `json:"queued"`
+	Reference string                `json:"reference"`
+	Status    string                `json:"status"`
+}
+
+type outdatedReportSerialize struct {
+	References []*reportEntrySerialize `json:"references"`
+}
+
+// reportReference is a reference of the input after it has been parsed.
+type reportReference struct {
+	digest   string
+	entry    *reportEntrySerialize
+	host     string
+	input    *reportReferenceInput
+	name     string
+	path     string
+	tag      string
+	image    *store.Image
+	imageTag *store.Tag
+}
+
+type reportsHandler struct {
+	queue       queue.Queue
+	rules       *versionparser.Rules
+	serializer  func(interface{}) ([]byte, error)
+	store       store.Store
+	webhookOpts WebhookOpts
+}
+
+// outdated reports for each reference if it points to the latest image of its distinction.
+// It reads all references with a constant number of queries.
+func (h *reportsHandler) outdated(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	input := &outdatedReportInput{}
+	err := json.NewDecoder(r.Body).Decode(input)
+	if err != nil {
+		log.Infof("reportsHandler.outdated: decoding request: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if len(input.References) > maxReportReferences {
+		log.Infof("reportsHandler.outdated: %d references exceed the maximum of %d", len(input.References), maxReportReferences)
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// Scrapes cost requests to registries. Only clients that know the secret of webhooks can trigger them.
+	scrapeUnknown := input.ScrapeUnknown
+	if scrapeUnknown && !h.webhookOpts.isAuthenticated(r) {
+		log.Infof("reportsHandler.outdated: ignoring scrape_unknown of unauthenticated request from %s", r.RemoteAddr)
+		scrapeUnknown = false
+	}
+
+	refs := []*reportReference{}
+	for _, in := range input.References {
+		if in == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		ref := &reportReference{
+			entry: &reportEntrySerialize{Reference: in.Reference, Status: reportStatusUnknown},
+			input: in,
+		}
+		refs = append(refs, ref)
+		host, path, tag, digest, err := registry.ParseImage(in.Reference)
+		if err != nil {
+			ref.entry.Error = err.Error()
+			continue
+		}
+
+		ref.digest = digest
+		ref.host = host
+		ref.name = host + "/" + path
+		ref.path = path
+		ref.tag = tag
+		ref.entry.Name = ref.name
+	}
+
+	err = h.findImages(refs)
+	if err != nil {
+		log.Errorf("reportsHandler.outdated: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = h.findLatestImages(refs)
+	if err != nil {
+		log.Errorf("reportsHandler.outdated: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	result := &outdatedReportSerialize{References: []*reportEntrySerialize{}}
+	for _, ref := range refs {
+		if ref.image == nil && ref.name != "" && ref.entry.Error == "" && scrapeUnknown {
+			ref.entry.Queued = h.enqueueScrape(ref)
+		}
+
+		result.References = append(result.References, ref.entry)
+	}
+
+	b, err := h.serializer(result)
+	if err != nil {
+		log.Errorf("reportsHandler.outdated: serializing result: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// findImages sets the image of every reference that is known.
+// A reference with a digest selects the image with that digest. A reference with only a tag selects the image that the tag currently points to.
+func (h *reportsHandler) findImages(refs []*reportReference) error {
+	names := []string{}
+	tagNames := []string{}
+	digests := []string{}
+	for _, ref := range refs {
+		if ref.name == "" {
+			continue
+		}
+
+		if ref.digest != "" {
+			digests = append(digests, ref.digest)
+			continue
+		}
+
+		names = append(names, ref.name)
+		tagNames = append(tagNames, ref.tag)
+	}
+
+	imagesByNameAndDigest := map[string]*store.Image{}
+	if len(digests) > 0 {
+		images, err := h.store.Images().List(store.ImageListOptions{Digests: digests})
+		if err != nil {
+			return err
+		}
+
+		for _, i := range images {
+			imagesByNameAndDigest[i.Name+"@"+i.Digest] = i
+		}
+	}
+
+	imagesByNameAndTag := map[string]*store.Image{}
+	tagsByNameAndTag := map[string]*store.Tag{}
+	if len(names) > 0 {
+		isTagged := true
+		tags, err := h.store.Tags().List(store.TagListOptions{ImageNames: names, IsTagged: &isTagged, Names: tagNames})
+		if err != nil {
+			return err
+		}
+
+		imageIDs := []int{}
+		for _, t := range tags {
+			imageIDs = append(imageIDs, t.ImageID)
+		}
+
+		imagesByID := map[int]*store.Image{}
+		if len(imageIDs) > 0 {
+			images, err := h.store.Images().List(store.ImageListOptions{IDs: imageIDs})
+			if err != nil {
+				return err
+			}
+
+			for _, i := range images {
+				imagesByID[i.ID] = i
+			}
+		}
+
+		for _, t := range tags {
+			i, ok := imagesByID[t.ImageID]
+			if !ok {
+				continue
+			}
+
+			// Tags.List returns the tags ordered by ID. The most recently created tag wins if a tag has not been untagged.
+			imagesByNameAndTag[i.Name+":"+t.Name] = i
+			tagsByNameAndTag[i.Name+":"+t.Name] = t
+		}
+	}
+
+	for _, ref := range refs {
+		if ref.name == "" {
+			continue
+		}
+
+		if ref.digest != "" {
+			ref.image = imagesByNameAndDigest[ref.name+"@"+ref.digest]
+			continue
+		}
+
+		ref.image = imagesByNameAndTag[ref.name+":"+ref.tag]
+		ref.imageTag = tagsByNameAndTag[ref.name+":"+ref.tag]
+	}
+
+	return nil
+}
+
+// findLatestImages compares the image of every known reference to the latest image of its distinction.
+func (h *reportsHandler) findLatestImages(refs []*reportReference) error {
+	names := []string{}
+	imageIDs := []int{}
+	for _, ref := range refs {
+		if ref.image != nil {
+			names = append(names, ref.name)
+			imageIDs = append(imageIDs, ref.image.ID)
+		}
+	}
+
+	if len(imageIDs) == 0 {
+		return nil
+	}
+
+	isTagged := true
+	tags, err := h.store.Tags().List(store.TagListOptions{ImageIDs: imageIDs, IsTagged: &isTagged})
+	if err != nil {
+		return err
+	}
+
+	tagsByImageID := groupTagsByImageID(tags)
+	latestImages, err := findLatestImagesOfNames(names, h.store)
+	if err != nil {
+		return err
+	}
+
+	latestByRef := map[*reportReference]*store.Image{}
+	for _, ref := range refs {
+		if ref.image == nil {
+			continue
+		}
+
+		var latest *store.Image
+		if ref.imageTag != nil {
+			latest = latestImages[imageDistinction{distinction: ref.imageTag.Distinction, name: ref.name}]
+		} else {
+			// A reference by digest has no tag that selects the distinction. All tags of the image are taken into account instead.
+			latest, _ = selectLatestImage(ref.image, tagsByImageID[ref.image.ID], latestImages, h.rules)
+		}
+
+		// Without a latest image of the distinction no newer image is known.
+		if latest == nil {
+			latest = ref.image
+		}
+
+		latestByRef[ref] = latest
+		imageIDs = append(imageIDs, latest.ID)
+	}
+
+	latestTags, err := h.store.Tags().List(store.TagListOptions{ImageIDs: imageIDs, IsTagged: &isTagged})
+	if err != nil {
+		return err
+	}
+
+	tagsByImageID = groupTagsByImageID(latestTags)
+	platformsByImageID := map[int][]*store.Platform{}
+	if hasPlatformInput(refs) {
+		platforms, err := h.store.Platforms().List(store.PlatformListOptions{ImageIDs: imageIDs})
+		if err != nil {
+			return err
+		}
+
+		for _, p := range platforms {
+			platformsByImageID[p.ImageID] = append(platformsByImageID[p.ImageID], p)
+		}
+	}
+
+	for _, ref := range refs {
+		latest, ok := latestByRef[ref]
+		if !ok {
+			continue
+		}
+
+		ref.entry.Current = convertImageToReportResult(ref.image, tagsByImageID[ref.image.ID])
+		ref.entry.Latest = convertImageToReportResult(latest, tagsByImageID[latest.ID])
+		if ref.input.Platform != nil {
+			current := findReportPlatform(ref.input.Platform, platformsByImageID[ref.image.ID])
+			if current == nil {
+				ref.entry.Current = nil
+				ref.entry.Latest = nil
+				ref.entry.Error = "image does not contain the platform"
+				continue
+			}
+
+			ref.entry.Current.Platform = convertPlatformToResult(current)
+			if latestPlatform := findReportPlatform(ref.input.Platform, platformsByImageID[latest.ID]); latestPlatform != nil {
+				ref.entry.Latest.Platform = convertPlatformToResult(latestPlatform)
+			}
+		}
+
+		if latest.Digest == ref.image.Digest {
+			ref.entry.Status = reportStatusLatest
+		} else {
+			ref.entry.Status = reportStatusOutdated
+		}
+	}
+
+	return nil
+}
+
+// enqueueScrape queues a scrape of an unknown reference. It returns true if the scrape has been queued.
+// Scrapes are restricted to the hosts and repositories that webhooks are allowed to scrape.
+func (h *reportsHandler) enqueueScrape(ref *reportReference) bool {
+	if ref.tag == "" {
+		return false
+	}
+
+	if !h.webhookOpts.allows(ref.host, ref.path) {
+		log.Infof("reportsHandler.enqueueScrape: repository %s of registry %s is not allowed", ref.path, ref.host)
+		return false
+	}
+
+	err := h.queue.Enqueue(&store.Job{
+		Action:    store.JobActionScrape,
+		Name:      ref.name,
+		Reference: ref.tag,
+	})
+	if err != nil {
+		log.Errorf("reportsHandler.enqueueScrape: enqueueing scrape of %s:%s: %s", ref.name, ref.tag, err)
+		return false
+	}
+
+	return true
+}
+
+func hasPlatformInput(refs []*reportReference) bool {
+	for _, ref := range refs {
+		if ref.input.Platform != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// findReportPlatform returns the platform that matches the input. Fields of the input that are not set match any value.
+func findReportPlatform(in *reportPlatformInput, platforms []*store.Platform) *store.Platform {
+	for _, p := range platforms {
+		if in.Architecture != "" && p.Architecture != in.Architecture {
+			continue
+		}
+
+		if in.OS != "" && p.OS != in.OS {
+			continue
+		}
+
+		if in.OSVersion != nil && p.OSVersion != *in.OSVersion {
+			continue
+		}
+
+		if in.Variant != nil && p.Variant != *in.Variant {
+			continue
+		}
+
+		return p
+	}
+
+	return nil
+}
+
+func convertImageToReportResult(i *store.Image, tags []*store.Tag) *reportImageSerialize {
+	result := &reportImageSerialize{Digest: i.Digest, Tags: []string{}}
+	for _, t := range tags {
+		result.Tags = append(result.Tags, t.Name)
+	}
+
+	return result
+}
+
+func convertPlatformToResult(p *store.Platform) *platformSerialize {
+	return &platformSerialize{
+		Architecture: p.Architecture,
+		OS:           p.OS,
+		OSVersion:    p.OSVersion,
+		Size:         p.Size,
+		Variant:      p.Variant,
+	}
+}
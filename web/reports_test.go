@@ -0,0 +1,205 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/imagespy/api/store"
+	"github.com/imagespy/api/store/memory"
+	"github.com/imagespy/api/versionparser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	reportDigestOld     = "sha256:" + strings.Repeat("1", 64)
+	reportDigestLatest  = "sha256:" + strings.Repeat("2", 64)
+	reportDigestUnknown = "sha256:" + strings.Repeat("9", 64)
+)
+
+func newTestReportsHandler(t *testing.T, q *testQueue, opts WebhookOpts) *reportsHandler {
+	s := memory.New()
+	createTestImage(t, s, "unit.test/app", reportDigestOld, testCreated,
+		testTag{distinction: "majorMinorPatch", isTagged: true, name: "1.0.0"},
+	)
+	createTestImage(t, s, "unit.test/app", reportDigestLatest, testCreated.Add(time.Hour),
+		testTag{distinction: "majorMinorPatch", isLatest: true, isTagged: true, name: "1.0.1"},
+	)
+	return &reportsHandler{
+		queue:       q,
+		rules:       versionparser.NewRules(versionparser.Opts{}),
+		serializer:  json.Marshal,
+		store:       s,
+		webhookOpts: opts,
+	}
+}
+
+func requestOutdatedReport(t *testing.T, h *reportsHandler, body string, authorization string) (int, *outdatedReportSerialize) {
+	req := httptest.NewRequest("POST", "/v2/reports/outdated", strings.NewReader(body))
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	w := httptest.NewRecorder()
+	h.outdated(w, req)
+	if w.Code != http.StatusOK {
+		return w.Code, nil
+	}
+
+	result := &outdatedReportSerialize{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), result))
+	return w.Code, result
+}
+
+func TestReportsHandler_outdated(t *testing.T) {
+	h := newTestReportsHandler(t, &testQueue{}, WebhookOpts{})
+	status, result := requestOutdatedReport(t, h, `{"references": [
+		{"reference": "unit.test/app:1.0.0"},
+		{"reference": "unit.test/app:1.0.1"},
+		{"reference": "unit.test/app@`+reportDigestOld+`"},
+		{"reference": "unit.test/app@`+reportDigestLatest+`"},
+		{"reference": "unit.test/app:2.0.0"},
+		{"reference": "unit.test/other:1.0.0"},
+		{"reference": "Invalid Reference"}
+	]}`, "")
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, result.References, 7)
+
+	old := &reportImageSerialize{Digest: reportDigestOld, Tags: []string{"1.0.0"}}
+	latest := &reportImageSerialize{Digest: reportDigestLatest, Tags: []string{"1.0.1"}}
+	assert.Equal(t, &reportEntrySerialize{Current: old, Latest: latest, Name: "unit.test/app", Reference: "unit.test/app:1.0.0", Status: reportStatusOutdated}, result.References[0], "outdated tag")
+	assert.Equal(t, &reportEntrySerialize{Current: latest, Latest: latest, Name: "unit.test/app", Reference: "unit.test/app:1.0.1", Status: reportStatusLatest}, result.References[1], "latest tag")
+	assert.Equal(t, &reportEntrySerialize{Current: old, Latest: latest, Name: "unit.test/app", Reference: "unit.test/app@" + reportDigestOld, Status: reportStatusOutdated}, result.References[2], "outdated digest")
+	assert.Equal(t, &reportEntrySerialize{Current: latest, Latest: latest, Name: "unit.test/app", Reference: "unit.test/app@" + reportDigestLatest, Status: reportStatusLatest}, result.References[3], "latest digest")
+	assert.Equal(t, &reportEntrySerialize{Name: "unit.test/app", Reference: "unit.test/app:2.0.0", Status: reportStatusUnknown}, result.References[4], "unknown tag")
+	assert.Equal(t, &reportEntrySerialize{Name: "unit.test/other", Reference: "unit.test/other:1.0.0", Status: reportStatusUnknown}, result.References[5], "unknown repository")
+	assert.Equal(t, "Invalid Reference", result.References[6].Reference)
+	assert.Equal(t, reportStatusUnknown, result.References[6].Status)
+	assert.NotEmpty(t, result.References[6].Error, "invalid reference")
+}
+
+func TestReportsHandler_outdated_Platform(t *testing.T) {
+	h := newTestReportsHandler(t, &testQueue{}, WebhookOpts{})
+	status, result := requestOutdatedReport(t, h, `{"references": [
+		{"reference": "unit.test/app:1.0.0", "platform": {"architecture": "amd64", "os": "linux"}},
+		{"reference": "unit.test/app:1.0.0", "platform": {"architecture": "arm64"}},
+		{"reference": "unit.test/app:1.0.0", "platform": {"variant": "v8"}}
+	]}`, "")
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, result.References, 3)
+
+	matching := result.References[0]
+	assert.Equal(t, reportStatusOutdated, matching.Status)
+	require.NotNil(t, matching.Current.Platform)
+	assert.Equal(t, &platformSerialize{Architecture: "amd64", OS: "linux", Size: 1024}, matching.Current.Platform)
+	require.NotNil(t, matching.Latest.Platform)
+	assert.Equal(t, "amd64", matching.Latest.Platform.Architecture)
+	for _, ref := range result.References[1:] {
+		assert.Equal(t, reportStatusUnknown, ref.Status)
+		assert.Equal(t, "image does not contain the platform", ref.Error)
+		assert.Nil(t, ref.Current)
+		assert.Nil(t, ref.Latest)
+	}
+}
+
+func TestReportsHandler_outdated_Limit(t *testing.T) {
+	h := newTestReportsHandler(t, &testQueue{}, WebhookOpts{})
+	body := func(count int) string {
+		refs := []string{}
+		for i := 0; i < count; i++ {
+			refs = append(refs, `{"reference": "unit.test/app:1.0.0"}`)
+		}
+
+		return fmt.Sprintf(`{"references": [%s]}`, strings.Join(refs, ","))
+	}
+
+	status, result := requestOutdatedReport(t, h, body(maxReportReferences), "")
+	require.Equal(t, http.StatusOK, status)
+	assert.Len(t, result.References, maxReportReferences)
+
+	status, _ = requestOutdatedReport(t, h, body(maxReportReferences+1), "")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, status)
+
+	status, _ = requestOutdatedReport(t, h, `{"references": [null]}`, "")
+	assert.Equal(t, http.StatusBadRequest, status, "null reference")
+
+	status, _ = requestOutdatedReport(t, h, `{"references": `, "")
+	assert.Equal(t, http.StatusBadRequest, status, "invalid JSON")
+}
+
+func TestReportsHandler_outdated_ScrapeUnknown(t *testing.T) {
+	body := `{"scrape_unknown": true, "references": [
+		{"reference": "unit.test/app:1.0.0"},
+		{"reference": "unit.test/app:2.0.0"},
+		{"reference": "unit.test/app@` + reportDigestUnknown + `"},
+		{"reference": "unit.test/other:1.0.0"},
+		{"reference": "denied.test/app:1.0.0"}
+	]}`
+	testcases := []struct {
+		name           string
+		body           string
+		opts           WebhookOpts
+		authorization  string
+		expectedQueued []bool
+		expectedJobs   []*store.Job
+	}{
+		{
+			name:           "authenticated",
+			body:           body,
+			opts:           WebhookOpts{AllowedHosts: []string{"unit.test"}, Secret: "secret"},
+			authorization:  "Bearer secret",
+			expectedQueued: []bool{false, true, false, true, false},
+			expectedJobs: []*store.Job{
+				{Action: store.JobActionScrape, Name: "unit.test/app", Reference: "2.0.0"},
+				{Action: store.JobActionScrape, Name: "unit.test/other", Reference: "1.0.0"},
+			},
+		},
+		{
+			name:           "authentication disabled",
+			body:           body,
+			opts:           WebhookOpts{AllowedRepositories: []string{"other"}},
+			expectedQueued: []bool{false, false, false, true, false},
+			expectedJobs: []*store.Job{
+				{Action: store.JobActionScrape, Name: "unit.test/other", Reference: "1.0.0"},
+			},
+		},
+		{
+			name:           "unauthenticated",
+			body:           body,
+			opts:           WebhookOpts{Secret: "secret"},
+			expectedQueued: []bool{false, false, false, false, false},
+		},
+		{
+			name:           "wrong secret",
+			body:           body,
+			opts:           WebhookOpts{Secret: "secret"},
+			authorization:  "Bearer other",
+			expectedQueued: []bool{false, false, false, false, false},
+		},
+		{
+			name:           "not requested",
+			body:           strings.Replace(body, `"scrape_unknown": true`, `"scrape_unknown": false`, 1),
+			expectedQueued: []bool{false, false, false, false, false},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			q := &testQueue{}
+			status, result := requestOutdatedReport(t, newTestReportsHandler(t, q, tc.opts), tc.body, tc.authorization)
+			require.Equal(t, http.StatusOK, status, "reports do not require authentication")
+			queued := []bool{}
+			for _, ref := range result.References {
+				queued = append(queued, ref.Queued)
+			}
+
+			assert.Equal(t, tc.expectedQueued, queued)
+			assert.Equal(t, tc.expectedJobs, q.jobs)
+		})
+	}
+}
@@ -0,0 +1,387 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/imagespy/api/registry"
+	"github.com/imagespy/api/store"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+)
+
+type historyPlatformSerialize struct {
+	Architecture   string    `json:"architecture"`
+	Created        time.Time `json:"created"`
+	ManifestDigest string    `json:"manifest_digest"`
+	OS             string    `json:"os"`
+	OSVersion      string    `json:"os_version"`
+	Size           int64     `json:"size"`
+	Variant        string    `json:"variant"`
+}
+
+type historyTagSerialize struct {
+	Distinction string `json:"distinction"`
+	IsLatest    bool   `json:"is_latest"`
+	IsTagged    bool   `json:"is_tagged"`
+	Name        string `json:"name"`
+	// ReplacedAt is the time the digest that the tag points to now has been scraped for the first time.
+	ReplacedAt *time.Time `json:"replaced_at,omitempty"`
+	ReplacedBy string     `json:"replaced_by,omitempty"`
+}
+
+type historyImageSerialize struct {
+	CreatedAt time.Time                   `json:"created_at"`
+	Digest    string                      `json:"digest"`
+	Name      string                      `json:"name"`
+	Platforms []*historyPlatformSerialize `json:"platforms"`
+	ScrapedAt time.Time                   `json:"scraped_at"`
+	Tags      []*historyTagSerialize      `json:"tags"`
+}
+
+type repositorySerialize struct {
+	ImageCount int       `json:"image_count"`
+	Name       string    `json:"name"`
+	ScrapedAt  time.Time `json:"scraped_at"`
+	TagCount   int       `json:"tag_count"`
+}
+
+type repositoriesSerialize struct {
+	Limit        int                    `json:"limit"`
+	NextOffset   *int                   `json:"next_offset,omitempty"`
+	Offset       int                    `json:"offset"`
+	Repositories []*repositorySerialize `json:"repositories"`
+}
+
+type repositoryImagesSerialize struct {
+	Images     []*historyImageSerialize `json:"images"`
+	Limit      int                      `json:"limit"`
+	NextOffset *int                     `json:"next_offset,omitempty"`
+	Offset     int                      `json:"offset"`
+}
+
+type repositoriesHandler struct {
+	serializer func(interface{}) ([]byte, error)
+	store      store.Store
+}
+
+// list lists the names of all images together with the number of their digests and tags.
+func (h *repositoriesHandler) list(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		log.Infof("repositoriesHandler.list: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	orderBy := getQueryParam(r, "sort", store.RepositoryOrderName)
+	if orderBy != store.RepositoryOrderName && orderBy != store.RepositoryOrderScrapedAt {
+		log.Infof("repositoriesHandler.list: unsupported sort %s", orderBy)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Read one repository more than requested to know if there is a next page.
+	repositories, err := h.store.Images().ListRepositories(store.RepositoryListOptions{
+		Limit:        limit + 1,
+		NameContains: getQueryParam(r, "q", ""),
+		NamePrefix:   getQueryParam(r, "prefix", ""),
+		Offset:       offset,
+		OrderBy:      orderBy,
+	})
+	if err != nil {
+		log.Errorf("repositoriesHandler.list: listing repositories: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	result := &repositoriesSerialize{
+		Limit:        limit,
+		Offset:       offset,
+		Repositories: []*repositorySerialize{},
+	}
+	if len(repositories) > limit {
+		repositories = repositories[:limit]
+		nextOffset := offset + limit
+		result.NextOffset = &nextOffset
+	}
+
+	for _, repository := range repositories {
+		result.Repositories = append(result.Repositories, &repositorySerialize{
+			ImageCount: repository.ImageCount,
+			Name:       repository.Name,
+			ScrapedAt:  repository.ScrapedAt,
+			TagCount:   repository.TagCount,
+		})
+	}
+
+	b, err := h.serializer(result)
+	if err != nil {
+		log.Errorf("repositoriesHandler.list: serializing result: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	addCacheHeaders(w)
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// images lists every image of a repository that has been scraped, newest first.
+func (h *repositoriesHandler) images(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	address, path, _, _, err := registry.ParseImage(vars["name"])
+	if err != nil {
+		log.Infof("repositoriesHandler.images: parsing repository name: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	opts, err := parseRepositoryImagesQuery(r)
+	if err != nil {
+		log.Infof("repositoriesHandler.images: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	name := address + "/" + path
+	opts.Name = name
+	_, err = h.store.Images().Get(store.ImageGetOptions{Name: name})
+	if err != nil {
+		if err == store.ErrDoesNotExist {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		log.Errorf("repositoriesHandler.images: reading repository '%s': %s", name, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	limit := opts.Limit
+	// Read one image more than requested to know if there is a next page.
+	opts.Limit = limit + 1
+	images, err := h.store.Images().List(opts)
+	if err != nil {
+		log.Errorf("repositoriesHandler.images: listing images of repository '%s': %s", name, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	result := &repositoryImagesSerialize{
+		Images: []*historyImageSerialize{},
+		Limit:  limit,
+		Offset: opts.Offset,
+	}
+	if len(images) > limit {
+		images = images[:limit]
+		nextOffset := opts.Offset + limit
+		result.NextOffset = &nextOffset
+	}
+
+	result.Images, err = h.convertImagesToHistory(name, images)
+	if err != nil {
+		log.Errorf("repositoriesHandler.images: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	b, err := h.serializer(result)
+	if err != nil {
+		log.Errorf("repositoriesHandler.images: serializing result: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	addCacheHeaders(w)
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// convertImagesToHistory reads the tags and platforms of the images and finds the image that replaced each removed tag.
+func (h *repositoriesHandler) convertImagesToHistory(name string, images []*store.Image) ([]*historyImageSerialize, error) {
+	result := []*historyImageSerialize{}
+	if len(images) == 0 {
+		return result, nil
+	}
+
+	imageIDs := []int{}
+	for _, i := range images {
+		imageIDs = append(imageIDs, i.ID)
+	}
+
+	tags, err := h.store.Tags().List(store.TagListOptions{ImageIDs: imageIDs})
+	if err != nil {
+		return nil, err
+	}
+
+	platforms, err := h.store.Platforms().List(store.PlatformListOptions{ImageIDs: imageIDs})
+	if err != nil {
+		return nil, err
+	}
+
+	replacements, err := h.findReplacements(name, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	tagsByImageID := groupTagsByImageID(tags)
+	platformsByImageID := map[int][]*store.Platform{}
+	for _, p := range platforms {
+		platformsByImageID[p.ImageID] = append(platformsByImageID[p.ImageID], p)
+	}
+
+	for _, i := range images {
+		serialization := &historyImageSerialize{
+			CreatedAt: i.CreatedAt,
+			Digest:    i.Digest,
+			Name:      i.Name,
+			Platforms: []*historyPlatformSerialize{},
+			ScrapedAt: i.ScrapedAt,
+			Tags:      []*historyTagSerialize{},
+		}
+		for _, p := range platformsByImageID[i.ID] {
+			serialization.Platforms = append(serialization.Platforms, &historyPlatformSerialize{
+				Architecture:   p.Architecture,
+				Created:        p.Created,
+				ManifestDigest: p.ManifestDigest,
+				OS:             p.OS,
+				OSVersion:      p.OSVersion,
+				Size:           p.Size,
+				Variant:        p.Variant,
+			})
+		}
+
+		for _, t := range tagsByImageID[i.ID] {
+			ts := &historyTagSerialize{
+				Distinction: t.Distinction,
+				IsLatest:    t.IsLatest,
+				IsTagged:    t.IsTagged,
+				Name:        t.Name,
+			}
+			if replacement, ok := replacements[t.ID]; ok {
+				replacedAt := replacement.CreatedAt
+				ts.ReplacedAt = &replacedAt
+				ts.ReplacedBy = replacement.Digest
+			}
+
+			serialization.Tags = append(serialization.Tags, ts)
+		}
+
+		result = append(result, serialization)
+	}
+
+	return result, nil
+}
+
+// findReplacements returns the image that a removed tag has been moved to, keyed by the ID of the removed tag.
+// The replacement is the oldest image of the repository that has been scraped after the image of the removed tag and carries a tag with the same name.
+// Tags that have been deleted from the registry have no replacement.
+func (h *repositoriesHandler) findReplacements(name string, tags []*store.Tag) (map[int]*store.Image, error) {
+	result := map[int]*store.Image{}
+	names := []string{}
+	for _, t := range tags {
+		if !t.IsTagged {
+			names = append(names, t.Name)
+		}
+	}
+
+	if len(names) == 0 {
+		return result, nil
+	}
+
+	candidates, err := h.store.Tags().List(store.TagListOptions{ImageName: name, Names: names})
+	if err != nil {
+		return nil, err
+	}
+
+	candidateImageIDs := []int{}
+	for _, c := range candidates {
+		candidateImageIDs = append(candidateImageIDs, c.ImageID)
+	}
+
+	candidateImages, err := h.store.Images().List(store.ImageListOptions{IDs: candidateImageIDs})
+	if err != nil {
+		return nil, err
+	}
+
+	imagesByID := map[int]*store.Image{}
+	for _, i := range candidateImages {
+		imagesByID[i.ID] = i
+	}
+
+	for _, t := range tags {
+		if t.IsTagged {
+			continue
+		}
+
+		for _, c := range candidates {
+			// Image IDs increase in the order in which images have been scraped.
+			if c.Name != t.Name || c.ImageID <= t.ImageID {
+				continue
+			}
+
+			replacement, ok := imagesByID[c.ImageID]
+			if !ok {
+				continue
+			}
+
+			if current, ok := result[t.ID]; !ok || replacement.ID < current.ID {
+				result[t.ID] = replacement
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// parsePagination reads the query parameters "limit" and "offset".
+func parsePagination(r *http.Request) (int, int, error) {
+	limit, err := strconv.Atoi(getQueryParam(r, "limit", strconv.Itoa(defaultPageLimit)))
+	if err != nil || limit < 1 || limit > maxPageLimit {
+		return 0, 0, fmt.Errorf("limit has to be a number between 1 and %d", maxPageLimit)
+	}
+
+	offset, err := strconv.Atoi(getQueryParam(r, "offset", "0"))
+	if err != nil || offset < 0 {
+		return 0, 0, fmt.Errorf("offset has to be a positive number")
+	}
+
+	return limit, offset, nil
+}
+
+func parseRepositoryImagesQuery(r *http.Request) (store.ImageListOptions, error) {
+	opts := store.ImageListOptions{TagDistinction: getQueryParam(r, "distinction", "")}
+	var err error
+	opts.Limit, opts.Offset, err = parsePagination(r)
+	if err != nil {
+		return opts, err
+	}
+
+	since := getQueryParam(r, "since", "")
+	if since != "" {
+		opts.CreatedSince, err = time.Parse(time.RFC3339, since)
+		if err != nil {
+			return opts, fmt.Errorf("parsing since: %s", err)
+		}
+	}
+
+	until := getQueryParam(r, "until", "")
+	if until != "" {
+		opts.CreatedUntil, err = time.Parse(time.RFC3339, until)
+		if err != nil {
+			return opts, fmt.Errorf("parsing until: %s", err)
+		}
+	}
+
+	return opts, nil
+}
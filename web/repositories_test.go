@@ -0,0 +1,381 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/imagespy/api/store"
+	"github.com/imagespy/api/store/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testCreated = time.Date(2019, 6, 20, 10, 0, 0, 0, time.UTC)
+
+type testTag struct {
+	distinction string
+	isLatest    bool
+	isTagged    bool
+	name        string
+}
+
+// createTestImage stores an image with one amd64 platform and the tags.
+func createTestImage(t *testing.T, s store.Store, name string, digest string, createdAt time.Time, tags ...testTag) *store.Image {
+	i := &store.Image{CreatedAt: createdAt, Digest: digest, Name: name, SchemaVersion: 2, ScrapedAt: createdAt}
+	require.NoError(t, s.Images().Create(i))
+	p := &store.Platform{
+		Architecture:   "amd64",
+		Created:        createdAt,
+		ImageID:        i.ID,
+		ManifestDigest: digest + "-config",
+		OS:             "linux",
+		Size:           1024,
+	}
+	require.NoError(t, s.Platforms().Create(p))
+	for _, tag := range tags {
+		require.NoError(t, s.Tags().Create(&store.Tag{
+			Distinction: tag.distinction,
+			ImageID:     i.ID,
+			IsLatest:    tag.isLatest,
+			IsTagged:    tag.isTagged,
+			Name:        tag.name,
+		}))
+	}
+
+	return i
+}
+
+func newTestRepositoriesHandler(s store.Store) *repositoriesHandler {
+	return &repositoriesHandler{serializer: json.Marshal, store: s}
+}
+
+func requestRepositoryImages(t *testing.T, h *repositoriesHandler, name string, query string) (int, *repositoryImagesSerialize) {
+	req := mux.SetURLVars(httptest.NewRequest("GET", "/v2/repositories/"+name+"/images"+query, nil), map[string]string{"name": name})
+	w := httptest.NewRecorder()
+	h.images(w, req)
+	if w.Code != http.StatusOK {
+		return w.Code, nil
+	}
+
+	result := &repositoryImagesSerialize{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), result))
+	return w.Code, result
+}
+
+func digestsOfHistory(images []*historyImageSerialize) []string {
+	digests := []string{}
+	for _, i := range images {
+		digests = append(digests, i.Digest)
+	}
+
+	return digests
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestRepositoriesHandler_images(t *testing.T) {
+	s := memory.New()
+	createTestImage(t, s, "unit.test/app", "sha256:100", testCreated,
+		testTag{distinction: "majorMinorPatch", isTagged: true, name: "1.0.0"},
+		testTag{distinction: "static", name: "latest"},
+	)
+	createTestImage(t, s, "unit.test/app", "sha256:110", testCreated.Add(time.Hour),
+		testTag{distinction: "majorMinorPatch", isLatest: true, isTagged: true, name: "1.1.0"},
+	)
+	createTestImage(t, s, "unit.test/app", "sha256:latest", testCreated.Add(2*time.Hour),
+		testTag{distinction: "static", isLatest: true, isTagged: true, name: "latest"},
+	)
+	createTestImage(t, s, "unit.test/other", "sha256:other", testCreated)
+
+	testcases := []struct {
+		name               string
+		query              string
+		expectedStatus     int
+		expectedDigests    []string
+		expectedLimit      int
+		expectedNextOffset *int
+		expectedOffset     int
+	}{
+		{
+			name:            "all images newest first",
+			expectedStatus:  http.StatusOK,
+			expectedDigests: []string{"sha256:latest", "sha256:110", "sha256:100"},
+			expectedLimit:   100,
+		},
+		{
+			name:               "first page",
+			query:              "?limit=2",
+			expectedStatus:     http.StatusOK,
+			expectedDigests:    []string{"sha256:latest", "sha256:110"},
+			expectedLimit:      2,
+			expectedNextOffset: intPtr(2),
+		},
+		{
+			name:            "last page",
+			query:           "?limit=2&offset=2",
+			expectedStatus:  http.StatusOK,
+			expectedDigests: []string{"sha256:100"},
+			expectedLimit:   2,
+			expectedOffset:  2,
+		},
+		{
+			name:            "page that fills the limit exactly has no next page",
+			query:           "?limit=3",
+			expectedStatus:  http.StatusOK,
+			expectedDigests: []string{"sha256:latest", "sha256:110", "sha256:100"},
+			expectedLimit:   3,
+		},
+		{
+			name:            "offset beyond the end",
+			query:           "?offset=5",
+			expectedStatus:  http.StatusOK,
+			expectedDigests: []string{},
+			expectedLimit:   100,
+			expectedOffset:  5,
+		},
+		{
+			name:            "distinction",
+			query:           "?distinction=static",
+			expectedStatus:  http.StatusOK,
+			expectedDigests: []string{"sha256:latest", "sha256:100"},
+			expectedLimit:   100,
+		},
+		{
+			name:            "since",
+			query:           "?since=2019-06-20T11:00:00Z",
+			expectedStatus:  http.StatusOK,
+			expectedDigests: []string{"sha256:latest", "sha256:110"},
+			expectedLimit:   100,
+		},
+		{
+			name:            "until",
+			query:           "?until=2019-06-20T11:00:00Z",
+			expectedStatus:  http.StatusOK,
+			expectedDigests: []string{"sha256:100"},
+			expectedLimit:   100,
+		},
+		{
+			name:            "since and until",
+			query:           "?since=2019-06-20T10:30:00Z&until=2019-06-20T11:30:00Z",
+			expectedStatus:  http.StatusOK,
+			expectedDigests: []string{"sha256:110"},
+			expectedLimit:   100,
+		},
+		{name: "invalid since", query: "?since=yesterday", expectedStatus: http.StatusBadRequest},
+		{name: "invalid until", query: "?until=2019-06-20", expectedStatus: http.StatusBadRequest},
+		{name: "limit below 1", query: "?limit=0", expectedStatus: http.StatusBadRequest},
+		{name: "limit above maximum", query: "?limit=1001", expectedStatus: http.StatusBadRequest},
+		{name: "negative offset", query: "?offset=-1", expectedStatus: http.StatusBadRequest},
+	}
+
+	h := newTestRepositoriesHandler(s)
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			status, result := requestRepositoryImages(t, h, "unit.test/app", tc.query)
+			require.Equal(t, tc.expectedStatus, status)
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			assert.Equal(t, tc.expectedDigests, digestsOfHistory(result.Images))
+			assert.Equal(t, tc.expectedLimit, result.Limit)
+			assert.Equal(t, tc.expectedNextOffset, result.NextOffset)
+			assert.Equal(t, tc.expectedOffset, result.Offset)
+		})
+	}
+
+	t.Run("unknown repository", func(t *testing.T) {
+		status, _ := requestRepositoryImages(t, h, "unit.test/unknown", "")
+		assert.Equal(t, http.StatusNotFound, status)
+	})
+}
+
+func TestRepositoriesHandler_images_Replacements(t *testing.T) {
+	s := memory.New()
+	createTestImage(t, s, "unit.test/app", "sha256:1", testCreated,
+		testTag{distinction: "static", name: "latest"},
+		testTag{distinction: "majorMinorPatch", name: "0.9.0"},
+	)
+	second := createTestImage(t, s, "unit.test/app", "sha256:2", testCreated.Add(time.Hour),
+		testTag{distinction: "static", name: "latest"},
+	)
+	third := createTestImage(t, s, "unit.test/app", "sha256:3", testCreated.Add(2*time.Hour),
+		testTag{distinction: "static", isLatest: true, isTagged: true, name: "latest"},
+	)
+	createTestImage(t, s, "unit.test/other", "sha256:other", testCreated.Add(3*time.Hour),
+		testTag{distinction: "majorMinorPatch", isLatest: true, isTagged: true, name: "0.9.0"},
+	)
+
+	status, result := requestRepositoryImages(t, newTestRepositoriesHandler(s), "unit.test/app", "")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, []string{"sha256:3", "sha256:2", "sha256:1"}, digestsOfHistory(result.Images))
+	tagsByDigest := map[string]map[string]*historyTagSerialize{}
+	for _, i := range result.Images {
+		tagsByDigest[i.Digest] = map[string]*historyTagSerialize{}
+		for _, tag := range i.Tags {
+			tagsByDigest[i.Digest][tag.Name] = tag
+		}
+	}
+
+	first := tagsByDigest["sha256:1"]["latest"]
+	assert.Equal(t, "sha256:2", first.ReplacedBy, "the next scraped image replaces a tag, not the current one")
+	require.NotNil(t, first.ReplacedAt)
+	assert.True(t, second.CreatedAt.Equal(*first.ReplacedAt))
+	replaced := tagsByDigest["sha256:2"]["latest"]
+	assert.Equal(t, "sha256:3", replaced.ReplacedBy)
+	require.NotNil(t, replaced.ReplacedAt)
+	assert.True(t, third.CreatedAt.Equal(*replaced.ReplacedAt))
+	current := tagsByDigest["sha256:3"]["latest"]
+	assert.Empty(t, current.ReplacedBy, "tagged tags have no replacement")
+	assert.Nil(t, current.ReplacedAt)
+	deleted := tagsByDigest["sha256:1"]["0.9.0"]
+	assert.Empty(t, deleted.ReplacedBy, "tags of other repositories are no replacement")
+	assert.Nil(t, deleted.ReplacedAt)
+}
+
+// findReplacements relies on image IDs to increase in the order in which images have been scraped.
+// An image with a lower ID never replaces a tag, even if it has been created later.
+func TestRepositoriesHandler_findReplacements_ImageIDOrder(t *testing.T) {
+	s := memory.New()
+	older := createTestImage(t, s, "unit.test/app", "sha256:older", testCreated.Add(time.Hour),
+		testTag{distinction: "static", isLatest: true, isTagged: true, name: "latest"},
+	)
+	newer := createTestImage(t, s, "unit.test/app", "sha256:newer", testCreated,
+		testTag{distinction: "static", name: "latest"},
+	)
+	require.True(t, older.ID < newer.ID)
+
+	tags, err := s.Tags().List(store.TagListOptions{ImageIDs: []int{newer.ID}})
+	require.NoError(t, err)
+	replacements, err := newTestRepositoriesHandler(s).findReplacements("unit.test/app", tags)
+	require.NoError(t, err)
+	assert.Empty(t, replacements)
+
+	tags, err = s.Tags().List(store.TagListOptions{ImageIDs: []int{older.ID}})
+	require.NoError(t, err)
+	tags[0].IsTagged = false
+	replacements, err = newTestRepositoriesHandler(s).findReplacements("unit.test/app", tags)
+	require.NoError(t, err)
+	require.Contains(t, replacements, tags[0].ID)
+	assert.Equal(t, newer.ID, replacements[tags[0].ID].ID)
+}
+
+func TestRepositoriesHandler_list(t *testing.T) {
+	s := memory.New()
+	createTestImage(t, s, "unit.test/team/app", "sha256:app1", testCreated,
+		testTag{distinction: "major", isTagged: true, name: "1"},
+	)
+	createTestImage(t, s, "unit.test/team/app", "sha256:app2", testCreated.Add(2*time.Hour),
+		testTag{distinction: "major", isLatest: true, isTagged: true, name: "2"},
+		testTag{distinction: "static-latest", isLatest: true, isTagged: true, name: "latest"},
+	)
+	createTestImage(t, s, "unit.test/team/db", "sha256:db", testCreated.Add(time.Hour))
+	createTestImage(t, s, "other.test/app", "sha256:other", testCreated)
+
+	testcases := []struct {
+		name               string
+		query              string
+		expectedStatus     int
+		expectedNames      []string
+		expectedLimit      int
+		expectedNextOffset *int
+		expectedOffset     int
+	}{
+		{
+			name:           "all repositories ordered by name",
+			expectedStatus: http.StatusOK,
+			expectedNames:  []string{"other.test/app", "unit.test/team/app", "unit.test/team/db"},
+			expectedLimit:  100,
+		},
+		{
+			name:               "first page",
+			query:              "?limit=2",
+			expectedStatus:     http.StatusOK,
+			expectedNames:      []string{"other.test/app", "unit.test/team/app"},
+			expectedLimit:      2,
+			expectedNextOffset: intPtr(2),
+		},
+		{
+			name:           "last page",
+			query:          "?limit=2&offset=2",
+			expectedStatus: http.StatusOK,
+			expectedNames:  []string{"unit.test/team/db"},
+			expectedLimit:  2,
+			expectedOffset: 2,
+		},
+		{
+			name:           "prefix",
+			query:          "?prefix=unit.test/team/",
+			expectedStatus: http.StatusOK,
+			expectedNames:  []string{"unit.test/team/app", "unit.test/team/db"},
+			expectedLimit:  100,
+		},
+		{
+			name:           "q ignores case",
+			query:          "?q=APP",
+			expectedStatus: http.StatusOK,
+			expectedNames:  []string{"other.test/app", "unit.test/team/app"},
+			expectedLimit:  100,
+		},
+		{
+			name:           "prefix and q",
+			query:          "?prefix=Unit.Test/&q=app",
+			expectedStatus: http.StatusOK,
+			expectedNames:  []string{"unit.test/team/app"},
+			expectedLimit:  100,
+		},
+		{
+			name:           "sort by scraped_at",
+			query:          "?sort=scraped_at",
+			expectedStatus: http.StatusOK,
+			expectedNames:  []string{"unit.test/team/app", "unit.test/team/db", "other.test/app"},
+			expectedLimit:  100,
+		},
+		{name: "unsupported sort", query: "?sort=size", expectedStatus: http.StatusBadRequest},
+		{name: "limit below 1", query: "?limit=0", expectedStatus: http.StatusBadRequest},
+		{name: "limit above maximum", query: "?limit=1001", expectedStatus: http.StatusBadRequest},
+		{name: "invalid offset", query: "?offset=abc", expectedStatus: http.StatusBadRequest},
+	}
+
+	h := newTestRepositoriesHandler(s)
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.list(w, httptest.NewRequest("GET", "/v2/repositories"+tc.query, nil))
+			require.Equal(t, tc.expectedStatus, w.Code)
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			result := &repositoriesSerialize{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), result))
+			names := []string{}
+			for _, r := range result.Repositories {
+				names = append(names, r.Name)
+			}
+
+			assert.Equal(t, tc.expectedNames, names)
+			assert.Equal(t, tc.expectedLimit, result.Limit)
+			assert.Equal(t, tc.expectedNextOffset, result.NextOffset)
+			assert.Equal(t, tc.expectedOffset, result.Offset)
+		})
+	}
+
+	t.Run("counts", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.list(w, httptest.NewRequest("GET", "/v2/repositories?prefix=unit.test/team/app", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		result := &repositoriesSerialize{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), result))
+		require.Len(t, result.Repositories, 1)
+		assert.Equal(t, 2, result.Repositories[0].ImageCount)
+		assert.Equal(t, 3, result.Repositories[0].TagCount)
+		assert.True(t, testCreated.Add(2*time.Hour).Equal(result.Repositories[0].ScrapedAt))
+	})
+}
@@ -0,0 +1,103 @@
+package web
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/imagespy/api/registry"
+	"github.com/imagespy/api/store"
+	log "github.com/sirupsen/logrus"
+)
+
+type tagEventSerialize struct {
+	Action      string    `json:"action"`
+	CreatedAt   time.Time `json:"created_at"`
+	Digest      string    `json:"digest"`
+	Distinction string    `json:"distinction"`
+}
+
+type tagHistorySerialize struct {
+	Events     []*tagEventSerialize `json:"events"`
+	Limit      int                  `json:"limit"`
+	Name       string               `json:"name"`
+	NextOffset *int                 `json:"next_offset,omitempty"`
+	Offset     int                  `json:"offset"`
+	Tag        string               `json:"tag"`
+}
+
+// getTagHistory lists the changes of a tag, newest first.
+func (h *imageHandler) getTagHistory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	address, path, tagInput, _, err := registry.ParseImage(vars["name"])
+	if err != nil {
+		log.Infof("imageHandler.getTagHistory: parsing image name: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		log.Infof("imageHandler.getTagHistory: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	name := address + "/" + path
+	// Read one event more than requested to know if there is a next page.
+	events, err := h.Store.TagEvents().List(store.TagEventListOptions{ImageName: name, Limit: limit + 1, Offset: offset, TagName: tagInput})
+	if err != nil {
+		log.Errorf("imageHandler.getTagHistory: reading events of tag %s:%s: %s", name, tagInput, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// The log contains at least one event of every tag that has ever been scraped.
+	if len(events) == 0 {
+		exists := false
+		if offset > 0 {
+			// An offset beyond the end returns an empty page if the tag exists.
+			first, err := h.Store.TagEvents().List(store.TagEventListOptions{ImageName: name, Limit: 1, TagName: tagInput})
+			if err != nil {
+				log.Errorf("imageHandler.getTagHistory: reading events of tag %s:%s: %s", name, tagInput, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			exists = len(first) > 0
+		}
+
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+	}
+
+	result := &tagHistorySerialize{Events: []*tagEventSerialize{}, Limit: limit, Name: name, Offset: offset, Tag: tagInput}
+	if len(events) > limit {
+		events = events[:limit]
+		nextOffset := offset + limit
+		result.NextOffset = &nextOffset
+	}
+
+	for _, e := range events {
+		result.Events = append(result.Events, &tagEventSerialize{
+			Action:      e.Action,
+			CreatedAt:   e.CreatedAt,
+			Digest:      e.Digest,
+			Distinction: e.Distinction,
+		})
+	}
+
+	b, err := h.serializer(result)
+	if err != nil {
+		log.Errorf("imageHandler.getTagHistory: serializing result: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	addCacheHeaders(w)
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
@@ -0,0 +1,108 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/imagespy/api/store"
+	"github.com/imagespy/api/store/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageHandler_getTagHistory(t *testing.T) {
+	s := memory.New()
+	for _, e := range []*store.TagEvent{
+		{Action: store.TagEventActionTag, CreatedAt: testCreated, Digest: "sha256:1", Distinction: "static-latest", ImageName: "unit.test/app", TagName: "latest"},
+		{Action: store.TagEventActionSetLatest, CreatedAt: testCreated, Digest: "sha256:1", Distinction: "static-latest", ImageName: "unit.test/app", TagName: "latest"},
+		{Action: store.TagEventActionTag, CreatedAt: testCreated, Digest: "sha256:1", Distinction: "major", ImageName: "unit.test/app", TagName: "1"},
+		{Action: store.TagEventActionUntag, CreatedAt: testCreated, Digest: "sha256:1", Distinction: "static-latest", ImageName: "unit.test/app", TagName: "latest"},
+		{Action: store.TagEventActionTag, CreatedAt: testCreated, Digest: "sha256:2", Distinction: "static-latest", ImageName: "unit.test/app", TagName: "latest"},
+	} {
+		require.NoError(t, s.TagEvents().Create(e))
+	}
+
+	testcases := []struct {
+		name               string
+		query              string
+		expectedStatus     int
+		expectedEvents     []string
+		expectedLimit      int
+		expectedNextOffset *int
+		expectedOffset     int
+	}{
+		{
+			name:           "all events newest first",
+			expectedStatus: http.StatusOK,
+			expectedEvents: []string{"tag sha256:2", "untag sha256:1", "set_latest sha256:1", "tag sha256:1"},
+			expectedLimit:  100,
+		},
+		{
+			name:               "first page",
+			query:              "?limit=3",
+			expectedStatus:     http.StatusOK,
+			expectedEvents:     []string{"tag sha256:2", "untag sha256:1", "set_latest sha256:1"},
+			expectedLimit:      3,
+			expectedNextOffset: intPtr(3),
+		},
+		{
+			name:           "last page",
+			query:          "?limit=3&offset=3",
+			expectedStatus: http.StatusOK,
+			expectedEvents: []string{"tag sha256:1"},
+			expectedLimit:  3,
+			expectedOffset: 3,
+		},
+		{
+			name:           "offset beyond the end",
+			query:          "?offset=10",
+			expectedStatus: http.StatusOK,
+			expectedEvents: []string{},
+			expectedLimit:  100,
+			expectedOffset: 10,
+		},
+		{name: "limit below 1", query: "?limit=0", expectedStatus: http.StatusBadRequest},
+		{name: "limit above maximum", query: "?limit=1001", expectedStatus: http.StatusBadRequest},
+		{name: "negative offset", query: "?offset=-1", expectedStatus: http.StatusBadRequest},
+	}
+
+	h := &imageHandler{serializer: json.Marshal, Store: s}
+	request := func(image string, query string) *httptest.ResponseRecorder {
+		req := mux.SetURLVars(httptest.NewRequest("GET", "/v2/images/"+image+"/tag-history"+query, nil), map[string]string{"name": image})
+		w := httptest.NewRecorder()
+		h.getTagHistory(w, req)
+		return w
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := request("unit.test/app:latest", tc.query)
+			require.Equal(t, tc.expectedStatus, w.Code)
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			result := &tagHistorySerialize{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), result))
+			events := []string{}
+			for _, e := range result.Events {
+				events = append(events, e.Action+" "+e.Digest)
+			}
+
+			assert.Equal(t, tc.expectedEvents, events)
+			assert.Equal(t, tc.expectedLimit, result.Limit)
+			assert.Equal(t, tc.expectedNextOffset, result.NextOffset)
+			assert.Equal(t, tc.expectedOffset, result.Offset)
+			assert.Equal(t, "unit.test/app", result.Name)
+			assert.Equal(t, "latest", result.Tag)
+		})
+	}
+
+	t.Run("unknown tag", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, request("unit.test/app:2", "").Code)
+		assert.Equal(t, http.StatusNotFound, request("unit.test/app:2", "?offset=10").Code)
+	})
+}
@@ -0,0 +1,150 @@
+package web
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/imagespy/api/queue"
+	"github.com/imagespy/api/store"
+	"github.com/imagespy/api/webhook"
+	log "github.com/sirupsen/logrus"
+)
+
+// WebhookOpts configures the authentication and filtering of webhook events sent by registries.
+type WebhookOpts struct {
+	// AllowedHosts are glob patterns of registry hosts, e.g. "*.example.com".
+	// Events of all hosts are processed if no pattern is set.
+	AllowedHosts []string
+	// AllowedRepositories are glob patterns of repositories without the host, e.g. "team/*".
+	// Events of all repositories are processed if no pattern is set.
+	AllowedRepositories []string
+	// Secret has to be sent as "Authorization: Bearer <secret>" by the registry.
+	// Authentication is disabled if it is empty.
+	Secret string
+}
+
+// Validate returns an error if a pattern is malformed.
+func (o WebhookOpts) Validate() error {
+	for _, pattern := range append(append([]string{}, o.AllowedHosts...), o.AllowedRepositories...) {
+		_, err := path.Match(pattern, "")
+		if err != nil {
+			return fmt.Errorf("invalid webhook pattern %s: %s", pattern, err)
+		}
+	}
+
+	return nil
+}
+
+// allows returns true if events of repository in the registry host should be processed.
+func (o WebhookOpts) allows(host string, repository string) bool {
+	return matchesAny(o.AllowedHosts, host) && matchesAny(o.AllowedRepositories, repository)
+}
+
+// authenticate responds with 401 to requests that do not contain the secret.
+func (o WebhookOpts) authenticate(h http.HandlerFunc) http.HandlerFunc {
+	if o.Secret == "" {
+		return h
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !o.isAuthenticated(r) {
+			log.Warnf("webhook request from %s to %s is not authorized", r.RemoteAddr, r.URL.Path)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		h(w, r)
+	}
+}
+
+// isAuthenticated returns true if the request contains the secret or if authentication is disabled.
+func (o WebhookOpts) isAuthenticated(r *http.Request) bool {
+	if o.Secret == "" {
+		return true
+	}
+
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+o.Secret)) == 1
+}
+
+// matchesAny returns true if name matches one of the patterns or if no pattern is set.
+func matchesAny(patterns []string, name string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+
+	for _, pattern := range patterns {
+		ok, _ := path.Match(pattern, name)
+		if ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+type webhookHandler struct {
+	opts  WebhookOpts
+	queue queue.Queue
+}
+
+// handleProvider decodes the webhook of the provider given in the path of the request.
+func (h *webhookHandler) handleProvider(w http.ResponseWriter, r *http.Request) {
+	provider := mux.Vars(r)["provider"]
+	d, ok := webhook.Get(provider)
+	if !ok {
+		log.Infof("webhookHandler.handleProvider: unknown provider %s", provider)
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, "unknown provider %s, supported providers are %s\n", provider, strings.Join(webhook.Providers(), ", "))
+		return
+	}
+
+	h.handle(provider, d, w, r)
+}
+
+// handleRegistryEvent decodes the notifications sent by the registry of the distribution project.
+func (h *webhookHandler) handleRegistryEvent(w http.ResponseWriter, r *http.Request) {
+	d, _ := webhook.Get("distribution")
+	h.handle("distribution", d, w, r)
+}
+
+func (h *webhookHandler) handle(provider string, d webhook.Decoder, w http.ResponseWriter, r *http.Request) {
+	log.Debugf("processing webhook of provider %s", provider)
+	defer r.Body.Close()
+	events, err := d.Decode(r)
+	if err != nil {
+		log.Errorf("webhookHandler.handle: decoding webhook of provider %s: %s", provider, err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	failed := false
+	for _, e := range events {
+		if !h.opts.allows(e.Host, e.Repository) {
+			log.Warnf("webhookHandler.handle: repository %s of registry %s is not allowed", e.Repository, e.Host)
+			continue
+		}
+
+		err := h.queue.Enqueue(&store.Job{
+			Action:    e.Action,
+			Name:      e.Name(),
+			Reference: e.Reference,
+		})
+		if err != nil {
+			log.Errorf("webhookHandler.handle: enqueueing %s of %s:%s: %s", e.Action, e.Name(), e.Reference, err)
+			failed = true
+		}
+	}
+
+	// Providers send the webhook again if the response is not successful.
+	if failed {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
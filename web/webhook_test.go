@@ -0,0 +1,167 @@
+package web
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/imagespy/api/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testQueue records enqueued jobs instead of processing them.
+type testQueue struct {
+	err  error
+	jobs []*store.Job
+}
+
+func (q *testQueue) Enqueue(j *store.Job) error {
+	if q.err != nil {
+		return q.err
+	}
+
+	q.jobs = append(q.jobs, j)
+	return nil
+}
+
+func (q *testQueue) Run(stop <-chan struct{}) {}
+
+func readFixtureRequest(t *testing.T, path string) *http.Request {
+	b, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+
+	req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(b)))
+	require.NoError(t, err)
+	return req
+}
+
+func TestWebhookOpts_authenticate(t *testing.T) {
+	testcases := []struct {
+		name           string
+		secret         string
+		authorization  string
+		expectedStatus int
+	}{
+		{name: "correct secret", secret: "secret", authorization: "Bearer secret", expectedStatus: http.StatusOK},
+		{name: "missing secret", secret: "secret", expectedStatus: http.StatusUnauthorized},
+		{name: "wrong secret", secret: "secret", authorization: "Bearer other", expectedStatus: http.StatusUnauthorized},
+		{name: "secret without scheme", secret: "secret", authorization: "secret", expectedStatus: http.StatusUnauthorized},
+		{name: "authentication disabled", expectedStatus: http.StatusOK},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			called := false
+			h := WebhookOpts{Secret: tc.secret}.authenticate(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest("POST", "/dockerRegistry/event", nil)
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+
+			w := httptest.NewRecorder()
+			h(w, req)
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			assert.Equal(t, tc.expectedStatus == http.StatusOK, called, "handler is only called if the request is authenticated")
+		})
+	}
+}
+
+func TestWebhookOpts_allows(t *testing.T) {
+	testcases := []struct {
+		name       string
+		opts       WebhookOpts
+		host       string
+		repository string
+		expected   bool
+	}{
+		{name: "no patterns", host: "registry.example.com", repository: "team/app", expected: true},
+		{name: "allowed host", opts: WebhookOpts{AllowedHosts: []string{"*.example.com"}}, host: "registry.example.com", repository: "team/app", expected: true},
+		{name: "denied host", opts: WebhookOpts{AllowedHosts: []string{"*.example.com"}}, host: "registry.other.com", repository: "team/app", expected: false},
+		{name: "allowed repository", opts: WebhookOpts{AllowedRepositories: []string{"team/*"}}, host: "registry.example.com", repository: "team/app", expected: true},
+		{name: "denied repository", opts: WebhookOpts{AllowedRepositories: []string{"team/*"}}, host: "registry.example.com", repository: "other/app", expected: false},
+		{name: "pattern does not match nested repositories", opts: WebhookOpts{AllowedRepositories: []string{"team/*"}}, host: "registry.example.com", repository: "team/sub/app", expected: false},
+		{name: "one of multiple patterns", opts: WebhookOpts{AllowedRepositories: []string{"team/*", "app"}}, host: "registry.example.com", repository: "app", expected: true},
+		{name: "host and repository have to match", opts: WebhookOpts{AllowedHosts: []string{"*.example.com"}, AllowedRepositories: []string{"team/*"}}, host: "registry.other.com", repository: "team/app", expected: false},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.opts.allows(tc.host, tc.repository))
+		})
+	}
+}
+
+func TestWebhookOpts_Validate(t *testing.T) {
+	assert.NoError(t, WebhookOpts{AllowedHosts: []string{"*.example.com"}, AllowedRepositories: []string{"team/*"}}.Validate())
+	assert.Error(t, WebhookOpts{AllowedHosts: []string{"[example.com"}}.Validate(), "invalid host pattern")
+	assert.Error(t, WebhookOpts{AllowedRepositories: []string{"team/[a-"}}.Validate(), "invalid repository pattern")
+}
+
+func TestWebhookHandler_handleRegistryEvent(t *testing.T) {
+	testcases := []struct {
+		name           string
+		opts           WebhookOpts
+		queueErr       error
+		expectedStatus int
+		expectedJobs   []*store.Job
+	}{
+		{
+			name:           "all allowed",
+			expectedStatus: http.StatusOK,
+			expectedJobs: []*store.Job{
+				{Action: store.JobActionScrape, Name: "registry.example.com/golang", Reference: "1.13"},
+				{Action: store.JobActionScrape, Name: "registry.example.com/alpine", Reference: "3.10"},
+				{Action: store.JobActionScrape, Name: "registry.example.com/busybox", Reference: "1.31"},
+			},
+		},
+		{
+			name:           "denied repositories are skipped",
+			opts:           WebhookOpts{AllowedRepositories: []string{"golang", "alpine"}},
+			expectedStatus: http.StatusOK,
+			expectedJobs: []*store.Job{
+				{Action: store.JobActionScrape, Name: "registry.example.com/golang", Reference: "1.13"},
+				{Action: store.JobActionScrape, Name: "registry.example.com/alpine", Reference: "3.10"},
+			},
+		},
+		{
+			name:           "denied host",
+			opts:           WebhookOpts{AllowedHosts: []string{"registry.other.com"}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "enqueueing fails",
+			queueErr:       fmt.Errorf("unit test"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			q := &testQueue{err: tc.queueErr}
+			h := &webhookHandler{opts: tc.opts, queue: q}
+			w := httptest.NewRecorder()
+			h.handleRegistryEvent(w, readFixtureRequest(t, "../webhook/fixtures/distribution-multi-platform.http"))
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			assert.Equal(t, tc.expectedJobs, q.jobs)
+		})
+	}
+}
+
+func TestWebhookHandler_handleProvider_UnknownProvider(t *testing.T) {
+	h := &webhookHandler{queue: &testQueue{}}
+	req := mux.SetURLVars(httptest.NewRequest("POST", "/webhooks/unknown", nil), map[string]string{"provider": "unknown"})
+	w := httptest.NewRecorder()
+	h.handleProvider(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "unknown provider unknown, supported providers are distribution, dockerhub, ghcr, gitlab, harbor, quay\n", w.Body.String())
+}
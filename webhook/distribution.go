@@ -0,0 +1,97 @@
+package webhook
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/docker/distribution/notifications"
+	"github.com/imagespy/api/registry"
+	"github.com/imagespy/api/store"
+)
+
+// distribution decodes the notifications of the registry of the distribution project.
+// The GitLab Container Registry is based on it and sends the same notifications.
+type distribution struct {
+	// preferRequestHost uses the host of the request sent by the client instead of the URL of the target.
+	// The URL of the target points to the internal address of the registry if it runs behind a proxy, as the GitLab Container Registry does.
+	preferRequestHost bool
+}
+
+func (d *distribution) Decode(r *http.Request) ([]Event, error) {
+	if r.Header.Get("Content-Type") != notifications.EventsMediaType {
+		return nil, nil
+	}
+
+	envelope := &notifications.Envelope{}
+	err := decodeJSON(r.Body, envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	events := []Event{}
+	seen := map[Event]struct{}{}
+	for _, e := range envelope.Events {
+		event := Event{Repository: e.Target.Repository}
+		switch e.Action {
+		case notifications.EventActionDelete:
+			// The registry sends the tag if a tag has been deleted and the digest if a manifest has been deleted.
+			// Events about deleted repositories contain neither and are ignored.
+			if e.Target.Tag != "" {
+				event.Action = store.JobActionDeleteTag
+				event.Reference = e.Target.Tag
+			} else if e.Target.Digest != "" {
+				event.Action = store.JobActionDeleteImage
+				event.Reference = e.Target.Digest.String()
+			} else {
+				continue
+			}
+		case notifications.EventActionPush:
+			// The manifests of the platforms of a manifest list or an OCI image index are pushed by digest before the list itself.
+			// They do not contain a tag and are scraped as part of the list.
+			if !registry.IsManifestMediaType(e.Target.MediaType) || e.Target.Tag == "" {
+				continue
+			}
+
+			event.Action = store.JobActionScrape
+			event.Reference = e.Target.Tag
+		default:
+			continue
+		}
+
+		event.Host, err = d.hostOf(e)
+		if err != nil {
+			return nil, err
+		}
+
+		// A batch can contain multiple pushes of the same tag. Scrape it once.
+		if _, ok := seen[event]; ok {
+			continue
+		}
+
+		seen[event] = struct{}{}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// hostOf returns the host of the registry of an event.
+// Delete events do not contain the URL of the target. The host that received the request is used instead.
+func (d *distribution) hostOf(e notifications.Event) (string, error) {
+	host := e.Request.Host
+	if e.Target.URL != "" && (!d.preferRequestHost || host == "") {
+		targetURL, err := url.ParseRequestURI(e.Target.URL)
+		if err != nil {
+			return "", err
+		}
+
+		host = targetURL.Host
+	}
+
+	if host == "" {
+		return "", fmt.Errorf("unable to determine registry host of event %s", e.ID)
+	}
+
+	return normalizeHost(host), nil
+}
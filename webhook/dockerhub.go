@@ -0,0 +1,40 @@
+package webhook
+
+import (
+	"net/http"
+
+	"github.com/imagespy/api/store"
+)
+
+type dockerHubPayload struct {
+	PushData struct {
+		Tag string `json:"tag"`
+	} `json:"push_data"`
+	Repository struct {
+		RepoName string `json:"repo_name"`
+	} `json:"repository"`
+}
+
+// dockerHub decodes the webhooks of Docker Hub. Docker Hub only sends webhooks for pushes.
+type dockerHub struct{}
+
+func (d *dockerHub) Decode(r *http.Request) ([]Event, error) {
+	p := &dockerHubPayload{}
+	err := decodeJSON(r.Body, p)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Repository.RepoName == "" || p.PushData.Tag == "" {
+		return nil, nil
+	}
+
+	return []Event{
+		{
+			Action:     store.JobActionScrape,
+			Host:       normalizeHost("docker.io"),
+			Reference:  p.PushData.Tag,
+			Repository: p.Repository.RepoName,
+		},
+	}, nil
+}
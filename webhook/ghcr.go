@@ -0,0 +1,91 @@
+package webhook
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/imagespy/api/store"
+)
+
+type ghcrPackage struct {
+	Ecosystem      string `json:"ecosystem"`
+	Name           string `json:"name"`
+	Namespace      string `json:"namespace"`
+	PackageType    string `json:"package_type"`
+	PackageVersion struct {
+		ContainerMetadata struct {
+			Tag struct {
+				Name string `json:"name"`
+			} `json:"tag"`
+		} `json:"container_metadata"`
+		PackageURL string `json:"package_url"`
+	} `json:"package_version"`
+}
+
+type ghcrPayload struct {
+	Action          string       `json:"action"`
+	Package         *ghcrPackage `json:"package"`
+	RegistryPackage *ghcrPackage `json:"registry_package"`
+}
+
+// ghcr decodes the "package" and "registry_package" webhooks of GitHub for the GitHub Container Registry.
+type ghcr struct{}
+
+func (g *ghcr) Decode(r *http.Request) ([]Event, error) {
+	ghEvent := r.Header.Get("X-GitHub-Event")
+	if ghEvent != "package" && ghEvent != "registry_package" {
+		return nil, nil
+	}
+
+	p := &ghcrPayload{}
+	err := decodeJSON(r.Body, p)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Action != "published" && p.Action != "updated" {
+		return nil, nil
+	}
+
+	pkg := p.Package
+	if pkg == nil {
+		pkg = p.RegistryPackage
+	}
+
+	if pkg == nil {
+		return nil, nil
+	}
+
+	// The "registry_package" webhook sends the type of the package as "ecosystem".
+	packageType := pkg.PackageType
+	if packageType == "" {
+		packageType = pkg.Ecosystem
+	}
+
+	if !strings.EqualFold(packageType, "container") {
+		return nil, nil
+	}
+
+	tag := pkg.PackageVersion.ContainerMetadata.Tag.Name
+	if tag == "" {
+		return nil, nil
+	}
+
+	event := Event{
+		Action:     store.JobActionScrape,
+		Host:       "ghcr.io",
+		Reference:  tag,
+		Repository: strings.ToLower(pkg.Namespace + "/" + pkg.Name),
+	}
+	if pkg.PackageVersion.PackageURL != "" {
+		host, repository, _, err := splitImageName(pkg.PackageVersion.PackageURL)
+		if err != nil {
+			return nil, err
+		}
+
+		event.Host = host
+		event.Repository = repository
+	}
+
+	return []Event{event}, nil
+}
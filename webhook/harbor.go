@@ -0,0 +1,69 @@
+package webhook
+
+import (
+	"net/http"
+
+	"github.com/imagespy/api/store"
+)
+
+type harborPayload struct {
+	EventData struct {
+		Repository struct {
+			RepoFullName string `json:"repo_full_name"`
+		} `json:"repository"`
+		Resources []struct {
+			Digest      string `json:"digest"`
+			ResourceURL string `json:"resource_url"`
+			Tag         string `json:"tag"`
+		} `json:"resources"`
+	} `json:"event_data"`
+	Type string `json:"type"`
+}
+
+// harbor decodes the webhooks of Harbor 2.
+type harbor struct{}
+
+func (h *harbor) Decode(r *http.Request) ([]Event, error) {
+	p := &harborPayload{}
+	err := decodeJSON(r.Body, p)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Type != "PUSH_ARTIFACT" && p.Type != "DELETE_ARTIFACT" {
+		return nil, nil
+	}
+
+	events := []Event{}
+	for _, resource := range p.EventData.Resources {
+		host, repository, _, err := splitImageName(resource.ResourceURL)
+		if err != nil {
+			return nil, err
+		}
+
+		if p.EventData.Repository.RepoFullName != "" {
+			repository = p.EventData.Repository.RepoFullName
+		}
+
+		event := Event{Host: host, Repository: repository}
+		if p.Type == "PUSH_ARTIFACT" {
+			if resource.Tag == "" {
+				continue
+			}
+
+			event.Action = store.JobActionScrape
+			event.Reference = resource.Tag
+		} else {
+			if resource.Digest == "" {
+				continue
+			}
+
+			event.Action = store.JobActionDeleteImage
+			event.Reference = resource.Digest
+		}
+
+		events = append(events, event)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,45 @@
+package webhook
+
+import (
+	"net/http"
+
+	"github.com/imagespy/api/store"
+)
+
+type quayPayload struct {
+	DockerURL   string   `json:"docker_url"`
+	Repository  string   `json:"repository"`
+	UpdatedTags []string `json:"updated_tags"`
+}
+
+// quay decodes the "Push to Repository" notification of Quay.
+type quay struct{}
+
+func (q *quay) Decode(r *http.Request) ([]Event, error) {
+	p := &quayPayload{}
+	err := decodeJSON(r.Body, p)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.DockerURL == "" || len(p.UpdatedTags) == 0 {
+		return nil, nil
+	}
+
+	host, repository, _, err := splitImageName(p.DockerURL)
+	if err != nil {
+		return nil, err
+	}
+
+	events := []Event{}
+	for _, tag := range p.UpdatedTags {
+		events = append(events, Event{
+			Action:     store.JobActionScrape,
+			Host:       host,
+			Reference:  tag,
+			Repository: repository,
+		})
+	}
+
+	return events, nil
+}
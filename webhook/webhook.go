@@ -0,0 +1,100 @@
+// Package webhook decodes the webhook payloads of different registries into events that imagespy processes.
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/imagespy/api/registry"
+)
+
+// Event references an image that has been changed in a registry.
+type Event struct {
+	// Action is one of the job actions defined in the store package.
+	Action string
+	// Host is the host of the registry, including the port if it is not the default port.
+	Host string
+	// Reference is a tag or, if Action is store.JobActionDeleteImage, a digest.
+	Reference string
+	// Repository is the path of the repository without the host.
+	Repository string
+}
+
+// Name returns the name of the repository including the host.
+func (e Event) Name() string {
+	return e.Host + "/" + e.Repository
+}
+
+// Decoder extracts events from a webhook request.
+type Decoder interface {
+	// Decode returns the events of the request.
+	// It returns no events and no error if the request does not describe a change that imagespy is interested in.
+	Decode(r *http.Request) ([]Event, error)
+}
+
+var decoders = map[string]Decoder{
+	"distribution": &distribution{},
+	"dockerhub":    &dockerHub{},
+	"ghcr":         &ghcr{},
+	"gitlab":       &distribution{preferRequestHost: true},
+	"harbor":       &harbor{},
+	"quay":         &quay{},
+}
+
+// Get returns the decoder of a provider.
+func Get(provider string) (Decoder, bool) {
+	d, ok := decoders[provider]
+	return d, ok
+}
+
+// Providers returns the names of all providers, sorted ascending.
+func Providers() []string {
+	names := []string{}
+	for name := range decoders {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+func decodeJSON(body io.Reader, v interface{}) error {
+	err := json.NewDecoder(body).Decode(v)
+	if err != nil {
+		return fmt.Errorf("decoding webhook payload: %s", err)
+	}
+
+	return nil
+}
+
+// splitImageName splits a name like "registry.example.com/team/app:1.0" into the host, the repository and the tag.
+// A digest, e.g. "registry.example.com/team/app@sha256:...", is removed.
+func splitImageName(name string) (string, string, string, error) {
+	name = strings.TrimPrefix(strings.TrimPrefix(name, "https://"), "http://")
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", "", fmt.Errorf("image name %s does not contain a host and a repository", name)
+	}
+
+	repository := parts[1]
+	if i := strings.Index(repository, "@"); i != -1 {
+		repository = repository[:i]
+	}
+
+	tag := ""
+	if i := strings.LastIndex(repository, ":"); i != -1 {
+		tag = repository[i+1:]
+		repository = repository[:i]
+	}
+
+	return normalizeHost(parts[0]), repository, tag, nil
+}
+
+// normalizeHost removes default ports and converts the aliases of Docker Hub to the host used by the registry package.
+func normalizeHost(host string) string {
+	return registry.NormalizeHost(strings.TrimSuffix(strings.TrimSuffix(host, ":443"), ":80"))
+}
@@ -0,0 +1,133 @@
+package webhook
+
+import (
+	"bufio"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/imagespy/api/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecoders(t *testing.T) {
+	testCases := []struct {
+		name     string
+		provider string
+		fixture  string
+		expected []Event
+	}{
+		{
+			name:     "distribution push",
+			provider: "distribution",
+			fixture:  "fixtures/distribution-push.http",
+			expected: []Event{
+				{Action: store.JobActionScrape, Host: "127.0.0.1:52854", Reference: "1.12.4", Repository: "golang"},
+			},
+		},
+		{
+			name:     "distribution delete",
+			provider: "distribution",
+			fixture:  "fixtures/distribution-delete.http",
+			expected: []Event{
+				{Action: store.JobActionDeleteImage, Host: "registry.example.com", Reference: "sha256:85032fa900cd979127f344c594f714b59e147c6ecbab83bd1ed0ad3ca2e64441", Repository: "golang"},
+				{Action: store.JobActionDeleteTag, Host: "registry.example.com", Reference: "1.12.4", Repository: "golang"},
+			},
+		},
+		{
+			name:     "distribution multi-platform",
+			provider: "distribution",
+			fixture:  "fixtures/distribution-multi-platform.http",
+			expected: []Event{
+				{Action: store.JobActionScrape, Host: "registry.example.com", Reference: "1.13", Repository: "golang"},
+				{Action: store.JobActionScrape, Host: "registry.example.com", Reference: "3.10", Repository: "alpine"},
+				{Action: store.JobActionScrape, Host: "registry.example.com", Reference: "1.31", Repository: "busybox"},
+			},
+		},
+		{
+			name:     "docker hub",
+			provider: "dockerhub",
+			fixture:  "fixtures/dockerhub.http",
+			expected: []Event{
+				{Action: store.JobActionScrape, Host: "index.docker.io", Reference: "latest", Repository: "svendowideit/testhook"},
+			},
+		},
+		{
+			name:     "ghcr",
+			provider: "ghcr",
+			fixture:  "fixtures/ghcr.http",
+			expected: []Event{
+				{Action: store.JobActionScrape, Host: "ghcr.io", Reference: "v1.2.0", Repository: "example-org/app"},
+			},
+		},
+		{
+			name:     "gitlab",
+			provider: "gitlab",
+			fixture:  "fixtures/gitlab.http",
+			expected: []Event{
+				{Action: store.JobActionScrape, Host: "registry.gitlab.example.com", Reference: "main", Repository: "group/project"},
+			},
+		},
+		{
+			name:     "harbor push",
+			provider: "harbor",
+			fixture:  "fixtures/harbor-push.http",
+			expected: []Event{
+				{Action: store.JobActionScrape, Host: "harbor.example.com", Reference: "1.19", Repository: "library/nginx"},
+			},
+		},
+		{
+			name:     "harbor delete",
+			provider: "harbor",
+			fixture:  "fixtures/harbor-delete.http",
+			expected: []Event{
+				{Action: store.JobActionDeleteImage, Host: "harbor.example.com", Reference: "sha256:954b378c375d852eb3c63ab88978f640b4348b01c1b3456a024a81536dafbbf4", Repository: "library/nginx"},
+			},
+		},
+		{
+			name:     "quay",
+			provider: "quay",
+			fixture:  "fixtures/quay.http",
+			expected: []Event{
+				{Action: store.JobActionScrape, Host: "quay.io", Reference: "latest", Repository: "mynamespace/repository"},
+				{Action: store.JobActionScrape, Host: "quay.io", Reference: "1.0.0", Repository: "mynamespace/repository"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := os.Open(tc.fixture)
+			require.NoError(t, err)
+			defer f.Close()
+
+			req, err := http.ReadRequest(bufio.NewReader(f))
+			require.NoError(t, err)
+
+			d, ok := Get(tc.provider)
+			require.True(t, ok)
+
+			events, err := d.Decode(req)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, events)
+		})
+	}
+}
+
+func TestDecoders_IgnoreUnrelatedEvents(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPost, "/webhooks/ghcr", strings.NewReader(`{"zen": "Keep it logically awesome."}`))
+	require.NoError(t, err)
+	req.Header.Set("X-GitHub-Event", "ping")
+
+	d, _ := Get("ghcr")
+	events, err := d.Decode(req)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestGet_UnknownProvider(t *testing.T) {
+	_, ok := Get("unknown")
+	assert.False(t, ok)
+}